@@ -0,0 +1,65 @@
+package commands
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	dsh "github.com/sdqri/sequined/internal/dashboard"
+	obs "github.com/sdqri/sequined/internal/observer"
+)
+
+type dashboardConfig struct {
+	Addr         string
+	ObserverFile string
+	Compact      bool
+}
+
+var dashboardCfg dashboardConfig
+
+var dashboardCmd = &cobra.Command{
+	Use:   "dashboard",
+	Short: "Serve the dashboard over observations persisted by weave.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return serveDashboard(dashboardCfg)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(dashboardCmd)
+
+	flags := dashboardCmd.Flags()
+	flags.StringVarP(&dashboardCfg.Addr, "addr", "a", ":8081", "address to serve the dashboard on")
+	flags.StringVar(&dashboardCfg.ObserverFile, "observer-file", "", "file the observations were persisted to")
+	flags.BoolVar(&dashboardCfg.Compact, "compact", false, "compact the observer file before serving")
+	dashboardCmd.MarkFlagRequired("observer-file")
+}
+
+func serveDashboard(cfg dashboardConfig) error {
+	storage, err := obs.OpenFileStorage(cfg.ObserverFile)
+	if err != nil {
+		return fmt.Errorf("opening observer file: %w", err)
+	}
+
+	if cfg.Compact {
+		if err := storage.Compact(); err != nil {
+			return fmt.Errorf("compacting observer file: %w", err)
+		}
+	}
+
+	observer, err := obs.Open(storage)
+	if err != nil {
+		return fmt.Errorf("loading observer file: %w", err)
+	}
+	defer observer.Close()
+
+	mux := http.NewServeMux()
+	// charts end at the last observation rather than now, which may be long after
+	lastActivity := observer.LastActivity().Add(time.Second)
+	dsh.NewDashboard(nil, observer, dsh.WithNow(func() time.Time { return lastActivity })).HandleBy(mux)
+
+	fmt.Printf("serving dashboard on %s/dashboard\n", cfg.Addr)
+	return http.ListenAndServe(cfg.Addr, mux)
+}
@@ -1,29 +1,848 @@
 package commands
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+
+	clk "github.com/sdqri/sequined/internal/clock"
+	dsh "github.com/sdqri/sequined/internal/dashboard"
+	ggr "github.com/sdqri/sequined/internal/graphgenerator"
+	gmx "github.com/sdqri/sequined/internal/graphmultiplexer"
+	hyr "github.com/sdqri/sequined/internal/hyperrenderer"
+	obs "github.com/sdqri/sequined/internal/observer"
 )
 
+type weaveConfig struct {
+	Addr              string
+	PathPrefix        string
+	CrawlerIdentifier string
+	ObserverFile      string
+	TimeScale         float64
+	Seed              int64
+	// Seeded is set if a seed was given, unseeded graphs differ on every run.
+	Seeded bool
+
+	LoadSnapshot     string
+	SaveSnapshot     string
+	SnapshotInterval time.Duration
+
+	Import       string
+	ImportFormat string
+
+	Sitemap             bool
+	SitemapRefresh      time.Duration
+	SitemapOmitFraction float64
+
+	Robots                 bool
+	RobotsDisallow         []string
+	RobotsDisallowFraction float64
+	CrawlDelay             time.Duration
+
+	Latency       string
+	LatencyMean   time.Duration
+	LatencyStddev time.Duration
+	LatencySigma  float64
+	SlowSubtrees  map[string]string
+	Bandwidth     int
+	LoadPeak      float64
+	LoadPeakHour  float64
+
+	Faults     map[string]string
+	FaultPaths []string
+	Outages    []string
+
+	Traps []string
+
+	RateLimit      float64
+	RateLimitBurst int
+	BanThreshold   int
+	BanDuration    time.Duration
+
+	InitialHubCount  int
+	InitialAuthCount int
+	MaxHubCount      int
+	MaxAuthCount     int
+
+	HubCreationRate        float64
+	AuthCreationRate       float64
+	PreferentialAttachment float64
+
+	Model                 string
+	ModelLinks            int
+	RandomLinkProbability float64
+	LinkProbability       float64
+	ForwardBurning        float64
+	BackwardBurning       float64
+	MaxDepth              int
+	Fanout                int
+
+	CrossLinksPerPage   float64
+	CrossLinkRate       float64
+	BackLinkProbability float64
+
+	MoveRate                float64
+	RedirectStatuses        []int
+	RedirectLoopProbability float64
+
+	HubDeletionRate      float64
+	AuthDeletionRate     float64
+	OrphanPolicy         string
+	DeletedPages         string
+	DeletedPageRetention time.Duration
+
+	DuplicateProbability  float64
+	DuplicateSimilarity   float64
+	URLVariantProbability float64
+	URLVariants           []string
+	CanonicalTags         bool
+	URLNormalization      []string
+	HubPageSize           int
+	HubPagination         string
+
+	ChangeRate             float64
+	ChangeRateDistribution string
+}
+
+var weaveCfg weaveConfig
+
 var weaveCmd = &cobra.Command{
 	Use:   "weave",
 	Short: "Generate a graph, activate observer, and serve the graph with dashboard.",
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("test")
+	RunE: func(cmd *cobra.Command, args []string) error {
+		weaveCfg.Seeded = cmd.Flags().Changed("seed")
+		return weave(weaveCfg)
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(weaveCmd)
 
-	// Here you will define your flags and configuration settings.
+	flags := weaveCmd.Flags()
+	flags.StringVarP(&weaveCfg.Addr, "addr", "a", ":8080", "address to serve the graph and dashboard on")
+	flags.StringVar(&weaveCfg.PathPrefix, "path-prefix", "", "path prefix of the root page")
+	flags.StringVar(&weaveCfg.CrawlerIdentifier, "crawler-identifier", "ip",
+		"how crawlers are told apart: ip, user-agent, forwarded-for, header:<name> or query:<name>")
+	flags.StringVar(&weaveCfg.ObserverFile, "observer-file", "",
+		"new file to persist observations to, analyze it later with the dashboard command")
+	flags.Float64Var(&weaveCfg.TimeScale, "time-scale", 1,
+		"simulated seconds per real second, e.g. 3600 evolves the graph an hour per second")
+	flags.Int64Var(&weaveCfg.Seed, "seed", 0, "seed making the generated graph and its evolution reproducible")
+
+	flags.StringVar(&weaveCfg.LoadSnapshot, "load-snapshot", "", "serve and evolve the graph of a snapshot instead of generating one")
+	flags.StringVar(&weaveCfg.SaveSnapshot, "save-snapshot", "", "file to write a snapshot of the graph to once it is generated or loaded")
+	flags.DurationVar(&weaveCfg.SnapshotInterval, "snapshot-interval", 0, "rewrite the snapshot of save-snapshot every interval during evolution, 0 writes it once")
+
+	flags.StringVar(&weaveCfg.Import, "import", "", "serve and evolve the structure of a sitemap, URL list or edge list instead of generating one")
+	flags.StringVar(&weaveCfg.ImportFormat, "import-format", "auto",
+		"format of the imported file: auto, sitemap, urls or edges (edge list or DOT), auto picks it by extension")
+
+	flags.BoolVar(&weaveCfg.Sitemap, "sitemap", false, "serve /sitemap.xml generated from the graph")
+	flags.DurationVar(&weaveCfg.SitemapRefresh, "sitemap-refresh", 0, "regenerate the sitemap at most once per interval, making it stale")
+	flags.Float64Var(&weaveCfg.SitemapOmitFraction, "sitemap-omit", 0, "fraction of pages left out of the sitemap, in [0, 1]")
+
+	flags.BoolVar(&weaveCfg.Robots, "robots", false, "serve /robots.txt and record violations of it")
+	flags.StringSliceVar(&weaveCfg.RobotsDisallow, "robots-disallow", nil, "path prefixes disallowed by robots.txt")
+	flags.Float64Var(&weaveCfg.RobotsDisallowFraction, "robots-disallow-fraction", 0, "fraction of hub subtrees disallowed by robots.txt, in [0, 1]")
+	flags.DurationVar(&weaveCfg.CrawlDelay, "crawl-delay", 0, "crawl delay asked for by robots.txt, 0 omits it")
+
+	flags.StringVar(&weaveCfg.Latency, "latency", "none", "latency distribution of pages: none, fixed, normal or long-tail")
+	flags.DurationVar(&weaveCfg.LatencyMean, "latency-mean", 100*time.Millisecond, "mean latency, the median for long-tail")
+	flags.DurationVar(&weaveCfg.LatencyStddev, "latency-stddev", 50*time.Millisecond, "standard deviation of the normal latency")
+	flags.Float64Var(&weaveCfg.LatencySigma, "latency-sigma", 1, "sigma of the long-tail latency, the larger the longer its tail")
+	flags.StringToStringVar(&weaveCfg.SlowSubtrees, "slow-subtree", nil, "latency factors of subtrees by path prefix, e.g. /archive=5")
+	flags.IntVar(&weaveCfg.Bandwidth, "bandwidth", 0, "bandwidth of page bodies in bytes per second, 0 leaves them unthrottled")
+	flags.Float64Var(&weaveCfg.LoadPeak, "load-peak", 1, "load factor of latency and bandwidth at the peak hour of the day, 1 keeps the load constant")
+	flags.Float64Var(&weaveCfg.LoadPeakHour, "load-peak-hour", 12, "hour of the day of the load peak on the simulation clock, in UTC")
+
+	flags.StringToStringVar(&weaveCfg.Faults, "faults", nil,
+		"rates of faults injected into pages by type: 500, 502, 503, timeout, reset or truncate, e.g. 503=0.05,reset=0.01")
+	flags.StringSliceVar(&weaveCfg.FaultPaths, "fault-paths", nil, "path prefixes faults are scoped to, all paths if empty")
+	flags.StringSliceVar(&weaveCfg.Outages, "outage", nil,
+		"outages answering 503 to every page as start+duration after the start of the simulation, e.g. 30m+10m")
+
+	flags.StringSliceVar(&weaveCfg.Traps, "traps", nil,
+		"spider traps linked from the root page: calendar, session-id, recursion or facets, optionally as type=prefix")
+
+	flags.Float64Var(&weaveCfg.RateLimit, "rate-limit", 0, "requests per second of the simulation allowed to every crawler before 429 Too Many Requests, 0 disables rate limiting")
+	flags.IntVar(&weaveCfg.RateLimitBurst, "rate-limit-burst", 1, "requests a crawler may make at once under rate-limit")
+	flags.IntVar(&weaveCfg.BanThreshold, "ban-threshold", 0, "requests ignoring Retry-After that get a crawler banned with 403 Forbidden, 0 never bans")
+	flags.DurationVar(&weaveCfg.BanDuration, "ban-duration", 10*time.Minute, "how long banned crawlers are answered 403 Forbidden")
+
+	flags.IntVar(&weaveCfg.InitialHubCount, "initial-hubs", 1, "number of hub pages (including root) generated before serving")
+	flags.IntVar(&weaveCfg.InitialAuthCount, "initial-authorities", 0, "number of authority pages generated before serving")
+	flags.IntVar(&weaveCfg.MaxHubCount, "max-hubs", 10, "maximum number of hub pages (including root) reached by evolution")
+	flags.IntVar(&weaveCfg.MaxAuthCount, "max-authorities", 100, "maximum number of authority pages reached by evolution")
+
+	flags.Float64Var(&weaveCfg.HubCreationRate, "hub-rate", 60, "hub pages created per hour during evolution")
+	flags.Float64Var(&weaveCfg.AuthCreationRate, "authority-rate", 600, "authority pages created per hour during evolution")
+	flags.Float64VarP(&weaveCfg.PreferentialAttachment, "preferential-attachment", "p", 0.5,
+		"weight of preferential attachment against uniform choice of parent hub, in [0, 1]")
+
+	flags.StringVar(&weaveCfg.Model, "model", "hub-authority",
+		"graph model: hub-authority, barabasi-albert, copying, erdos-renyi, forest-fire or hierarchical")
+	flags.IntVar(&weaveCfg.ModelLinks, "model-links", 2, "pages a new page attaches to in the barabasi-albert and copying models")
+	flags.Float64Var(&weaveCfg.RandomLinkProbability, "random-link-probability", 0.5, "probability of a random link instead of a copied one in the copying model, in [0, 1]")
+	flags.Float64Var(&weaveCfg.LinkProbability, "link-probability", 0.05, "probability of a link between a new page and any other in the erdos-renyi model, in [0, 1]")
+	flags.Float64Var(&weaveCfg.ForwardBurning, "forward-burning", 0.37, "forward burning probability of the forest-fire model, in [0, 1)")
+	flags.Float64Var(&weaveCfg.BackwardBurning, "backward-burning", 0.32, "backward burning ratio of the forest-fire model, in [0, 1]")
+	flags.IntVar(&weaveCfg.MaxDepth, "max-depth", 3, "depth of detail pages in the hierarchical model, at least 2")
+	flags.IntVar(&weaveCfg.Fanout, "fanout", 0, "hub children per hub in the hierarchical model, 0 leaves it unbounded")
+
+	flags.Float64Var(&weaveCfg.CrossLinksPerPage, "cross-links-per-page", 0, "mean links per page besides the ones to children in the generated graph")
+	flags.Float64Var(&weaveCfg.CrossLinkRate, "cross-link-rate", 0, "links besides the ones to children added per hour during evolution")
+	flags.Float64Var(&weaveCfg.BackLinkProbability, "back-link-probability", 0, "probability of an added link pointing to an ancestor of its page, in [0, 1]")
+
+	flags.Float64Var(&weaveCfg.MoveRate, "move-rate", 0, "pages moved to another hub per hour during evolution, their former paths redirecting, 0 disables moves")
+	flags.IntSliceVar(&weaveCfg.RedirectStatuses, "redirect-statuses", []int{http.StatusMovedPermanently}, "statuses former paths of moved pages redirect with: 301, 302, 307 or 308")
+	flags.Float64Var(&weaveCfg.RedirectLoopProbability, "redirect-loop-probability", 0, "probability of the former path of a moved page redirecting into a loop, in [0, 1]")
+
+	flags.Float64Var(&weaveCfg.HubDeletionRate, "hub-deletion-rate", 0, "hub pages deleted per hour during evolution, 0 disables deletion")
+	flags.Float64Var(&weaveCfg.AuthDeletionRate, "authority-deletion-rate", 0, "authority pages deleted per hour during evolution, 0 disables deletion")
+	flags.StringVar(&weaveCfg.OrphanPolicy, "orphan-policy", string(ggr.OrphanPolicyReparent),
+		"what happens to the subtree of a deleted page: reparent, cascade or dangle")
+	flags.StringVar(&weaveCfg.DeletedPages, "deleted-pages", string(gmx.DeletedPageNotFound),
+		"how paths of deleted pages are answered: 404, 410 or soft-404 (200 with a not found page)")
+	flags.DurationVar(&weaveCfg.DeletedPageRetention, "deleted-page-retention", gmx.DefaultDeletedPageRetention,
+		"how long after their deletion, in simulation time, paths of deleted pages are answered as such, 0 keeps them forever")
+
+	flags.Float64Var(&weaveCfg.DuplicateProbability, "duplicate-probability", 0, "probability of a created authority page duplicating the content of another one, in [0, 1]")
+	flags.Float64Var(&weaveCfg.DuplicateSimilarity, "duplicate-similarity", 1, "share of the duplicated content duplicates keep, 1 for exact duplicates, in [0, 1]")
+	flags.Float64Var(&weaveCfg.URLVariantProbability, "url-variant-probability", 0, "probability of a created page being served under url-variants besides its path, in [0, 1]")
+	flags.StringSliceVar(&weaveCfg.URLVariants, "url-variants", []string{"trailing-slash", "query", "case"},
+		"URL variants serving the same content: trailing-slash, query or case")
+	flags.BoolVar(&weaveCfg.CanonicalTags, "canonical-tags", false, "render rel=canonical links to the path of pages or of the page they duplicate")
+	flags.StringSliceVar(&weaveCfg.URLNormalization, "url-normalization", nil,
+		"URL normalization rules matching request URLs to pages: strict, lenient, case-insensitive or ignore-tracking-params")
+	flags.IntVar(&weaveCfg.HubPageSize, "hub-page-size", 0, "links per page of hubs, newest first, 0 disables pagination")
+	flags.StringVar(&weaveCfg.HubPagination, "hub-pagination", string(hyr.PaginationQuery),
+		"URLs of the pages of hubs beyond the first: query (?page=N) or path (/page/N)")
+
+	flags.Float64Var(&weaveCfg.ChangeRate, "change-rate", 0, "mean content changes per hour of a page, 0 disables modification")
+	flags.StringVar(&weaveCfg.ChangeRateDistribution, "change-rate-distribution", "exponential",
+		"distribution of per-page change rates around change-rate: constant, uniform or exponential")
+}
+
+func (cfg weaveConfig) validate() error {
+	if cfg.TimeScale <= 0 {
+		return errors.New("time-scale must be positive")
+	}
+	if cfg.InitialHubCount < 1 {
+		return errors.New("initial-hubs must be at least 1, the root page is a hub")
+	}
+	if cfg.InitialHubCount > cfg.MaxHubCount || cfg.InitialAuthCount > cfg.MaxAuthCount {
+		return errors.New("initial counts must not exceed max counts")
+	}
+	if cfg.HubCreationRate <= 0 || cfg.AuthCreationRate <= 0 {
+		return errors.New("creation rates must be positive")
+	}
+	if cfg.PreferentialAttachment < 0 || cfg.PreferentialAttachment > 1 {
+		return errors.New("preferential-attachment must be in [0, 1]")
+	}
+	if _, err := cfg.graphModel(); err != nil {
+		return err
+	}
+	if cfg.CrossLinksPerPage < 0 || cfg.CrossLinkRate < 0 {
+		return errors.New("cross links must not be negative")
+	}
+	if cfg.BackLinkProbability < 0 || cfg.BackLinkProbability > 1 {
+		return errors.New("back-link-probability must be in [0, 1]")
+	}
+	if cfg.MoveRate < 0 {
+		return errors.New("move-rate must not be negative")
+	}
+	if len(cfg.RedirectStatuses) == 0 {
+		return errors.New("redirect-statuses must not be empty")
+	}
+	for _, status := range cfg.RedirectStatuses {
+		switch status {
+		case http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		default:
+			return fmt.Errorf("unsupported redirect status %d", status)
+		}
+	}
+	if cfg.RedirectLoopProbability < 0 || cfg.RedirectLoopProbability > 1 {
+		return errors.New("redirect-loop-probability must be in [0, 1]")
+	}
+	if cfg.HubDeletionRate < 0 || cfg.AuthDeletionRate < 0 {
+		return errors.New("deletion rates must not be negative")
+	}
+	switch ggr.OrphanPolicy(cfg.OrphanPolicy) {
+	case ggr.OrphanPolicyReparent, ggr.OrphanPolicyCascade, ggr.OrphanPolicyDangle:
+	default:
+		return fmt.Errorf("unknown orphan-policy %q", cfg.OrphanPolicy)
+	}
+	switch gmx.DeletedPageResponse(cfg.DeletedPages) {
+	case gmx.DeletedPageNotFound, gmx.DeletedPageGone, gmx.DeletedPageSoft404:
+	default:
+		return fmt.Errorf("unknown deleted-pages %q", cfg.DeletedPages)
+	}
+	if cfg.DeletedPageRetention < 0 {
+		return errors.New("deleted-page-retention must not be negative")
+	}
+	if cfg.DuplicateProbability < 0 || cfg.DuplicateProbability > 1 {
+		return errors.New("duplicate-probability must be in [0, 1]")
+	}
+	if cfg.DuplicateSimilarity < 0 || cfg.DuplicateSimilarity > 1 {
+		return errors.New("duplicate-similarity must be in [0, 1]")
+	}
+	if cfg.URLVariantProbability < 0 || cfg.URLVariantProbability > 1 {
+		return errors.New("url-variant-probability must be in [0, 1]")
+	}
+	if _, err := cfg.urlVariants(); err != nil {
+		return err
+	}
+	if _, err := cfg.urlNormalization(); err != nil {
+		return err
+	}
+	if cfg.HubPageSize < 0 {
+		return errors.New("hub-page-size must not be negative")
+	}
+	if _, err := hyr.ParsePaginationStyle(cfg.HubPagination); err != nil {
+		return fmt.Errorf("hub-pagination: %w", err)
+	}
+	if cfg.RobotsDisallowFraction < 0 || cfg.RobotsDisallowFraction > 1 {
+		return errors.New("robots-disallow-fraction must be in [0, 1]")
+	}
+	if cfg.CrawlDelay < 0 {
+		return errors.New("crawl-delay must not be negative")
+	}
+	if _, err := cfg.latencyConfig(); err != nil {
+		return err
+	}
+	if _, err := cfg.faultConfig(time.Time{}); err != nil {
+		return err
+	}
+	if _, err := cfg.traps(); err != nil {
+		return err
+	}
+	if cfg.RateLimit < 0 {
+		return errors.New("rate-limit must not be negative")
+	}
+	if cfg.RateLimitBurst < 1 {
+		return errors.New("rate-limit-burst must be at least 1")
+	}
+	if cfg.BanThreshold < 0 || cfg.BanDuration < 0 {
+		return errors.New("ban-threshold and ban-duration must not be negative")
+	}
+	if cfg.SnapshotInterval < 0 {
+		return errors.New("snapshot-interval must not be negative")
+	}
+	if cfg.SnapshotInterval > 0 && cfg.SaveSnapshot == "" {
+		return errors.New("snapshot-interval requires save-snapshot")
+	}
+	if cfg.Import != "" && cfg.LoadSnapshot != "" {
+		return errors.New("import and load-snapshot are mutually exclusive")
+	}
+	if _, err := cfg.importReader(); err != nil {
+		return err
+	}
+	if cfg.ChangeRate < 0 {
+		return errors.New("change-rate must not be negative")
+	}
+	if _, err := cfg.changeRateDistribution(); err != nil {
+		return err
+	}
+	if _, err := cfg.crawlerIdentifier(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (cfg weaveConfig) crawlerIdentifier() (gmx.CrawlerIdentifier, error) {
+	kind, name, _ := strings.Cut(cfg.CrawlerIdentifier, ":")
+	switch {
+	case kind == "ip":
+		return gmx.IdentifyByIP, nil
+	case kind == "user-agent":
+		return gmx.IdentifyByUserAgent, nil
+	case kind == "forwarded-for":
+		return gmx.IdentifyByForwardedFor, nil
+	case kind == "header" && name != "":
+		return gmx.IdentifyByHeader(name), nil
+	case kind == "query" && name != "":
+		return gmx.IdentifyByQueryParam(name), nil
+	default:
+		return nil, fmt.Errorf("unknown crawler-identifier %q", cfg.CrawlerIdentifier)
+	}
+}
+
+func (cfg weaveConfig) graphModel() (ggr.GraphModel, error) {
+	inUnitInterval := func(name string, value float64) error {
+		if value < 0 || value > 1 {
+			return fmt.Errorf("%s must be in [0, 1]", name)
+		}
+		return nil
+	}
+
+	switch cfg.Model {
+	case "hub-authority":
+		return ggr.HubAuthorityModel{PreferentialAttachment: cfg.PreferentialAttachment}, nil
+	case "barabasi-albert":
+		if cfg.ModelLinks < 1 {
+			return nil, errors.New("model-links must be at least 1")
+		}
+		return ggr.BarabasiAlbertModel{M: cfg.ModelLinks}, nil
+	case "copying":
+		if cfg.ModelLinks < 0 {
+			return nil, errors.New("model-links must not be negative")
+		}
+		if err := inUnitInterval("random-link-probability", cfg.RandomLinkProbability); err != nil {
+			return nil, err
+		}
+		return ggr.CopyingModel{Beta: cfg.RandomLinkProbability, Links: cfg.ModelLinks}, nil
+	case "erdos-renyi":
+		if err := inUnitInterval("link-probability", cfg.LinkProbability); err != nil {
+			return nil, err
+		}
+		return ggr.ErdosRenyiModel{P: cfg.LinkProbability}, nil
+	case "forest-fire":
+		if cfg.ForwardBurning < 0 || cfg.ForwardBurning >= 1 {
+			return nil, errors.New("forward-burning must be in [0, 1)")
+		}
+		if err := inUnitInterval("backward-burning", cfg.BackwardBurning); err != nil {
+			return nil, err
+		}
+		return ggr.ForestFireModel{ForwardBurning: cfg.ForwardBurning, BackwardBurning: cfg.BackwardBurning}, nil
+	case "hierarchical":
+		if cfg.MaxDepth < 2 {
+			return nil, errors.New("max-depth must be at least 2")
+		}
+		if cfg.Fanout < 0 {
+			return nil, errors.New("fanout must not be negative")
+		}
+		return ggr.HierarchicalModel{Depth: cfg.MaxDepth, Fanout: cfg.Fanout}, nil
+	default:
+		return nil, fmt.Errorf("unknown model %q", cfg.Model)
+	}
+}
+
+// latencyConfig returns the latency of served pages, nil if none is injected.
+func (cfg weaveConfig) latencyConfig() (*gmx.LatencyConfig, error) {
+	if cfg.LatencyMean < 0 || cfg.LatencyStddev < 0 || cfg.LatencySigma < 0 {
+		return nil, errors.New("latency parameters must not be negative")
+	}
+	if cfg.Bandwidth < 0 {
+		return nil, errors.New("bandwidth must not be negative")
+	}
+	if cfg.LoadPeak <= 0 {
+		return nil, errors.New("load-peak must be positive")
+	}
+	if cfg.LoadPeakHour < 0 || cfg.LoadPeakHour >= 24 {
+		return nil, errors.New("load-peak-hour must be in [0, 24)")
+	}
+
+	config := gmx.LatencyConfig{Bandwidth: cfg.Bandwidth}
+	switch cfg.Latency {
+	case "none":
+	case "fixed":
+		config.Distribution = gmx.FixedLatency(cfg.LatencyMean)
+	case "normal":
+		config.Distribution = gmx.NormalLatency(cfg.LatencyMean, cfg.LatencyStddev)
+	case "long-tail":
+		config.Distribution = gmx.LongTailLatency(cfg.LatencyMean, cfg.LatencySigma)
+	default:
+		return nil, fmt.Errorf("unknown latency %q", cfg.Latency)
+	}
+
+	if len(cfg.SlowSubtrees) > 0 {
+		config.Slowdowns = make(map[string]float64, len(cfg.SlowSubtrees))
+		for prefix, factor := range cfg.SlowSubtrees {
+			slowdown, err := strconv.ParseFloat(factor, 64)
+			if err != nil || slowdown < 0 {
+				return nil, fmt.Errorf("slow-subtree %s: invalid factor %q", prefix, factor)
+			}
+			config.Slowdowns[prefix] = slowdown
+		}
+	}
+	if cfg.LoadPeak != 1 {
+		config.Load = gmx.DailyLoad(cfg.LoadPeakHour, 1, cfg.LoadPeak)
+	}
+
+	if config.Distribution == nil && config.Bandwidth == 0 {
+		return nil, nil
+	}
+	return &config, nil
+}
+
+var faultTypes = []obs.FaultType{
+	obs.FaultTypeInternalServerError,
+	obs.FaultTypeBadGateway,
+	obs.FaultTypeServiceUnavailable,
+	obs.FaultTypeTimeout,
+	obs.FaultTypeReset,
+	obs.FaultTypeTruncate,
+}
+
+// faultConfig returns the faults injected into pages, outages starting
+// relative to start, nil if none are.
+func (cfg weaveConfig) faultConfig(start time.Time) (*gmx.FaultConfig, error) {
+	config := gmx.FaultConfig{}
+
+	for _, outage := range cfg.Outages {
+		offsetStr, durationStr, ok := strings.Cut(outage, "+")
+		offset, offsetErr := time.ParseDuration(offsetStr)
+		duration, durationErr := time.ParseDuration(durationStr)
+		if !ok || offsetErr != nil || durationErr != nil || offset < 0 || duration <= 0 {
+			return nil, fmt.Errorf("invalid outage %q, want start+duration such as 30m+10m", outage)
+		}
+		config.Rules = append(config.Rules, gmx.FaultRule{
+			Type:       obs.FaultTypeServiceUnavailable,
+			Rate:       1,
+			Paths:      cfg.FaultPaths,
+			From:       start.Add(offset),
+			Until:      start.Add(offset + duration),
+			RetryAfter: duration,
+		})
+	}
+
+	// in a fixed order, so that seeded runs draw the same faults
+	for _, faultType := range faultTypes {
+		rateStr, ok := cfg.Faults[string(faultType)]
+		if !ok {
+			continue
+		}
+		rate, err := strconv.ParseFloat(rateStr, 64)
+		if err != nil || rate < 0 || rate > 1 {
+			return nil, fmt.Errorf("faults %s: rate %q must be in [0, 1]", faultType, rateStr)
+		}
+		config.Rules = append(config.Rules, gmx.FaultRule{Type: faultType, Rate: rate, Paths: cfg.FaultPaths})
+	}
+	for name := range cfg.Faults {
+		if !slices.Contains(faultTypes, obs.FaultType(name)) {
+			return nil, fmt.Errorf("unknown fault type %q", name)
+		}
+	}
+
+	if len(config.Rules) == 0 {
+		return nil, nil
+	}
+	return &config, nil
+}
+
+func (cfg weaveConfig) changeRateDistribution() (ggr.RateDistribution, error) {
+	switch cfg.ChangeRateDistribution {
+	case "constant":
+		return ggr.ConstantRate(cfg.ChangeRate), nil
+	case "uniform":
+		return ggr.UniformRate(0, 2*cfg.ChangeRate), nil
+	case "exponential":
+		return ggr.ExponentialRate(cfg.ChangeRate), nil
+	default:
+		return nil, fmt.Errorf("unknown change-rate-distribution %q", cfg.ChangeRateDistribution)
+	}
+}
+
+func (cfg weaveConfig) clock() clk.Clock {
+	if cfg.TimeScale == 1 {
+		return clk.Real()
+	}
+	return clk.NewScaled(time.Now(), cfg.TimeScale)
+}
+
+func (cfg weaveConfig) openObserver(clock clk.Clock) (*obs.Observer, error) {
+	if cfg.ObserverFile == "" {
+		return obs.New(obs.WithClock(clock)), nil
+	}
+
+	// observations of another graph would never see their nodes deleted
+	if info, err := os.Stat(cfg.ObserverFile); err == nil && info.Size() > 0 {
+		return nil, fmt.Errorf("observer file %s already exists", cfg.ObserverFile)
+	}
+
+	storage, err := obs.OpenFileStorage(cfg.ObserverFile)
+	if err != nil {
+		return nil, fmt.Errorf("opening observer file: %w", err)
+	}
+	return obs.Open(storage, obs.WithClock(clock))
+}
+
+// importReader returns the reader of the import format, picked by the file
+// extension for auto.
+func (cfg weaveConfig) importReader() (func(io.Reader, ...hyr.WebpageOption) (*hyr.Webpage, error), error) {
+	format := cfg.ImportFormat
+	if format == "auto" {
+		switch strings.ToLower(filepath.Ext(cfg.Import)) {
+		case ".xml":
+			format = "sitemap"
+		case ".dot", ".gv", ".edges":
+			format = "edges"
+		default:
+			format = "urls"
+		}
+	}
+
+	switch format {
+	case "sitemap":
+		return hyr.ReadSitemap, nil
+	case "urls":
+		return hyr.ReadURLList, nil
+	case "edges":
+		return hyr.ReadEdgeList, nil
+	default:
+		return nil, fmt.Errorf("unknown import-format %q", cfg.ImportFormat)
+	}
+}
+
+// traps parses the traps flag, whose entries are trap types optionally
+// followed by =prefix.
+func (cfg weaveConfig) traps() ([]*hyr.Trap, error) {
+	traps := make([]*hyr.Trap, 0, len(cfg.Traps))
+	for _, entry := range cfg.Traps {
+		trapType, prefix, _ := strings.Cut(entry, "=")
+		trap, err := hyr.NewTrap(hyr.TrapType(trapType), prefix)
+		if err != nil {
+			return nil, fmt.Errorf("traps: %w", err)
+		}
+		traps = append(traps, trap)
+	}
+	return traps, nil
+}
+
+func (cfg weaveConfig) urlVariants() ([]hyr.URLVariant, error) {
+	variants := make([]hyr.URLVariant, 0, len(cfg.URLVariants))
+	for _, name := range cfg.URLVariants {
+		variant, err := hyr.ParseURLVariant(name)
+		if err != nil {
+			return nil, fmt.Errorf("url-variants: %w", err)
+		}
+		variants = append(variants, variant)
+	}
+	return variants, nil
+}
+
+func (cfg weaveConfig) urlNormalization() ([]gmx.URLNormalization, error) {
+	rules := make([]gmx.URLNormalization, 0, len(cfg.URLNormalization))
+	for _, name := range cfg.URLNormalization {
+		rule, err := gmx.ParseURLNormalization(name)
+		if err != nil {
+			return nil, fmt.Errorf("url-normalization: %w", err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// root loads the graph of the snapshot or the imported structure if one is
+// given, it creates a new root otherwise. The snapshot is returned for the
+// multiplexer to restore its state too.
+func (cfg weaveConfig) root(clock clk.Clock) (*hyr.Webpage, *hyr.Snapshot, error) {
+	var opts []hyr.WebpageOption
+	if cfg.Seeded {
+		opts = append(opts, hyr.WithSeed(cfg.Seed))
+	}
+	if cfg.CanonicalTags {
+		opts = append(opts, hyr.WithCanonicalTag())
+	}
+	if cfg.HubPageSize > 0 {
+		style, _ := hyr.ParsePaginationStyle(cfg.HubPagination)
+		opts = append(opts, hyr.WithPagination(cfg.HubPageSize, style))
+	}
+
+	if cfg.LoadSnapshot != "" {
+		file, err := os.Open(cfg.LoadSnapshot)
+		if err != nil {
+			return nil, nil, fmt.Errorf("opening snapshot: %w", err)
+		}
+		defer file.Close()
+
+		snapshot, err := hyr.DecodeSnapshot(file)
+		if err != nil {
+			return nil, nil, fmt.Errorf("loading snapshot %s: %w", cfg.LoadSnapshot, err)
+		}
+		root, err := snapshot.Build(opts...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("loading snapshot %s: %w", cfg.LoadSnapshot, err)
+		}
+		return root, snapshot, nil
+	}
+
+	if cfg.Import != "" {
+		read, err := cfg.importReader()
+		if err != nil {
+			return nil, nil, err
+		}
+		file, err := os.Open(cfg.Import)
+		if err != nil {
+			return nil, nil, fmt.Errorf("opening import: %w", err)
+		}
+		defer file.Close()
+
+		root, err := read(file, opts...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("importing %s: %w", cfg.Import, err)
+		}
+		return root, nil, nil
+	}
+
+	opts = append(opts, hyr.WithPathPrefix(cfg.PathPrefix))
+	root := hyr.NewWebpage(hyr.WebpageTypeHub, opts...)
+	root.CreatedAt = clock.Now().UTC()
+	return root, nil, nil
+}
+
+// writeSnapshot replaces the snapshot file atomically, so that a checkpoint is
+// never left half written.
+func writeSnapshot(mux *gmx.GraphMux, path string) error {
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if err := mux.WriteSnapshot(file); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
+
+func checkpoint(mux *gmx.GraphMux, path string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := writeSnapshot(mux, path); err != nil {
+			fmt.Fprintf(os.Stderr, "writing snapshot: %v\n", err)
+		}
+	}
+}
+
+func weave(cfg weaveConfig) error {
+	if err := cfg.validate(); err != nil {
+		return err
+	}
+
+	clock := cfg.clock()
+	root, snapshot, err := cfg.root(clock)
+	if err != nil {
+		return err
+	}
+	// traps given on the command line replace the ones of a snapshot
+	if traps, _ := cfg.traps(); len(traps) > 0 {
+		root.Traps = traps
+	}
+
+	model, _ := cfg.graphModel()
+	generatorOpts := []ggr.GraphGeneratorOption{
+		ggr.WithModel(model),
+		ggr.WithClock(clock),
+		ggr.WithDeletionRates(cfg.HubDeletionRate, cfg.AuthDeletionRate),
+		ggr.WithOrphanPolicy(ggr.OrphanPolicy(cfg.OrphanPolicy)),
+		ggr.WithCrossLinks(cfg.CrossLinksPerPage, cfg.CrossLinkRate),
+		ggr.WithBackLinkProbability(cfg.BackLinkProbability),
+		ggr.WithMoves(cfg.MoveRate, cfg.RedirectStatuses...),
+		ggr.WithRedirectLoopProbability(cfg.RedirectLoopProbability),
+		ggr.WithDuplicates(cfg.DuplicateProbability, cfg.DuplicateSimilarity),
+	}
+	if cfg.Seeded {
+		generatorOpts = append(generatorOpts, ggr.WithSeed(cfg.Seed))
+	}
+	if variants, _ := cfg.urlVariants(); len(variants) > 0 {
+		generatorOpts = append(generatorOpts, ggr.WithURLVariants(cfg.URLVariantProbability, variants...))
+	}
+	if cfg.ChangeRate > 0 {
+		distribution, _ := cfg.changeRateDistribution()
+		generatorOpts = append(generatorOpts, ggr.WithChangeRateDistribution(distribution))
+	}
+	// loaded and imported graphs may have more pages than the maxima
+	if cfg.LoadSnapshot != "" || cfg.Import != "" {
+		generatorOpts = append(generatorOpts, ggr.WithExceededMaxima())
+	}
+
+	generator := ggr.New(root, cfg.PreferentialAttachment, generatorOpts...)
+	if cfg.LoadSnapshot == "" && cfg.Import == "" {
+		if err := generator.Generate(cfg.InitialHubCount, cfg.InitialAuthCount); err != nil {
+			return fmt.Errorf("generating initial graph: %w", err)
+		}
+	}
+	observer, err := cfg.openObserver(clock)
+	if err != nil {
+		return err
+	}
+	defer observer.Close()
+
+	crawlerIdentifier, _ := cfg.crawlerIdentifier()
+	muxOpts := []gmx.GraphMuxOption{
+		gmx.WithObserver(observer),
+		gmx.WithCrawlerIdentifier(crawlerIdentifier),
+		gmx.WithClock(clock),
+		gmx.WithDeletedPageResponse(gmx.DeletedPageResponse(cfg.DeletedPages)),
+		gmx.WithDeletedPageRetention(cfg.DeletedPageRetention),
+	}
+	if rules, _ := cfg.urlNormalization(); len(rules) > 0 {
+		muxOpts = append(muxOpts, gmx.WithURLNormalization(rules...))
+	}
+	if cfg.Sitemap {
+		muxOpts = append(muxOpts, gmx.WithSitemap(gmx.SitemapConfig{
+			RefreshInterval: cfg.SitemapRefresh,
+			OmitFraction:    cfg.SitemapOmitFraction,
+		}))
+	}
+	if cfg.Robots {
+		muxOpts = append(muxOpts, gmx.WithRobots(gmx.RobotsConfig{
+			Disallow:         cfg.RobotsDisallow,
+			DisallowFraction: cfg.RobotsDisallowFraction,
+			CrawlDelay:       cfg.CrawlDelay,
+		}))
+	}
+	if latency, _ := cfg.latencyConfig(); latency != nil {
+		muxOpts = append(muxOpts, gmx.WithLatency(*latency))
+	}
+	if faults, _ := cfg.faultConfig(clock.Now()); faults != nil {
+		muxOpts = append(muxOpts, gmx.WithFaults(*faults))
+	}
+	if cfg.RateLimit > 0 {
+		muxOpts = append(muxOpts, gmx.WithRateLimit(gmx.RateLimitConfig{
+			Rate:         cfg.RateLimit,
+			Burst:        cfg.RateLimitBurst,
+			BanThreshold: cfg.BanThreshold,
+			BanDuration:  cfg.BanDuration,
+		}))
+	}
+	if cfg.Seeded {
+		muxOpts = append(muxOpts, gmx.WithSeed(cfg.Seed))
+	}
+	if snapshot != nil {
+		muxOpts = append(muxOpts, gmx.WithSnapshot(snapshot))
+	}
+
+	mux, err := gmx.New(root, muxOpts...)
+	if err != nil {
+		return fmt.Errorf("creating graph multiplexer: %w", err)
+	}
+	mux.ActivateDashboard(dsh.NewDashboard(root, observer))
+
+	if cfg.SaveSnapshot != "" {
+		if err := writeSnapshot(mux, cfg.SaveSnapshot); err != nil {
+			return fmt.Errorf("writing snapshot: %w", err)
+		}
+	}
 
-	// Cobra supports Persistent Flags which will work for this command
-	// and all subcommands, e.g.:
-	// weaveCmd.PersistentFlags().String("foo", "", "A help for foo")
+	updateChan, errChan, err := generator.StartGraphEvolution(
+		cfg.MaxHubCount, cfg.MaxAuthCount,
+		cfg.AuthCreationRate, cfg.HubCreationRate,
+	)
+	if err != nil {
+		return fmt.Errorf("starting graph evolution: %w", err)
+	}
+	go mux.SyncGraph(updateChan, errChan)
+	if cfg.SnapshotInterval > 0 {
+		go checkpoint(mux, cfg.SaveSnapshot, cfg.SnapshotInterval)
+	}
 
-	// Cobra supports local flags which will only run when this command
-	// is called directly, e.g.:
-	// weaveCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	fmt.Printf("serving graph on %s, dashboard on %s/dashboard\n", cfg.Addr, cfg.Addr)
+	return http.ListenAndServe(cfg.Addr, mux)
 }
@@ -2,8 +2,9 @@ package main
 
 import (
 	"fmt"
+	"net/http"
 
-	gr "github.com/sdqri/sequined/internal/graphrouter"
+	gmx "github.com/sdqri/sequined/internal/graphmultiplexer"
 	hr "github.com/sdqri/sequined/internal/hyperrenderer"
 )
 
@@ -18,8 +19,14 @@ func main() {
 
 	page2.Faker().Paragraph(5, 50, 500, "|")
 
+	mux, err := gmx.New(root)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
 	// Run the HTTP server
-	if err := gr.Run(":8080", root); err != nil {
+	if err := http.ListenAndServe(":8080", mux); err != nil {
 		fmt.Println("Error:", err)
 	}
 }
@@ -4,20 +4,21 @@ go 1.22.0
 
 require (
 	github.com/brianvoe/gofakeit/v7 v7.0.2
+	github.com/go-echarts/go-echarts/v2 v2.3.3
 	github.com/goccy/go-graphviz v0.1.2
+	github.com/pkg/errors v0.9.1
+	github.com/spf13/cobra v1.8.0
+	github.com/stretchr/testify v1.9.0
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/fogleman/gg v1.3.0 // indirect
-	github.com/go-echarts/go-echarts/v2 v2.3.3 // indirect
 	github.com/golang/freetype v0.0.0-20170609003504-e2365dfdc4a0 // indirect
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
-	github.com/pkg/errors v0.9.1 // indirect
+	github.com/kr/text v0.2.0 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	github.com/spf13/cobra v1.8.0 // indirect
 	github.com/spf13/pflag v1.0.5 // indirect
-	github.com/stretchr/testify v1.9.0 // indirect
 	golang.org/x/image v0.14.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
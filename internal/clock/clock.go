@@ -0,0 +1,297 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the time and schedules ticks, so that simulations can run on a
+// time other than the wall clock.
+type Clock interface {
+	Now() time.Time
+	NewTicker(d time.Duration) Ticker
+	NewTimer(d time.Duration) Timer
+}
+
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+type Timer interface {
+	C() <-chan time.Time
+	// Stop reports whether the timer was stopped before it fired.
+	Stop() bool
+}
+
+type realClock struct{}
+
+type realTicker struct {
+	*time.Ticker
+}
+
+type realTimer struct {
+	*time.Timer
+}
+
+// Real returns the wall clock.
+func Real() Clock {
+	return realClock{}
+}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+func (ticker realTicker) C() <-chan time.Time {
+	return ticker.Ticker.C
+}
+
+func (timer realTimer) C() <-chan time.Time {
+	return timer.Timer.C
+}
+
+// ScaledClock runs factor times faster than the wall clock, e.g. a factor of
+// 3600 makes a real second a simulated hour.
+type ScaledClock struct {
+	start     time.Time
+	realStart time.Time
+	factor    float64
+}
+
+type scaledTicker struct {
+	ticker *time.Ticker
+	c      chan time.Time
+	done   chan struct{}
+	once   sync.Once
+}
+
+type scaledTimer struct {
+	timer *time.Timer
+	c     chan time.Time
+}
+
+// NewScaled returns a clock starting at start and running factor times faster
+// than the wall clock.
+func NewScaled(start time.Time, factor float64) *ScaledClock {
+	return &ScaledClock{
+		start:     start,
+		realStart: time.Now(),
+		factor:    factor,
+	}
+}
+
+func (clock *ScaledClock) Now() time.Time {
+	return clock.simulated(time.Now())
+}
+
+func (clock *ScaledClock) simulated(t time.Time) time.Time {
+	return clock.start.Add(time.Duration(float64(t.Sub(clock.realStart)) * clock.factor))
+}
+
+// real converts a simulated duration to a positive wall clock duration.
+func (clock *ScaledClock) real(d time.Duration) time.Duration {
+	return max(time.Duration(float64(d)/clock.factor), 1)
+}
+
+func (clock *ScaledClock) NewTicker(d time.Duration) Ticker {
+	ticker := &scaledTicker{
+		ticker: time.NewTicker(clock.real(d)),
+		c:      make(chan time.Time, 1),
+		done:   make(chan struct{}),
+	}
+	go func() {
+		for {
+			select {
+			case t := <-ticker.ticker.C:
+				// drop ticks for slow receivers like time.Ticker does
+				select {
+				case ticker.c <- clock.simulated(t):
+				default:
+				}
+			case <-ticker.done:
+				return
+			}
+		}
+	}()
+	return ticker
+}
+
+func (clock *ScaledClock) NewTimer(d time.Duration) Timer {
+	timer := &scaledTimer{c: make(chan time.Time, 1)}
+	timer.timer = time.AfterFunc(clock.real(d), func() {
+		timer.c <- clock.Now()
+	})
+	return timer
+}
+
+func (ticker *scaledTicker) C() <-chan time.Time {
+	return ticker.c
+}
+
+func (ticker *scaledTicker) Stop() {
+	ticker.ticker.Stop()
+	ticker.once.Do(func() { close(ticker.done) })
+}
+
+func (timer *scaledTimer) C() <-chan time.Time {
+	return timer.c
+}
+
+func (timer *scaledTimer) Stop() bool {
+	return timer.timer.Stop()
+}
+
+// ManualClock only moves when advanced, for tests. Unlike the other clocks it
+// does not drop ticks: Advance delivers every tick and timer due in
+// chronological order, waiting for each to be received.
+type ManualClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []*manualWaiter
+	// added is signaled whenever a waiter is added.
+	added *sync.Cond
+}
+
+// manualWaiter is a ticker if it has a period, a timer otherwise.
+type manualWaiter struct {
+	clock    *ManualClock
+	c        chan time.Time
+	deadline time.Time
+	period   time.Duration
+	stopped  chan struct{}
+	once     sync.Once
+}
+
+type manualTicker struct {
+	*manualWaiter
+}
+
+func NewManual(start time.Time) *ManualClock {
+	clock := &ManualClock{now: start}
+	clock.added = sync.NewCond(&clock.mu)
+	return clock
+}
+
+func (clock *ManualClock) Now() time.Time {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+
+	return clock.now
+}
+
+func (clock *ManualClock) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("non-positive interval for NewTicker")
+	}
+	return manualTicker{clock.addWaiter(d, d)}
+}
+
+func (clock *ManualClock) NewTimer(d time.Duration) Timer {
+	return clock.addWaiter(d, 0)
+}
+
+func (clock *ManualClock) addWaiter(d time.Duration, period time.Duration) *manualWaiter {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+
+	waiter := &manualWaiter{
+		clock:    clock,
+		c:        make(chan time.Time),
+		deadline: clock.now.Add(d),
+		period:   period,
+		stopped:  make(chan struct{}),
+	}
+	clock.waiters = append(clock.waiters, waiter)
+	clock.added.Broadcast()
+	return waiter
+}
+
+// BlockUntil waits until at least n tickers and timers wait on the clock, e.g.
+// for a goroutine to catch up with the last Advance before the next one.
+func (clock *ManualClock) BlockUntil(n int) {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+
+	for len(clock.waiters) < n {
+		clock.added.Wait()
+	}
+}
+
+// removeWaiter reports whether the waiter was still scheduled, the clock must
+// be locked.
+func (clock *ManualClock) removeWaiter(waiter *manualWaiter) bool {
+	for i, w := range clock.waiters {
+		if w == waiter {
+			clock.waiters = append(clock.waiters[:i], clock.waiters[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Advance moves the clock forward by d, firing due tickers and timers on the
+// way. Waiters created while advancing are scheduled relative to the time
+// they are created at and fire within the same call if they are due by then.
+// Advance must not be called concurrently.
+func (clock *ManualClock) Advance(d time.Duration) {
+	clock.mu.Lock()
+	target := clock.now.Add(d)
+	clock.mu.Unlock()
+
+	for {
+		clock.mu.Lock()
+		var next *manualWaiter
+		for _, waiter := range clock.waiters {
+			if !waiter.deadline.After(target) && (next == nil || waiter.deadline.Before(next.deadline)) {
+				next = waiter
+			}
+		}
+		if next == nil {
+			clock.now = target
+			clock.mu.Unlock()
+			return
+		}
+
+		deadline := next.deadline
+		if deadline.After(clock.now) {
+			clock.now = deadline
+		}
+		if next.period > 0 {
+			next.deadline = next.deadline.Add(next.period)
+		} else {
+			clock.removeWaiter(next)
+		}
+		clock.mu.Unlock()
+
+		select {
+		case next.c <- deadline:
+		case <-next.stopped:
+		}
+	}
+}
+
+func (waiter *manualWaiter) C() <-chan time.Time {
+	return waiter.c
+}
+
+func (waiter *manualWaiter) Stop() bool {
+	waiter.clock.mu.Lock()
+	active := waiter.clock.removeWaiter(waiter)
+	waiter.clock.mu.Unlock()
+
+	waiter.once.Do(func() { close(waiter.stopped) })
+	return active
+}
+
+func (ticker manualTicker) Stop() {
+	ticker.manualWaiter.Stop()
+}
@@ -0,0 +1,132 @@
+package clock_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sdqri/sequined/internal/clock"
+	"github.com/stretchr/testify/assert"
+)
+
+func receiveAll(c <-chan time.Time, done <-chan struct{}) <-chan []time.Time {
+	result := make(chan []time.Time, 1)
+	go func() {
+		ticks := make([]time.Time, 0)
+		for {
+			select {
+			case t := <-c:
+				ticks = append(ticks, t)
+			case <-done:
+				result <- ticks
+				return
+			}
+		}
+	}()
+	return result
+}
+
+func TestManualClock(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		interval      time.Duration
+		isTicker      bool
+		advance       time.Duration
+		expectedTicks []time.Time
+	}{
+		{"ticker", time.Minute, true, 3*time.Minute + time.Second, []time.Time{
+			start.Add(time.Minute), start.Add(2 * time.Minute), start.Add(3 * time.Minute),
+		}},
+		{"ticker not due", time.Minute, true, 59 * time.Second, []time.Time{}},
+		{"timer", time.Minute, false, time.Hour, []time.Time{start.Add(time.Minute)}},
+		{"timer due exactly", time.Minute, false, time.Minute, []time.Time{start.Add(time.Minute)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clk := clock.NewManual(start)
+			var c <-chan time.Time
+			if tt.isTicker {
+				ticker := clk.NewTicker(tt.interval)
+				defer ticker.Stop()
+				c = ticker.C()
+			} else {
+				c = clk.NewTimer(tt.interval).C()
+			}
+
+			done := make(chan struct{})
+			result := receiveAll(c, done)
+			clk.Advance(tt.advance)
+			close(done)
+
+			assert.Equal(t, tt.expectedTicks, <-result)
+			assert.Equal(t, start.Add(tt.advance), clk.Now())
+		})
+	}
+}
+
+func TestManualClockStop(t *testing.T) {
+	clk := clock.NewManual(time.Now())
+
+	timer := clk.NewTimer(time.Minute)
+	assert.True(t, timer.Stop())
+	assert.False(t, timer.Stop())
+
+	// an unreceived tick of a stopped ticker does not block Advance
+	ticker := clk.NewTicker(time.Minute)
+	go func() {
+		<-ticker.C()
+		ticker.Stop()
+	}()
+	clk.Advance(time.Hour)
+}
+
+func TestScaledClock(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clk := clock.NewScaled(start, 3600)
+
+	timer := clk.NewTimer(time.Hour)
+	select {
+	case at := <-timer.C():
+		assert.False(t, at.Before(start.Add(time.Hour)))
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "Timer of a simulated hour should fire after a real second")
+	}
+
+	ticker := clk.NewTicker(time.Minute)
+	defer ticker.Stop()
+	previous := <-ticker.C()
+	next := <-ticker.C()
+	assert.True(t, next.After(previous))
+	assert.True(t, clk.Now().After(start.Add(time.Hour)))
+}
+
+func TestRealClock(t *testing.T) {
+	clk := clock.Real()
+	before := time.Now()
+	assert.False(t, clk.Now().Before(before))
+
+	timer := clk.NewTimer(time.Millisecond)
+	<-timer.C()
+	ticker := clk.NewTicker(time.Millisecond)
+	<-ticker.C()
+	ticker.Stop()
+}
+
+func TestManualClockBlockUntil(t *testing.T) {
+	clk := clock.NewManual(time.Now())
+	ticked := make(chan struct{})
+	go func() {
+		for i := 0; i < 3; i++ {
+			<-clk.NewTimer(time.Minute).C()
+		}
+		close(ticked)
+	}()
+
+	for i := 0; i < 3; i++ {
+		clk.BlockUntil(1)
+		clk.Advance(time.Minute)
+	}
+	<-ticked
+}
@@ -22,12 +22,30 @@ var dashboardTmpl string
 type Dashboard struct {
 	observer *obs.Observer
 	root     *hyr.Webpage
+	// now returns the end of the time range charts cover.
+	now func() time.Time
 }
 
-func NewDashboard(root *hyr.Webpage, observer *obs.Observer) *Dashboard {
-	return &Dashboard{
+type DashboardOption func(*Dashboard)
+
+func NewDashboard(root *hyr.Webpage, observer *obs.Observer, opts ...DashboardOption) *Dashboard {
+	dashboard := &Dashboard{
 		observer: observer,
 		root:     root,
+		now:      observer.Now,
+	}
+
+	for _, opt := range opts {
+		opt(dashboard)
+	}
+	return dashboard
+}
+
+// WithNow sets the end of the time range charts cover, e.g. to the last
+// activity of stored observations.
+func WithNow(now func() time.Time) DashboardOption {
+	return func(dashboard *Dashboard) {
+		dashboard.now = now
 	}
 }
 
@@ -35,6 +53,11 @@ func (dashboard *Dashboard) HandleBy(mux *http.ServeMux) {
 	mux.HandleFunc("/dashboard", dashboard.HandleMainPage)
 	mux.HandleFunc("/charts/freshness", dashboard.HandleFreshnessChart)
 	mux.HandleFunc("/charts/age", dashboard.HandleAgeChart)
+	mux.HandleFunc("/charts/violations", dashboard.HandleViolationsChart)
+	mux.HandleFunc("/charts/fetches", dashboard.HandleFetchesChart)
+	mux.HandleFunc("/charts/throttling", dashboard.HandleThrottlingChart)
+	mux.HandleFunc("/charts/stale-index", dashboard.HandleStaleIndexChart)
+	mux.HandleFunc("/charts/duplicates", dashboard.HandleDuplicatesChart)
 	mux.HandleFunc("/charts/tree", dashboard.HandleTreeChart)
 }
 
@@ -44,14 +67,19 @@ func (dashboard *Dashboard) HandleMainPage(w http.ResponseWriter, r *http.Reques
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	err = dashboardTemplate.Execute(w, nil)
+	data := struct {
+		CrawlerIDs []obs.CrawlerID
+	}{
+		CrawlerIDs: dashboard.observer.GetCrawlerIDs(),
+	}
+	err = dashboardTemplate.Execute(w, data)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 }
 
-func (dashboard *Dashboard) GetFreshnessChart(bucketDuration time.Duration, duration time.Duration, ip string) *charts.Line {
+func (dashboard *Dashboard) GetFreshnessChart(bucketDuration time.Duration, duration time.Duration, crawlerID string) *charts.Line {
 	line := charts.NewLine()
 	line.SetGlobalOptions(
 		charts.WithTitleOpts(opts.Title{
@@ -64,7 +92,7 @@ func (dashboard *Dashboard) GetFreshnessChart(bucketDuration time.Duration, dura
 		}),
 	)
 
-	now := time.Now().UTC()
+	now := dashboard.now().UTC()
 	numBuckets := int(duration / bucketDuration)
 	buckets := make([]time.Time, 0, numBuckets)
 	for i := 0; i < numBuckets; i++ {
@@ -73,21 +101,19 @@ func (dashboard *Dashboard) GetFreshnessChart(bucketDuration time.Duration, dura
 	slices.Reverse(buckets)
 
 	freshnessSeries := make([]opts.LineData, numBuckets)
+	coverageSeries := make([]opts.LineData, numBuckets)
 
 	for i := 0; i < numBuckets; i++ {
-		freshness := dashboard.observer.GetFreshness(ip, buckets[i])
+		freshness := dashboard.observer.GetAverageFreshness(crawlerID, buckets[i])
 		freshnessSeries[i] = opts.LineData{Value: freshness}
+		coverage := dashboard.observer.GetFreshness(crawlerID, buckets[i])
+		coverageSeries[i] = opts.LineData{Value: coverage}
 	}
 
 	xs := ConvertToHHMMSS(buckets)
 	line.SetXAxis(xs).
 		AddSeries("Freshness", freshnessSeries).
-		SetSeriesOptions(
-			charts.WithLineChartOpts(
-				opts.LineChart{
-					Stack: "stack",
-				}),
-		)
+		AddSeries("Coverage", coverageSeries)
 
 	return line
 }
@@ -95,7 +121,7 @@ func (dashboard *Dashboard) GetFreshnessChart(bucketDuration time.Duration, dura
 func (dashboard *Dashboard) HandleFreshnessChart(w http.ResponseWriter, r *http.Request) {
 	bucketDurationStr := r.URL.Query().Get("bucket-duration")
 	durationStr := r.URL.Query().Get("duration")
-	ip := r.URL.Query().Get("ip")
+	crawlerID := getCrawlerID(r)
 
 	bucketDuration, err := time.ParseDuration(bucketDurationStr)
 	if err != nil {
@@ -109,7 +135,7 @@ func (dashboard *Dashboard) HandleFreshnessChart(w http.ResponseWriter, r *http.
 		return
 	}
 
-	freshnessChart := dashboard.GetFreshnessChart(bucketDuration, duration, ip)
+	freshnessChart := dashboard.GetFreshnessChart(bucketDuration, duration, crawlerID)
 	snippetRenderer := snippetrenderer.NewSnippetRenderer(freshnessChart, freshnessChart.Validate)
 	err = snippetRenderer.Render(w)
 	if err != nil {
@@ -119,7 +145,7 @@ func (dashboard *Dashboard) HandleFreshnessChart(w http.ResponseWriter, r *http.
 
 }
 
-func (dashboard *Dashboard) GetAgeChart(bucketDuration time.Duration, duration time.Duration, ip string) *charts.Line {
+func (dashboard *Dashboard) GetAgeChart(bucketDuration time.Duration, duration time.Duration, crawlerID string) *charts.Line {
 	line := charts.NewLine()
 	line.SetGlobalOptions(
 		charts.WithTitleOpts(opts.Title{
@@ -130,7 +156,7 @@ func (dashboard *Dashboard) GetAgeChart(bucketDuration time.Duration, duration t
 		}),
 	)
 
-	now := time.Now().UTC()
+	now := dashboard.now().UTC()
 	numBuckets := int(duration / bucketDuration)
 	buckets := make([]time.Time, 0, numBuckets)
 	for i := 0; i < numBuckets; i++ {
@@ -139,21 +165,19 @@ func (dashboard *Dashboard) GetAgeChart(bucketDuration time.Duration, duration t
 	slices.Reverse(buckets)
 
 	ageSeries := make([]opts.LineData, numBuckets)
+	discoveryAgeSeries := make([]opts.LineData, numBuckets)
 
 	for i := 0; i < numBuckets; i++ {
-		age := dashboard.observer.GetAge(ip, buckets[i])
+		age := dashboard.observer.GetAverageAge(crawlerID, buckets[i])
 		ageSeries[i] = opts.LineData{Value: age.Seconds()}
+		discoveryAge := dashboard.observer.GetAge(crawlerID, buckets[i])
+		discoveryAgeSeries[i] = opts.LineData{Value: discoveryAge.Seconds()}
 	}
 
 	xs := ConvertToHHMMSS(buckets)
 	line.SetXAxis(xs).
 		AddSeries("Age (seconds)", ageSeries).
-		SetSeriesOptions(
-			charts.WithLineChartOpts(
-				opts.LineChart{
-					Stack: "stack",
-				}),
-		)
+		AddSeries("Discovery age (seconds)", discoveryAgeSeries)
 
 	return line
 }
@@ -161,7 +185,7 @@ func (dashboard *Dashboard) GetAgeChart(bucketDuration time.Duration, duration t
 func (dashboard *Dashboard) HandleAgeChart(w http.ResponseWriter, r *http.Request) {
 	bucketDurationStr := r.URL.Query().Get("bucket-duration")
 	durationStr := r.URL.Query().Get("duration")
-	ip := r.URL.Query().Get("ip")
+	crawlerID := getCrawlerID(r)
 
 	bucketDuration, err := time.ParseDuration(bucketDurationStr)
 	if err != nil {
@@ -175,7 +199,7 @@ func (dashboard *Dashboard) HandleAgeChart(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	ageChart := dashboard.GetAgeChart(bucketDuration, duration, ip)
+	ageChart := dashboard.GetAgeChart(bucketDuration, duration, crawlerID)
 	err = ageChart.Render(w)
 	if err != nil {
 		http.Error(w, "Failed to render charts", http.StatusInternalServerError)
@@ -183,7 +207,152 @@ func (dashboard *Dashboard) HandleAgeChart(w http.ResponseWriter, r *http.Reques
 	}
 }
 
+func (dashboard *Dashboard) GetViolationsChart(bucketDuration time.Duration, duration time.Duration, crawlerID string) *charts.Line {
+	line := charts.NewLine()
+	line.SetGlobalOptions(
+		charts.WithTitleOpts(opts.Title{
+			Title: "robots.txt Violations - Last " + duration.String(),
+		}),
+		charts.WithYAxisOpts(opts.YAxis{
+			Type: "value",
+			Min:  0,
+		}),
+	)
+
+	now := dashboard.now().UTC()
+	numBuckets := int(duration / bucketDuration)
+	buckets := make([]time.Time, 0, numBuckets)
+	for i := 0; i < numBuckets; i++ {
+		buckets = append(buckets, now.Add(-time.Duration(i*int(bucketDuration))))
+	}
+	slices.Reverse(buckets)
+
+	disallowedSeries := make([]opts.LineData, numBuckets)
+	crawlDelaySeries := make([]opts.LineData, numBuckets)
+	retryAfterSeries := make([]opts.LineData, numBuckets)
+
+	for i := 0; i < numBuckets; i++ {
+		counts := dashboard.observer.GetViolationCounts(crawlerID, buckets[i])
+		disallowedSeries[i] = opts.LineData{Value: counts[obs.ViolationTypeDisallowed]}
+		crawlDelaySeries[i] = opts.LineData{Value: counts[obs.ViolationTypeCrawlDelay]}
+		retryAfterSeries[i] = opts.LineData{Value: counts[obs.ViolationTypeRetryAfter]}
+	}
+
+	xs := ConvertToHHMMSS(buckets)
+	line.SetXAxis(xs).
+		AddSeries("Disallowed visits", disallowedSeries).
+		AddSeries("Crawl-delay violations", crawlDelaySeries).
+		AddSeries("Retry-After violations", retryAfterSeries)
+
+	return line
+}
+
+func (dashboard *Dashboard) HandleViolationsChart(w http.ResponseWriter, r *http.Request) {
+	bucketDurationStr := r.URL.Query().Get("bucket-duration")
+	durationStr := r.URL.Query().Get("duration")
+	crawlerID := getCrawlerID(r)
+
+	bucketDuration, err := time.ParseDuration(bucketDurationStr)
+	if err != nil {
+		http.Error(w, "Invalid bucketDuration", http.StatusBadRequest)
+		return
+	}
+
+	duration, err := time.ParseDuration(durationStr)
+	if err != nil {
+		http.Error(w, "Invalid duration", http.StatusBadRequest)
+		return
+	}
+
+	violationsChart := dashboard.GetViolationsChart(bucketDuration, duration, crawlerID)
+	err = violationsChart.Render(w)
+	if err != nil {
+		http.Error(w, "Failed to render charts", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (dashboard *Dashboard) GetFetchesChart(bucketDuration time.Duration, duration time.Duration, crawlerID string) *charts.Line {
+	line := charts.NewLine()
+	line.SetGlobalOptions(
+		charts.WithTitleOpts(opts.Title{
+			Title: "Fetches - Last " + duration.String(),
+		}),
+		charts.WithYAxisOpts(opts.YAxis{
+			Type: "value",
+			Min:  0,
+		}),
+	)
+
+	now := dashboard.now().UTC()
+	numBuckets := int(duration / bucketDuration)
+	buckets := make([]time.Time, 0, numBuckets)
+	for i := 0; i < numBuckets; i++ {
+		buckets = append(buckets, now.Add(-time.Duration(i*int(bucketDuration))))
+	}
+	slices.Reverse(buckets)
+
+	fullFetchSeries := make([]opts.LineData, numBuckets)
+	revalidationSeries := make([]opts.LineData, numBuckets)
+	trapSeries := make([]opts.LineData, numBuckets)
+
+	for i := 0; i < numBuckets; i++ {
+		fullFetches, revalidations := dashboard.observer.GetFetchCounts(crawlerID, buckets[i])
+		fullFetchSeries[i] = opts.LineData{Value: fullFetches}
+		revalidationSeries[i] = opts.LineData{Value: revalidations}
+		trapRequests := 0
+		for _, count := range dashboard.observer.GetTrapRequests(crawlerID, buckets[i]) {
+			trapRequests += count
+		}
+		trapSeries[i] = opts.LineData{Value: trapRequests}
+	}
+
+	xs := ConvertToHHMMSS(buckets)
+	line.SetXAxis(xs).
+		AddSeries("Full fetches", fullFetchSeries).
+		AddSeries("304 revalidations", revalidationSeries).
+		AddSeries("Wasted in spider traps", trapSeries)
+
+	return line
+}
+
+func (dashboard *Dashboard) HandleFetchesChart(w http.ResponseWriter, r *http.Request) {
+	bucketDurationStr := r.URL.Query().Get("bucket-duration")
+	durationStr := r.URL.Query().Get("duration")
+	crawlerID := getCrawlerID(r)
+
+	bucketDuration, err := time.ParseDuration(bucketDurationStr)
+	if err != nil {
+		http.Error(w, "Invalid bucketDuration", http.StatusBadRequest)
+		return
+	}
+
+	duration, err := time.ParseDuration(durationStr)
+	if err != nil {
+		http.Error(w, "Invalid duration", http.StatusBadRequest)
+		return
+	}
+
+	fetchesChart := dashboard.GetFetchesChart(bucketDuration, duration, crawlerID)
+	err = fetchesChart.Render(w)
+	if err != nil {
+		http.Error(w, "Failed to render charts", http.StatusInternalServerError)
+		return
+	}
+}
+
+// getCrawlerID reads the crawler query parameter, falling back to the legacy ip parameter.
+func getCrawlerID(r *http.Request) string {
+	if crawlerID := r.URL.Query().Get("crawler"); crawlerID != "" {
+		return crawlerID
+	}
+	return r.URL.Query().Get("ip")
+}
+
 func (dashboard *Dashboard) A() *[]opts.TreeData {
+	dashboard.root.RLockGraph()
+	defer dashboard.root.RUnlockGraph()
+
 	return GetTreeData(dashboard.root)
 }
 
@@ -192,8 +361,12 @@ func GetTreeData(root *hyr.Webpage) *[]opts.TreeData {
 
 	var f func(*hyr.Webpage, *[]*opts.TreeData)
 	f = func(node *hyr.Webpage, treeData *[]*opts.TreeData) {
+		// the tree of parents spans the graph, other links would form cycles
 		children := make([]*opts.TreeData, 0)
-		for _, child := range node.Links {
+		for _, child := range node.Children() {
+			if child.Deleted {
+				continue
+			}
 			f(child, &children)
 		}
 
@@ -237,7 +410,9 @@ func (dashboard *Dashboard) GetTreeChart() *charts.Tree {
 		}),
 	)
 
+	dashboard.root.RLockGraph()
 	treeData := GetTreeData(dashboard.root)
+	dashboard.root.RUnlockGraph()
 	tree.AddSeries("Root", *treeData).SetSeriesOptions(
 		charts.WithTreeOpts(
 			opts.TreeChart{
@@ -256,6 +431,12 @@ func (dashboard *Dashboard) GetTreeChart() *charts.Tree {
 }
 
 func (dashboard *Dashboard) HandleTreeChart(w http.ResponseWriter, r *http.Request) {
+	// a dashboard over stored observations has no graph
+	if dashboard.root == nil {
+		http.NotFound(w, r)
+		return
+	}
+
 	treeChart := dashboard.GetTreeChart()
 	if err := treeChart.Render(w); err != nil {
 		http.Error(w, "Failed to render charts", http.StatusInternalServerError)
@@ -270,3 +451,198 @@ func ConvertToHHMMSS(times []time.Time) []string {
 	}
 	return formattedTimes
 }
+
+func (dashboard *Dashboard) GetThrottlingChart(bucketDuration time.Duration, duration time.Duration, crawlerID string) *charts.Line {
+	line := charts.NewLine()
+	line.SetGlobalOptions(
+		charts.WithTitleOpts(opts.Title{
+			Title: "Throttling and Faults - Last " + duration.String(),
+		}),
+		charts.WithYAxisOpts(opts.YAxis{
+			Type: "value",
+			Min:  0,
+		}),
+	)
+
+	now := dashboard.now().UTC()
+	numBuckets := int(duration / bucketDuration)
+	buckets := make([]time.Time, 0, numBuckets)
+	for i := 0; i < numBuckets; i++ {
+		buckets = append(buckets, now.Add(-time.Duration(i*int(bucketDuration))))
+	}
+	slices.Reverse(buckets)
+
+	rateLimitedSeries := make([]opts.LineData, numBuckets)
+	bannedSeries := make([]opts.LineData, numBuckets)
+	faultSeries := make([]opts.LineData, numBuckets)
+
+	for i := 0; i < numBuckets; i++ {
+		counts := dashboard.observer.GetFaultCounts(crawlerID, buckets[i])
+		faults := 0
+		for faultType, count := range counts {
+			if faultType != obs.FaultTypeRateLimited && faultType != obs.FaultTypeBanned {
+				faults += count
+			}
+		}
+		rateLimitedSeries[i] = opts.LineData{Value: counts[obs.FaultTypeRateLimited]}
+		bannedSeries[i] = opts.LineData{Value: counts[obs.FaultTypeBanned]}
+		faultSeries[i] = opts.LineData{Value: faults}
+	}
+
+	xs := ConvertToHHMMSS(buckets)
+	line.SetXAxis(xs).
+		AddSeries("429 Too Many Requests", rateLimitedSeries).
+		AddSeries("403 bans", bannedSeries).
+		AddSeries("Injected faults", faultSeries)
+
+	return line
+}
+
+func (dashboard *Dashboard) HandleThrottlingChart(w http.ResponseWriter, r *http.Request) {
+	bucketDurationStr := r.URL.Query().Get("bucket-duration")
+	durationStr := r.URL.Query().Get("duration")
+	crawlerID := getCrawlerID(r)
+
+	bucketDuration, err := time.ParseDuration(bucketDurationStr)
+	if err != nil {
+		http.Error(w, "Invalid bucketDuration", http.StatusBadRequest)
+		return
+	}
+
+	duration, err := time.ParseDuration(durationStr)
+	if err != nil {
+		http.Error(w, "Invalid duration", http.StatusBadRequest)
+		return
+	}
+
+	throttlingChart := dashboard.GetThrottlingChart(bucketDuration, duration, crawlerID)
+	err = throttlingChart.Render(w)
+	if err != nil {
+		http.Error(w, "Failed to render charts", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (dashboard *Dashboard) GetStaleIndexChart(bucketDuration time.Duration, duration time.Duration, crawlerID string) *charts.Line {
+	line := charts.NewLine()
+	line.SetGlobalOptions(
+		charts.WithTitleOpts(opts.Title{
+			Title: "Stale Index - Last " + duration.String(),
+		}),
+		charts.WithYAxisOpts(opts.YAxis{
+			Type: "value",
+			Min:  0,
+		}),
+	)
+
+	now := dashboard.now().UTC()
+	numBuckets := int(duration / bucketDuration)
+	buckets := make([]time.Time, 0, numBuckets)
+	for i := 0; i < numBuckets; i++ {
+		buckets = append(buckets, now.Add(-time.Duration(i*int(bucketDuration))))
+	}
+	slices.Reverse(buckets)
+
+	staleNodesSeries := make([]opts.LineData, numBuckets)
+	staleTimeSeries := make([]opts.LineData, numBuckets)
+
+	for i := 0; i < numBuckets; i++ {
+		staleNodes, meanStaleTime := dashboard.observer.GetStaleIndex(crawlerID, buckets[i])
+		staleNodesSeries[i] = opts.LineData{Value: staleNodes}
+		staleTimeSeries[i] = opts.LineData{Value: meanStaleTime.Seconds()}
+	}
+
+	xs := ConvertToHHMMSS(buckets)
+	line.SetXAxis(xs).
+		AddSeries("Deleted pages fetched again", staleNodesSeries).
+		AddSeries("Mean time kept after deletion (seconds)", staleTimeSeries)
+
+	return line
+}
+
+func (dashboard *Dashboard) GetDuplicatesChart(bucketDuration time.Duration, duration time.Duration, crawlerID string) *charts.Line {
+	line := charts.NewLine()
+	line.SetGlobalOptions(
+		charts.WithTitleOpts(opts.Title{
+			Title: "Duplicate Content - Last " + duration.String(),
+		}),
+		charts.WithYAxisOpts(opts.YAxis{
+			Type: "value",
+			Min:  0,
+		}),
+	)
+
+	now := dashboard.now().UTC()
+	numBuckets := int(duration / bucketDuration)
+	buckets := make([]time.Time, 0, numBuckets)
+	for i := 0; i < numBuckets; i++ {
+		buckets = append(buckets, now.Add(-time.Duration(i*int(bucketDuration))))
+	}
+	slices.Reverse(buckets)
+
+	duplicateFetchesSeries := make([]opts.LineData, numBuckets)
+	scoreSeries := make([]opts.LineData, numBuckets)
+
+	for i := 0; i < numBuckets; i++ {
+		duplicateFetches, score := dashboard.observer.GetDuplicateScore(crawlerID, buckets[i])
+		duplicateFetchesSeries[i] = opts.LineData{Value: duplicateFetches}
+		scoreSeries[i] = opts.LineData{Value: score * 100}
+	}
+
+	xs := ConvertToHHMMSS(buckets)
+	line.SetXAxis(xs).
+		AddSeries("Duplicate fetches", duplicateFetchesSeries).
+		AddSeries("Share of fetches (%)", scoreSeries)
+
+	return line
+}
+
+func (dashboard *Dashboard) HandleDuplicatesChart(w http.ResponseWriter, r *http.Request) {
+	bucketDurationStr := r.URL.Query().Get("bucket-duration")
+	durationStr := r.URL.Query().Get("duration")
+	crawlerID := getCrawlerID(r)
+
+	bucketDuration, err := time.ParseDuration(bucketDurationStr)
+	if err != nil {
+		http.Error(w, "Invalid bucketDuration", http.StatusBadRequest)
+		return
+	}
+
+	duration, err := time.ParseDuration(durationStr)
+	if err != nil {
+		http.Error(w, "Invalid duration", http.StatusBadRequest)
+		return
+	}
+
+	duplicatesChart := dashboard.GetDuplicatesChart(bucketDuration, duration, crawlerID)
+	err = duplicatesChart.Render(w)
+	if err != nil {
+		http.Error(w, "Failed to render charts", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (dashboard *Dashboard) HandleStaleIndexChart(w http.ResponseWriter, r *http.Request) {
+	bucketDurationStr := r.URL.Query().Get("bucket-duration")
+	durationStr := r.URL.Query().Get("duration")
+	crawlerID := getCrawlerID(r)
+
+	bucketDuration, err := time.ParseDuration(bucketDurationStr)
+	if err != nil {
+		http.Error(w, "Invalid bucketDuration", http.StatusBadRequest)
+		return
+	}
+
+	duration, err := time.ParseDuration(durationStr)
+	if err != nil {
+		http.Error(w, "Invalid duration", http.StatusBadRequest)
+		return
+	}
+
+	staleIndexChart := dashboard.GetStaleIndexChart(bucketDuration, duration, crawlerID)
+	err = staleIndexChart.Render(w)
+	if err != nil {
+		http.Error(w, "Failed to render charts", http.StatusInternalServerError)
+		return
+	}
+}
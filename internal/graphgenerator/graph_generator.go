@@ -2,31 +2,236 @@ package graphgenerator
 
 import (
 	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"path"
 	"sync"
 	"time"
 
+	clk "github.com/sdqri/sequined/internal/clock"
 	hr "github.com/sdqri/sequined/internal/hyperrenderer"
+	"github.com/sdqri/sequined/internal/lockedrand"
 )
 
 var (
 	ErrMaxHubOrAuthCountAlreadyExceeded error = errors.New("maxHubCount or maxAuthCount is already exceeded")
+	ErrNoPageToDelete                   error = errors.New("no page of the requested type can be deleted")
+	ErrNoPageToModify                   error = errors.New("no page with a positive change rate to modify")
+	ErrNoPageToLink                     error = errors.New("no page left to link")
+	ErrNoPageToMove                     error = errors.New("no page can be moved to another hub")
+	ErrInvalidRate                      error = errors.New("invalid evolution rate")
 )
 
 type SelectorFunc func(probabilities []float64) (int, error)
 
+// OrphanPolicy decides what happens to the subtree of a deleted page.
+type OrphanPolicy string
+
+const (
+	// OrphanPolicyReparent links the children of a deleted page to its parent.
+	// Pages whose path is derived from their parent's path move accordingly,
+	// their former paths redirecting as for moves.
+	OrphanPolicyReparent OrphanPolicy = "reparent"
+	// OrphanPolicyCascade deletes the whole subtree of a deleted page.
+	OrphanPolicyCascade OrphanPolicy = "cascade"
+	// OrphanPolicyDangle deletes the whole subtree of a deleted page but keeps
+	// the links to its pages, leaving dangling links behind.
+	OrphanPolicyDangle OrphanPolicy = "dangle"
+)
+
 type GraphGenerator struct {
-	Root                   *hr.Webpage
+	Root *hr.Webpage
+	// PreferentialAttachment weights preferential attachment against uniform
+	// choice for cross links and for the default graph model.
 	PreferentialAttachment float64
-	Debug                  bool
+	// Model decides where created pages go, HubAuthorityModel by default.
+	Model GraphModel
 	SelectorFunc
-	mu sync.Mutex
+	// Clock times the evolution and stamps created and modified pages.
+	Clock clk.Clock
+	// rng draws the random choices of the generator, see WithSeed.
+	rng *rand.Rand
+
+	// Deletion rates are specified in pages per hour, zero disables deletion.
+	HubDeletionRate  float64
+	AuthDeletionRate float64
+	OrphanPolicy     OrphanPolicy
+	// ChangeRateDistribution draws the change rate of pages that have none,
+	// nil disables content modification.
+	ChangeRateDistribution RateDistribution
+
+	// CrossLinksPerPage is the mean number of links besides the ones to
+	// children Generate adds per page.
+	CrossLinksPerPage float64
+	// CrossLinkRate is the rate of links added during evolution in links per
+	// hour, zero disables it.
+	CrossLinkRate float64
+	// BackLinkProbability is the probability of an added link pointing to an
+	// ancestor of its page, as navigation back does, rather than to any page.
+	BackLinkProbability float64
+
+	// MoveRate is the rate of pages moved to another hub during evolution in
+	// pages per hour, zero disables moves.
+	MoveRate float64
+	// RedirectStatuses are the statuses former paths of moved pages redirect
+	// with, one being chosen uniformly per move.
+	RedirectStatuses []int
+	// RedirectLoopProbability is the probability of the former path of a
+	// moved page redirecting into a loop instead of to its new path.
+	RedirectLoopProbability float64
+
+	// DuplicateProbability is the probability of a created authority page
+	// duplicating the content of another one, keeping the share
+	// DuplicateSimilarity of its sentences.
+	DuplicateProbability float64
+	DuplicateSimilarity  float64
+	// URLVariantProbability is the probability of a created page being served
+	// under URLVariants besides its path.
+	URLVariantProbability float64
+	URLVariants           []hr.URLVariant
+
+	// ExceededMaximaAllowed lets StartGraphEvolution start on a graph with
+	// more pages than the maxima, see WithExceededMaxima.
+	ExceededMaximaAllowed bool
+
+	// mu serializes generator operations, which additionally hold the graph
+	// lock of Root while touching the graph so it can be served concurrently.
+	mu            sync.Mutex
+	stopEvolution func()
 }
 
-func New(root *hr.Webpage, preferentialAttachment float64) *GraphGenerator {
-	return &GraphGenerator{
+type GraphGeneratorOption func(*GraphGenerator)
+
+func New(root *hr.Webpage, preferentialAttachment float64, opts ...GraphGeneratorOption) *GraphGenerator {
+	gg := &GraphGenerator{
 		Root:                   root,
 		PreferentialAttachment: preferentialAttachment,
-		SelectorFunc:           SelectByProbability,
+		Clock:                  clk.Real(),
+		OrphanPolicy:           OrphanPolicyReparent,
+		RedirectStatuses:       []int{http.StatusMovedPermanently},
+		rng:                    lockedrand.New(time.Now().UnixNano()),
+	}
+
+	for _, opt := range opts {
+		opt(gg)
+	}
+	if gg.SelectorFunc == nil {
+		gg.SelectorFunc = NewProbabilitySelector(gg.rng)
+	}
+	if gg.Model == nil {
+		gg.Model = HubAuthorityModel{PreferentialAttachment: preferentialAttachment}
+	}
+	return gg
+}
+
+// WithSeed makes the choices of the generator reproducible. Along with a root
+// created with hyperrenderer.WithSeed and a manual clock, the graph and its
+// evolution are reproduced exactly, IDs, paths and timestamps included.
+func WithSeed(seed int64) GraphGeneratorOption {
+	return func(gg *GraphGenerator) {
+		gg.rng = lockedrand.New(seed)
+	}
+}
+
+func WithModel(model GraphModel) GraphGeneratorOption {
+	return func(gg *GraphGenerator) {
+		gg.Model = model
+	}
+}
+
+func WithClock(clock clk.Clock) GraphGeneratorOption {
+	return func(gg *GraphGenerator) {
+		gg.Clock = clock
+	}
+}
+
+func WithDeletionRates(hubDeletionRate, authDeletionRate float64) GraphGeneratorOption {
+	return func(gg *GraphGenerator) {
+		gg.HubDeletionRate = hubDeletionRate
+		gg.AuthDeletionRate = authDeletionRate
+	}
+}
+
+func WithOrphanPolicy(policy OrphanPolicy) GraphGeneratorOption {
+	return func(gg *GraphGenerator) {
+		gg.OrphanPolicy = policy
+	}
+}
+
+func WithChangeRateDistribution(distribution RateDistribution) GraphGeneratorOption {
+	return func(gg *GraphGenerator) {
+		gg.ChangeRateDistribution = distribution
+	}
+}
+
+// WithCrossLinks adds links besides the ones to children, making the graph
+// other than a tree. Their targets are chosen by preferential attachment on
+// in-degree, weighted like the parents of new pages.
+func WithCrossLinks(perPage, rate float64) GraphGeneratorOption {
+	return func(gg *GraphGenerator) {
+		gg.CrossLinksPerPage = perPage
+		gg.CrossLinkRate = rate
+	}
+}
+
+// WithBackLinkProbability makes cross links point to an ancestor of their page
+// with the given probability, forming cycles like navigation back does.
+func WithBackLinkProbability(probability float64) GraphGeneratorOption {
+	return func(gg *GraphGenerator) {
+		gg.BackLinkProbability = probability
+	}
+}
+
+// WithMoves moves pages to another hub during evolution at the given rate in
+// pages per hour, their former paths redirecting with one of statuses, 301
+// Moved Permanently if none is given. Moving a hub moves its subtree along.
+func WithMoves(rate float64, statuses ...int) GraphGeneratorOption {
+	return func(gg *GraphGenerator) {
+		gg.MoveRate = rate
+		if len(statuses) > 0 {
+			gg.RedirectStatuses = statuses
+		}
+	}
+}
+
+func WithRedirectLoopProbability(probability float64) GraphGeneratorOption {
+	return func(gg *GraphGenerator) {
+		gg.RedirectLoopProbability = probability
+	}
+}
+
+// WithDuplicates makes created authority pages duplicate the content of a
+// uniformly chosen authority page that is no duplicate itself with the given
+// probability. similarity is the share of its sentences they keep, 1 making
+// exact duplicates.
+func WithDuplicates(probability, similarity float64) GraphGeneratorOption {
+	return func(gg *GraphGenerator) {
+		gg.DuplicateProbability = probability
+		gg.DuplicateSimilarity = similarity
+	}
+}
+
+// WithURLVariants serves created pages under the URL variants besides their
+// path with the given probability, every known variant if none is given.
+func WithURLVariants(probability float64, variants ...hr.URLVariant) GraphGeneratorOption {
+	return func(gg *GraphGenerator) {
+		gg.URLVariantProbability = probability
+		gg.URLVariants = variants
+		if len(variants) == 0 {
+			gg.URLVariants = hr.URLVariants
+		}
+	}
+}
+
+// WithExceededMaxima lets StartGraphEvolution start on a graph with more pages
+// than the maxima, as imported or loaded graphs may have, rather than failing
+// with ErrMaxHubOrAuthCountAlreadyExceeded. Pages of the exceeded types are
+// only created once deletions bring the graph back under the maxima.
+func WithExceededMaxima() GraphGeneratorOption {
+	return func(gg *GraphGenerator) {
+		gg.ExceededMaximaAllowed = true
 	}
 }
 
@@ -35,11 +240,30 @@ type UpdateType string
 const (
 	UpdateTypeCreate UpdateType = "create"
 	UpdateTypeDelete UpdateType = "delete"
+	UpdateTypeModify UpdateType = "modify"
+	// UpdateTypeLink reports a link added to Webpage.
+	UpdateTypeLink UpdateType = "link"
+	// UpdateTypeMove reports Webpage moved to another hub, or reparented when
+	// its parent was deleted, its former paths and the ones of its subtree
+	// redirecting as given by Redirects.
+	UpdateTypeMove UpdateType = "move"
 )
 
 type UpdateMessage struct {
 	Type    UpdateType
 	Webpage *hr.Webpage
+	// At is the time of the update on the generator clock.
+	At time.Time
+	// Redirects are the redirects left behind by a move.
+	Redirects []Redirect
+}
+
+// Redirect sends requests for From to To with Status, a 3xx status code.
+type Redirect struct {
+	From, To string
+	Status   int
+	// Webpage is the moved page the redirect leads to, or loops away from.
+	Webpage *hr.Webpage
 }
 
 var (
@@ -47,11 +271,90 @@ var (
 )
 
 func (gg *GraphGenerator) CreateHubPage() (*hr.Webpage, error) {
+	return gg.createHubPage(gg.Clock.Now())
+}
+
+func (gg *GraphGenerator) createHubPage(at time.Time) (*hr.Webpage, error) {
+	return gg.createPage(hr.WebpageTypeHub, at)
+}
+
+func (gg *GraphGenerator) CreateAuthorityPage() (*hr.Webpage, error) {
+	return gg.createAuthorityPage(gg.Clock.Now())
+}
+
+func (gg *GraphGenerator) createAuthorityPage(at time.Time) (*hr.Webpage, error) {
+	return gg.createPage(hr.WebpageTypeAuthority, at)
+}
+
+// createPage creates a page where the graph model attaches it.
+func (gg *GraphGenerator) createPage(webpageType hr.WebpageType, at time.Time) (*hr.Webpage, error) {
+	gg.mu.Lock()
+	defer gg.mu.Unlock()
+	gg.Root.LockGraph()
+	defer gg.Root.UnlockGraph()
+
+	graph, err := gg.graph()
+	if err != nil {
+		return nil, err
+	}
+
+	attachment, err := gg.Model.Attach(graph, webpageType)
+	if err != nil {
+		return nil, err
+	}
+	if attachment.Parent == nil {
+		return nil, ErrNoParent
+	}
+
+	webpage := attachment.Parent.AddChild(webpageType)
+	for _, link := range attachment.Links {
+		webpage.AddLink(link)
+	}
+	for _, page := range attachment.LinkedFrom {
+		page.AddLink(webpage)
+	}
+	gg.initPage(webpage, at)
+	return webpage, nil
+}
+
+// DeleteHubPage deletes a uniformly chosen hub page other than root and
+// returns every page deleted along with it according to OrphanPolicy, and the
+// redirects from the former paths of the pages reparented whose path changed.
+func (gg *GraphGenerator) DeleteHubPage() ([]*hr.Webpage, []Redirect, error) {
+	deleted, reparented, err := gg.deletePage(hr.WebpageTypeHub)
+	return deleted, reparentedRedirects(reparented), err
+}
+
+// DeleteAuthorityPage deletes a uniformly chosen authority page and returns
+// every page deleted along with it according to OrphanPolicy, and the
+// redirects from the former paths of the pages reparented whose path changed.
+func (gg *GraphGenerator) DeleteAuthorityPage() ([]*hr.Webpage, []Redirect, error) {
+	deleted, reparented, err := gg.deletePage(hr.WebpageTypeAuthority)
+	return deleted, reparentedRedirects(reparented), err
+}
+
+// reparenting is a child of a deleted page adopted by its grandparent, and the
+// redirects left behind by it and its subtree.
+type reparenting struct {
+	webpage   *hr.Webpage
+	redirects []Redirect
+}
+
+func reparentedRedirects(reparented []reparenting) []Redirect {
+	redirects := make([]Redirect, 0)
+	for _, r := range reparented {
+		redirects = append(redirects, r.redirects...)
+	}
+	return redirects
+}
+
+func (gg *GraphGenerator) deletePage(webpageType hr.WebpageType) ([]*hr.Webpage, []reparenting, error) {
 	gg.mu.Lock()
 	defer gg.mu.Unlock()
+	gg.Root.LockGraph()
+	defer gg.Root.UnlockGraph()
 
-	hubsMap := make(map[*hr.Webpage]bool)
-	totalHtoHLinksCount := 0
+	candidates := make([]*hr.Webpage, 0)
 
 	var err error = nil
 	hr.Traverse(gg.Root, func(currentRenderer hr.HyperRenderer) bool {
@@ -61,55 +364,243 @@ func (gg *GraphGenerator) CreateHubPage() (*hr.Webpage, error) {
 			return true
 		}
 
-		if currentPage.Type == hr.WebpageTypeHub {
-			totalHtoHLinksCount += len(currentPage.Links)
-			hubsMap[currentPage] = true
+		if currentPage != gg.Root && currentPage.Parent != nil && currentPage.Type == webpageType {
+			candidates = append(candidates, currentPage)
 		}
 		return false
 	})
 
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	totalHubsCount := len(hubsMap)
+	if len(candidates) == 0 {
+		return nil, nil, ErrNoPageToDelete
+	}
 
-	if totalHubsCount == 0 {
-		webpage := gg.Root.AddChild(hr.WebpageTypeHub)
-		return webpage, nil
+	probabilities := make([]float64, len(candidates))
+	for i := range probabilities {
+		probabilities[i] = 1 / float64(len(candidates))
 	}
 
-	probabilities := make([]float64, 0, totalHubsCount)
-	hubNodes := make([]*hr.Webpage, 0, totalHubsCount)
-	for node := range hubsMap {
-		htohLinkCount := len(node.Links)
-		probability := float64(1) / float64(totalHubsCount)
-		if totalHtoHLinksCount != 0 {
-			probability = (float64(htohLinkCount)/float64(totalHtoHLinksCount))*
-				float64(gg.PreferentialAttachment) +
-				(1-gg.PreferentialAttachment)*(1/float64(totalHubsCount))
+	index, err := gg.SelectorFunc(probabilities)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	deleted, reparented := gg.removePage(candidates[index])
+	return deleted, reparented, nil
+}
+
+// removePage deletes the page according to OrphanPolicy and returns the pages
+// deleted and, when reparenting, the children adopted by its parent. Adopted
+// pages are given paths under their new parent, so their former paths redirect
+// as if they were moved.
+func (gg *GraphGenerator) removePage(webpage *hr.Webpage) ([]*hr.Webpage, []reparenting) {
+	parent := webpage.Parent
+
+	var deleted []*hr.Webpage
+	var reparented []reparenting
+	switch gg.OrphanPolicy {
+	case OrphanPolicyCascade:
+		deleted = markSubtreeDeleted(webpage)
+	case OrphanPolicyDangle:
+		return markSubtreeDeleted(webpage), nil
+	default:
+		status := 0
+		for _, child := range webpage.Children() {
+			subtree := liveSubtree(child)
+			oldPaths := make([]string, len(subtree))
+			for i, page := range subtree {
+				oldPaths[i] = page.GetPath()
+			}
+
+			parent.Adopt(child)
+
+			redirects := make([]Redirect, 0)
+			for i, page := range subtree {
+				newPath := page.GetPath()
+				if newPath == oldPaths[i] {
+					continue
+				}
+				if status == 0 {
+					status = gg.RedirectStatuses[gg.rng.Intn(len(gg.RedirectStatuses))]
+				}
+				redirects = append(redirects, Redirect{From: oldPaths[i], To: newPath, Status: status, Webpage: page})
+			}
+			if len(redirects) > 0 {
+				reparented = append(reparented, reparenting{webpage: child, redirects: redirects})
+			}
 		}
-		probabilities = append(probabilities, probability)
-		hubNodes = append(hubNodes, node)
+		webpage.Links = make([]*hr.Webpage, 0)
+		webpage.Deleted = true
+		deleted = []*hr.Webpage{webpage}
 	}
 
-	hubIndex, err := gg.SelectorFunc(probabilities)
-	if err != nil {
-		return nil, err
+	gg.unlink(deleted)
+	return deleted, reparented
+}
+
+// unlink removes every link to the given pages from the graph.
+func (gg *GraphGenerator) unlink(pages []*hr.Webpage) {
+	removed := make(map[*hr.Webpage]struct{}, len(pages))
+	for _, webpage := range pages {
+		removed[webpage] = struct{}{}
 	}
 
-	webpage := hubNodes[hubIndex].AddChild(hr.WebpageTypeHub)
-	return webpage, nil
+	hr.Traverse(gg.Root, func(currentRenderer hr.HyperRenderer) bool {
+		currentPage, ok := currentRenderer.(*hr.Webpage)
+		if !ok {
+			return false
+		}
+		links := currentPage.Links[:0]
+		for _, link := range currentPage.Links {
+			if _, ok := removed[link]; !ok {
+				links = append(links, link)
+			}
+		}
+		currentPage.Links = links
+		return false
+	})
 }
 
-func (gg *GraphGenerator) CreateAuthorityPage() (*hr.Webpage, error) {
+// markSubtreeDeleted marks the page and its descendants in the tree of
+// parents deleted, pages only linked from the subtree are left alone.
+func markSubtreeDeleted(root *hr.Webpage) []*hr.Webpage {
+	deleted := make([]*hr.Webpage, 0)
+	stack := []*hr.Webpage{root}
+	for len(stack) > 0 {
+		webpage := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		deleted = append(deleted, webpage)
+		stack = append(stack, webpage.Children()...)
+	}
+
+	for _, webpage := range deleted {
+		webpage.Deleted = true
+	}
+	return deleted
+}
+
+// CreateCrossLink adds a link from a uniformly chosen page to a page it does
+// not link yet and returns both, pages linking every other page are not
+// chosen. The target is an ancestor of the source with
+// BackLinkProbability, if any is left to link, otherwise it is chosen by
+// preferential attachment on in-degree.
+func (gg *GraphGenerator) CreateCrossLink() (source, target *hr.Webpage, err error) {
 	gg.mu.Lock()
 	defer gg.mu.Unlock()
+	gg.Root.LockGraph()
+	defer gg.Root.UnlockGraph()
 
-	hubsMap := make(map[*hr.Webpage]bool)
-	totalHubsLinksCount := 0
+	// pages are kept in traversal order for choices to be reproducible
+	pages := make([]*hr.Webpage, 0)
+	inDegrees := make(map[*hr.Webpage]int)
+	outDegrees := make(map[*hr.Webpage]int)
+	totalInDegree := 0
+	hr.Traverse(gg.Root, func(currentRenderer hr.HyperRenderer) bool {
+		currentPage, ok := currentRenderer.(*hr.Webpage)
+		if !ok {
+			err = ErrUnexpectedNodeType
+			return true
+		}
 
-	var err error = nil
+		pages = append(pages, currentPage)
+		for _, link := range currentPage.Links {
+			if !link.Deleted && link != currentPage {
+				inDegrees[link]++
+				outDegrees[currentPage]++
+				totalInDegree++
+			}
+		}
+		return false
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sources := make([]*hr.Webpage, 0, len(pages))
+	for _, page := range pages {
+		if outDegrees[page] < len(pages)-1 {
+			sources = append(sources, page)
+		}
+	}
+	if len(sources) == 0 {
+		return nil, nil, ErrNoPageToLink
+	}
+
+	probabilities := make([]float64, len(sources))
+	for i := range probabilities {
+		probabilities[i] = 1 / float64(len(sources))
+	}
+	index, err := gg.SelectorFunc(probabilities)
+	if err != nil {
+		return nil, nil, err
+	}
+	source = sources[index]
+
+	linkable := func(page *hr.Webpage) bool {
+		return page != source && !source.HasLink(page)
+	}
+
+	candidates := make([]*hr.Webpage, 0)
+	if gg.BackLinkProbability > 0 && gg.rng.Float64() < gg.BackLinkProbability {
+		for ancestor := source.Parent; ancestor != nil; ancestor = ancestor.Parent {
+			if linkable(ancestor) {
+				candidates = append(candidates, ancestor)
+			}
+		}
+	}
+
+	probabilities = make([]float64, 0, len(pages))
+	if len(candidates) > 0 {
+		for range candidates {
+			probabilities = append(probabilities, 1/float64(len(candidates)))
+		}
+	} else {
+		for _, page := range pages {
+			if !linkable(page) {
+				continue
+			}
+			probability := 1 / float64(len(pages))
+			if totalInDegree != 0 {
+				probability = (float64(inDegrees[page])/float64(totalInDegree))*
+					gg.PreferentialAttachment +
+					(1-gg.PreferentialAttachment)*(1/float64(len(pages)))
+			}
+			candidates = append(candidates, page)
+			probabilities = append(probabilities, probability)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return nil, nil, ErrNoPageToLink
+	}
+
+	index, err = gg.SelectorFunc(probabilities)
+	if err != nil {
+		return nil, nil, err
+	}
+	target = candidates[index]
+
+	source.AddLink(target)
+	return source, target, nil
+}
+
+// MovePage moves a uniformly chosen page, root aside, under a uniformly
+// chosen hub other than its parent and out of its subtree, and returns the
+// redirects from the former paths of the page and its descendants whose path
+// changed. With RedirectLoopProbability the former path of the page redirects
+// into a loop instead.
+func (gg *GraphGenerator) MovePage() (*hr.Webpage, []Redirect, error) {
+	gg.mu.Lock()
+	defer gg.mu.Unlock()
+	gg.Root.LockGraph()
+	defer gg.Root.UnlockGraph()
+
+	pages := make([]*hr.Webpage, 0)
+	hubs := make([]*hr.Webpage, 0)
+	var err error
 	hr.Traverse(gg.Root, func(currentRenderer hr.HyperRenderer) bool {
 		currentPage, ok := currentRenderer.(*hr.Webpage)
 		if !ok {
@@ -117,48 +608,217 @@ func (gg *GraphGenerator) CreateAuthorityPage() (*hr.Webpage, error) {
 			return true
 		}
 
+		if currentPage != gg.Root && currentPage.Parent != nil {
+			pages = append(pages, currentPage)
+		}
 		if currentPage.Type == hr.WebpageTypeHub {
-			totalHubsLinksCount += len(currentPage.Links)
-			hubsMap[currentPage] = true
+			hubs = append(hubs, currentPage)
 		}
 		return false
 	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(pages) == 0 {
+		return nil, nil, ErrNoPageToMove
+	}
 
+	probabilities := make([]float64, len(pages))
+	for i := range probabilities {
+		probabilities[i] = 1 / float64(len(pages))
+	}
+	index, err := gg.SelectorFunc(probabilities)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	webpage := pages[index]
+
+	targets := make([]*hr.Webpage, 0)
+	for _, hub := range hubs {
+		if hub != webpage.Parent && !webpage.IsAncestorOf(hub) {
+			targets = append(targets, hub)
+		}
+	}
+	if len(targets) == 0 {
+		return nil, nil, ErrNoPageToMove
+	}
+
+	probabilities = make([]float64, len(targets))
+	for i := range probabilities {
+		probabilities[i] = 1 / float64(len(targets))
+	}
+	index, err = gg.SelectorFunc(probabilities)
+	if err != nil {
+		return nil, nil, err
+	}
+	target := targets[index]
+
+	subtree := liveSubtree(webpage)
+	oldPaths := make([]string, len(subtree))
+	for i, page := range subtree {
+		oldPaths[i] = page.GetPath()
 	}
 
-	totalHubsCount := len(hubsMap)
+	webpage.Parent.RemoveLink(webpage)
+	// a fixed path would not follow the page
+	webpage.Path = ""
+	target.Adopt(webpage)
 
-	probabilities := make([]float64, 0, totalHubsCount)
-	hubNodes := make([]*hr.Webpage, 0, totalHubsCount)
+	status := gg.RedirectStatuses[gg.rng.Intn(len(gg.RedirectStatuses))]
+	redirects := make([]Redirect, 0, len(subtree))
+	for i, page := range subtree {
+		newPath := page.GetPath()
+		if newPath == oldPaths[i] {
+			continue
+		}
 
-	for node := range hubsMap {
-		linkCount := len(node.Links)
-		probability := float64(1) / float64(totalHubsCount)
-		if totalHubsLinksCount != 0 {
-			probability = (float64(linkCount)/float64(totalHubsLinksCount))*
-				float64(gg.PreferentialAttachment) +
-				(1-gg.PreferentialAttachment)*(1/float64(totalHubsCount))
+		if page == webpage && gg.RedirectLoopProbability > 0 && gg.rng.Float64() < gg.RedirectLoopProbability {
+			// paths are unescaped, as in request URLs
+			loopPath := path.Join(oldPaths[i], "moved")
+			redirects = append(redirects,
+				Redirect{From: oldPaths[i], To: loopPath, Status: status, Webpage: page},
+				Redirect{From: loopPath, To: oldPaths[i], Status: status, Webpage: page},
+			)
+			continue
 		}
-		probabilities = append(probabilities, probability)
-		hubNodes = append(hubNodes, node)
+		redirects = append(redirects, Redirect{From: oldPaths[i], To: newPath, Status: status, Webpage: page})
+	}
+	return webpage, redirects, nil
+}
+
+// liveSubtree returns the page and its descendants in the tree of parents
+// that are not deleted.
+func liveSubtree(root *hr.Webpage) []*hr.Webpage {
+	subtree := make([]*hr.Webpage, 0)
+	stack := []*hr.Webpage{root}
+	for len(stack) > 0 {
+		webpage := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if webpage.Deleted {
+			continue
+		}
+		subtree = append(subtree, webpage)
+		stack = append(stack, webpage.Children()...)
+	}
+	return subtree
+}
+
+// initPage stamps a page created at the given time and draws its change rate,
+// whether it duplicates another page and its URL variants. The graph must be
+// locked.
+func (gg *GraphGenerator) initPage(webpage *hr.Webpage, at time.Time) {
+	webpage.CreatedAt = at.UTC()
+	gg.assignChangeRate(webpage)
+	gg.assignDuplicate(webpage)
+	if gg.URLVariantProbability > 0 && gg.rng.Float64() < gg.URLVariantProbability {
+		webpage.Variants = gg.URLVariants
+	}
+}
+
+func (gg *GraphGenerator) assignDuplicate(webpage *hr.Webpage) {
+	if webpage.Type != hr.WebpageTypeAuthority || gg.DuplicateProbability <= 0 || gg.rng.Float64() >= gg.DuplicateProbability {
+		return
+	}
+
+	originals := make([]*hr.Webpage, 0)
+	hr.Traverse(gg.Root, func(currentRenderer hr.HyperRenderer) bool {
+		currentPage, ok := currentRenderer.(*hr.Webpage)
+		if ok && currentPage != webpage && currentPage.Type == hr.WebpageTypeAuthority && currentPage.DuplicateOf == nil {
+			originals = append(originals, currentPage)
+		}
+		return false
+	})
+	if len(originals) == 0 {
+		return
+	}
+
+	original := originals[gg.rng.Intn(len(originals))]
+	webpage.DuplicateOf = original
+	webpage.DuplicateVersion = original.Version
+	webpage.Similarity = gg.DuplicateSimilarity
+}
+
+func (gg *GraphGenerator) assignChangeRate(webpage *hr.Webpage) {
+	if gg.ChangeRateDistribution != nil && webpage.ChangeRate == 0 {
+		webpage.ChangeRate = gg.ChangeRateDistribution(gg.rng)
+	}
+}
+
+// ModifyPage modifies the content of a live page chosen with probability
+// proportional to its change rate, so that every page changes following a
+// Poisson process of its own rate.
+func (gg *GraphGenerator) ModifyPage() (*hr.Webpage, error) {
+	return gg.modifyPage(gg.Clock.Now())
+}
+
+func (gg *GraphGenerator) modifyPage(at time.Time) (*hr.Webpage, error) {
+	gg.mu.Lock()
+	defer gg.mu.Unlock()
+	gg.Root.LockGraph()
+	defer gg.Root.UnlockGraph()
+
+	candidates, rates, err := gg.changingPages()
+	if err != nil {
+		return nil, err
 	}
 
-	hubIndex, err := gg.SelectorFunc(probabilities)
+	if len(candidates) == 0 {
+		return nil, ErrNoPageToModify
+	}
+
+	index, err := gg.SelectorFunc(rates)
 	if err != nil {
 		return nil, err
 	}
 
-	webpage := hubNodes[hubIndex].AddChild(hr.WebpageTypeAuthority)
+	webpage := candidates[index]
+	webpage.Modify(at.UTC())
 	return webpage, nil
 }
 
-func (gg *GraphGenerator) Generate(maxHubCount, maxAuthCount int) error {
-	hubCount := 0
-	authCount := 0
+// changingPages returns live pages with a positive change rate along with their rates.
+func (gg *GraphGenerator) changingPages() ([]*hr.Webpage, []float64, error) {
+	candidates := make([]*hr.Webpage, 0)
+	rates := make([]float64, 0)
 
 	var err error = nil
+	hr.Traverse(gg.Root, func(currentRenderer hr.HyperRenderer) bool {
+		currentPage, ok := currentRenderer.(*hr.Webpage)
+		if !ok {
+			err = ErrUnexpectedNodeType
+			return true
+		}
+
+		if currentPage.ChangeRate > 0 {
+			candidates = append(candidates, currentPage)
+			rates = append(rates, currentPage.ChangeRate)
+		}
+		return false
+	})
+
+	return candidates, rates, err
+}
+
+func (gg *GraphGenerator) totalChangeRate() float64 {
+	gg.mu.Lock()
+	defer gg.mu.Unlock()
+	gg.Root.RLockGraph()
+	defer gg.Root.RUnlockGraph()
+
+	_, rates, _ := gg.changingPages()
+	total := float64(0)
+	for _, rate := range rates {
+		total += rate
+	}
+	return total
+}
+
+func (gg *GraphGenerator) countPages() (hubCount, authCount int, err error) {
+	gg.mu.Lock()
+	defer gg.mu.Unlock()
+	gg.Root.RLockGraph()
+	defer gg.Root.RUnlockGraph()
+
 	hr.Traverse(gg.Root, func(currentRenderer hr.HyperRenderer) bool {
 		currentPage, ok := currentRenderer.(*hr.Webpage)
 		if !ok {
@@ -170,10 +830,18 @@ func (gg *GraphGenerator) Generate(maxHubCount, maxAuthCount int) error {
 		} else if currentPage.Type == hr.WebpageTypeAuthority {
 			authCount++
 		}
-
 		return false
 	})
 
+	return hubCount, authCount, err
+}
+
+// Generate creates pages until the graph has maxHubCount hubs and maxAuthCount
+// authorities, then cross links up to CrossLinksPerPage. If the model can not
+// attach a page, e.g. a hierarchy of bounded fanout being full, it stops with
+// an error telling how many pages the graph has.
+func (gg *GraphGenerator) Generate(maxHubCount, maxAuthCount int) error {
+	hubCount, authCount, err := gg.countPages()
 	if err != nil {
 		return err
 	}
@@ -183,168 +851,398 @@ func (gg *GraphGenerator) Generate(maxHubCount, maxAuthCount int) error {
 	}
 
 	for hubCount < maxHubCount {
-		gg.CreateHubPage()
+		if _, err := gg.CreateHubPage(); err != nil {
+			return fmt.Errorf("generating hub %d of %d: %w", hubCount+1, maxHubCount, err)
+		}
 		hubCount++
 	}
 	for authCount < maxAuthCount {
-		gg.CreateAuthorityPage()
+		if _, err := gg.CreateAuthorityPage(); err != nil {
+			return fmt.Errorf("generating authority %d of %d: %w", authCount+1, maxAuthCount, err)
+		}
 		authCount++
 	}
+
+	crossLinkCount, err := gg.countCrossLinks()
+	if err != nil {
+		return err
+	}
+	for crossLinkCount < int(gg.CrossLinksPerPage*float64(hubCount+authCount)) {
+		if _, _, err := gg.CreateCrossLink(); err != nil {
+			if errors.Is(err, ErrNoPageToLink) {
+				break
+			}
+			return err
+		}
+		crossLinkCount++
+	}
 	return nil
 }
 
-func (gg *GraphGenerator) StartGraphEvolution(
-	maxHubCount, maxAuthCount int,
-	authCreationRate float64, hubCreationRate float64,
-) (chan UpdateMessage, chan error, error) {
-	// Since the rate is specified in pages per hour,
-	authCreationInterval := time.Hour / time.Duration(authCreationRate)
-	hubCreationInterval := time.Hour / time.Duration(hubCreationRate)
+// countCrossLinks counts the links of live pages to live pages other than
+// their children.
+func (gg *GraphGenerator) countCrossLinks() (count int, err error) {
+	gg.mu.Lock()
+	defer gg.mu.Unlock()
+	gg.Root.RLockGraph()
+	defer gg.Root.RUnlockGraph()
 
-	// Count existing hub and authority pages
-	hubCount := 0
-	authCount := 0
-	var err error
 	hr.Traverse(gg.Root, func(currentRenderer hr.HyperRenderer) bool {
 		currentPage, ok := currentRenderer.(*hr.Webpage)
 		if !ok {
 			err = ErrUnexpectedNodeType
-			return false
+			return true
 		}
-		if currentPage.Type == hr.WebpageTypeHub {
-			hubCount++
-		} else if currentPage.Type == hr.WebpageTypeAuthority {
-			authCount++
+		for _, link := range currentPage.Links {
+			if !link.Deleted && link.Parent != currentPage {
+				count++
+			}
 		}
 		return false
 	})
 
+	return count, err
+}
+
+// evolutionStep performs a single step of an evolution process at the given
+// time, done reports that the process has nothing left to do.
+type evolutionStep func(at time.Time) (updates []UpdateMessage, done bool, err error)
+
+// evolutionProcess is a recurring step of the evolution.
+type evolutionProcess struct {
+	step evolutionStep
+	// next returns the time of the occurrence following the one at the given time.
+	next func(at time.Time) time.Time
+	at   time.Time
+}
+
+// rateInterval returns the interval between occurrences at rate per hour. The
+// rate must be positive and the interval at least a nanosecond, lest the
+// evolution never moves forward.
+func rateInterval(rate float64) (time.Duration, error) {
+	interval := float64(time.Hour) / rate
+	if !(rate > 0) || interval < 1 || interval >= math.MaxInt64 {
+		return 0, fmt.Errorf("%w: %v per hour", ErrInvalidRate, rate)
+	}
+	return time.Duration(interval), nil
+}
+
+func every(interval time.Duration) func(time.Time) time.Time {
+	return func(at time.Time) time.Time {
+		return at.Add(interval)
+	}
+}
+
+// StartGraphEvolution grows the graph up to maxHubCount hubs and maxAuthCount
+// authorities, rates being specified in pages per hour. Creation rates must be
+// positive and the other rates positive or zero, ErrInvalidRate is returned
+// otherwise. If the graph already exceeds a maximum it fails with
+// ErrMaxHubOrAuthCountAlreadyExceeded, unless WithExceededMaxima is given.
+// With deletion enabled deleted pages are replaced to keep the counts steady.
+// Without deletion, modification, cross links and moves the evolution ends
+// once both counts are reached, otherwise it runs until StopGraphEvolution is
+// called. Both returned channels are closed when the evolution ends.
+func (gg *GraphGenerator) StartGraphEvolution(
+	maxHubCount, maxAuthCount int,
+	authCreationRate float64, hubCreationRate float64,
+) (chan UpdateMessage, chan error, error) {
+	authCreationInterval, err := rateInterval(authCreationRate)
+	if err != nil {
+		return nil, nil, fmt.Errorf("authority creation: %w", err)
+	}
+	hubCreationInterval, err := rateInterval(hubCreationRate)
+	if err != nil {
+		return nil, nil, fmt.Errorf("hub creation: %w", err)
+	}
+
+	// Count existing hub and authority pages
+	hubCount, authCount, err := gg.countPages()
 	if err != nil {
 		return nil, nil, err
 	}
 
-	if hubCount > maxHubCount || authCount > maxAuthCount {
+	if (hubCount > maxHubCount || authCount > maxAuthCount) && !gg.ExceededMaximaAllowed {
 		return nil, nil, ErrMaxHubOrAuthCountAlreadyExceeded
 	}
 
-	actionsCount := (maxHubCount - hubCount) + (maxAuthCount - authCount)
+	steady := gg.HubDeletionRate > 0 || gg.AuthDeletionRate > 0
+
+	processes := []*evolutionProcess{
+		{step: gg.creationStep(hr.WebpageTypeHub, maxHubCount, steady), next: every(hubCreationInterval)},
+		{step: gg.creationStep(hr.WebpageTypeAuthority, maxAuthCount, steady), next: every(authCreationInterval)},
+	}
+
+	// zero disables the optional processes
+	optionalRates := []struct {
+		name string
+		rate float64
+		step evolutionStep
+	}{
+		{"hub deletion", gg.HubDeletionRate, gg.deletionStep(hr.WebpageTypeHub)},
+		{"authority deletion", gg.AuthDeletionRate, gg.deletionStep(hr.WebpageTypeAuthority)},
+		{"cross link", gg.CrossLinkRate, gg.crossLinkStep()},
+		{"move", gg.MoveRate, gg.moveStep()},
+	}
+	for _, optionalRate := range optionalRates {
+		if optionalRate.rate == 0 {
+			continue
+		}
+		interval, err := rateInterval(optionalRate.rate)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", optionalRate.name, err)
+		}
+		processes = append(processes, &evolutionProcess{
+			step: optionalRate.step,
+			next: every(interval),
+		})
+	}
+
+	if gg.ChangeRateDistribution != nil {
+		gg.mu.Lock()
+		gg.Root.LockGraph()
+		hr.Traverse(gg.Root, func(currentRenderer hr.HyperRenderer) bool {
+			if currentPage, ok := currentRenderer.(*hr.Webpage); ok {
+				gg.assignChangeRate(currentPage)
+			}
+			return false
+		})
+		gg.Root.UnlockGraph()
+		gg.mu.Unlock()
+
+		processes = append(processes, &evolutionProcess{
+			step: gg.modificationStep(),
+			next: gg.nextModification,
+		})
+	}
+
+	// the evolution is scheduled from now on however late its goroutine runs
+	start := gg.Clock.Now()
+	for _, process := range processes {
+		process.at = process.next(start)
+	}
+
+	actionsCount := max(0, maxHubCount-hubCount) + max(0, maxAuthCount-authCount)
 	updateChan := make(chan UpdateMessage, actionsCount)
-	errChan := make(chan error, actionsCount)
-	GeneratorErrorChan := make(chan struct{}, 0)
-	GeneratorDoneChan := make(chan struct{}, 0)
+	errChan := make(chan error, 1)
+	stopChan := make(chan struct{})
+
+	var stopOnce sync.Once
+	stop := func() {
+		stopOnce.Do(func() { close(stopChan) })
+	}
+	gg.mu.Lock()
+	gg.stopEvolution = stop
+	gg.mu.Unlock()
 
-	// Creates hub pages Asynchronously
-	retreatChan := make(chan struct{}, 0)
 	go func() {
-		var tickerChan <-chan time.Time
-		if gg.Debug {
-			tickerChan = MockTicker(maxHubCount)
-		} else {
-			ticker := time.NewTicker(hubCreationInterval)
-			tickerChan = ticker.C
-			defer ticker.Stop()
+		defer close(updateChan)
+		defer close(errChan)
+		if err := gg.runEvolution(processes, stopChan, updateChan); err != nil {
+			errChan <- err
 		}
-	outerLoop:
-		for {
-			select {
-			case <-tickerChan:
-				if hubCount < maxHubCount {
-					webpage, err := gg.CreateHubPage()
-					if err != nil {
-						errChan <- err
-						GeneratorErrorChan <- struct{}{}
-						retreatChan <- struct{}{}
-						return
-					}
-					hubCount++
-					updateChan <- UpdateMessage{
-						Type:    UpdateTypeCreate,
-						Webpage: webpage,
-					}
-				} else {
-					break outerLoop
-				}
-			case <-retreatChan:
-				GeneratorErrorChan <- struct{}{}
-				return
+	}()
+
+	return updateChan, errChan, nil
+}
+
+// StopGraphEvolution stops the running graph evolution, if any.
+func (gg *GraphGenerator) StopGraphEvolution() {
+	gg.mu.Lock()
+	stop := gg.stopEvolution
+	gg.mu.Unlock()
+
+	if stop != nil {
+		stop()
+	}
+}
+
+// runEvolution runs the processes one occurrence at a time in chronological
+// order, ties going to the earlier process, so that a seeded evolution on a
+// manual clock is reproducible. Occurrences the clock has already passed are
+// caught up on at once. It returns when every process is done, on the first
+// error or when the evolution is stopped.
+func (gg *GraphGenerator) runEvolution(
+	processes []*evolutionProcess,
+	stopChan <-chan struct{}, updateChan chan<- UpdateMessage,
+) error {
+	for len(processes) > 0 {
+		next := 0
+		for i, process := range processes {
+			if process.at.Before(processes[next].at) {
+				next = i
 			}
 		}
-		GeneratorDoneChan <- struct{}{}
-	}()
+		process := processes[next]
 
-	go func() {
-		var tickerChan <-chan time.Time
-		if gg.Debug {
-			tickerChan = MockTicker(maxAuthCount)
+		if wait := process.at.Sub(gg.Clock.Now()); wait > 0 {
+			timer := gg.Clock.NewTimer(wait)
+			select {
+			case <-timer.C():
+			case <-stopChan:
+				timer.Stop()
+				return nil
+			}
 		} else {
-			ticker := time.NewTicker(authCreationInterval)
-			tickerChan = ticker.C
-			defer ticker.Stop()
-		}
-	outerLoop:
-		for {
 			select {
-			case <-tickerChan:
-				if authCount < maxAuthCount {
-					webpage, err := gg.CreateAuthorityPage()
-					if err != nil {
-						errChan <- err
-						GeneratorErrorChan <- struct{}{}
-						retreatChan <- struct{}{}
-						return
-					}
-					authCount++
-					updateChan <- UpdateMessage{
-						Type:    UpdateTypeCreate,
-						Webpage: webpage,
-					}
-				} else {
-					break outerLoop
-				}
-			case <-retreatChan:
-				GeneratorErrorChan <- struct{}{}
-				return
+			case <-stopChan:
+				return nil
+			default:
 			}
 		}
-		GeneratorDoneChan <- struct{}{}
-	}()
 
-	go func() {
-		countDone := 0
-		countRetreated := 0
-	outerLoop:
-		for {
+		updates, done, err := process.step(process.at)
+		if err != nil {
+			return err
+		}
+		for _, update := range updates {
 			select {
-			case <-GeneratorErrorChan:
-				countRetreated++
-				if countRetreated == 2 {
-					break outerLoop
-				}
-			case <-GeneratorDoneChan:
-				countDone++
-				if countDone == 2 {
-					break outerLoop
-				}
+			case updateChan <- update:
+			case <-stopChan:
+				return nil
 			}
 		}
-		close(updateChan)
-		close(errChan)
-		close(GeneratorDoneChan)
-		close(GeneratorErrorChan)
-		close(retreatChan)
-	}()
 
-	return updateChan, errChan, nil
+		if done {
+			processes = append(processes[:next], processes[next+1:]...)
+		} else {
+			process.at = process.next(process.at)
+		}
+	}
+	return nil
 }
 
-func MockTicker(n int) <-chan time.Time {
-	t := time.Now()
-	c := make(chan time.Time, n)
-	go func() {
-		for i := 0; i < n; i++ {
-			c <- t
+// poissonIdleInterval is how long the modification process waits before
+// checking again when no page has a positive change rate.
+const poissonIdleInterval = time.Second
+
+// nextModification draws exponentially distributed intervals whose rate is
+// the total change rate of the graph, i.e. the superposition of the per-page
+// modification processes.
+func (gg *GraphGenerator) nextModification(at time.Time) time.Time {
+	rate := gg.totalChangeRate()
+	if rate <= 0 {
+		return at.Add(poissonIdleInterval)
+	}
+	return at.Add(time.Duration(gg.rng.ExpFloat64() / rate * float64(time.Hour)))
+}
+
+func (gg *GraphGenerator) creationStep(webpageType hr.WebpageType, maxCount int, steady bool) evolutionStep {
+	return func(at time.Time) ([]UpdateMessage, bool, error) {
+		hubCount, authCount, err := gg.countPages()
+		if err != nil {
+			return nil, false, err
 		}
-		close(c)
-	}()
-	return c
+
+		count := authCount
+		if webpageType == hr.WebpageTypeHub {
+			count = hubCount
+		}
+		if count >= maxCount {
+			return nil, !steady, nil
+		}
+
+		webpage, err := gg.createPage(webpageType, at)
+		// the model may have no room left, e.g. a hierarchy of bounded fanout
+		if errors.Is(err, ErrNoParent) {
+			return nil, !steady, nil
+		}
+		if err != nil {
+			return nil, false, err
+		}
+
+		update := UpdateMessage{
+			Type:    UpdateTypeCreate,
+			Webpage: webpage,
+			At:      at,
+		}
+		return []UpdateMessage{update}, !steady && count+1 >= maxCount, nil
+	}
+}
+
+func (gg *GraphGenerator) deletionStep(webpageType hr.WebpageType) evolutionStep {
+	return func(at time.Time) ([]UpdateMessage, bool, error) {
+		deleted, reparented, err := gg.deletePage(webpageType)
+		if errors.Is(err, ErrNoPageToDelete) {
+			return nil, false, nil
+		}
+		if err != nil {
+			return nil, false, err
+		}
+
+		updates := make([]UpdateMessage, 0, len(deleted)+len(reparented))
+		for _, webpage := range deleted {
+			updates = append(updates, UpdateMessage{
+				Type:    UpdateTypeDelete,
+				Webpage: webpage,
+				At:      at,
+			})
+		}
+		for _, r := range reparented {
+			updates = append(updates, UpdateMessage{
+				Type:      UpdateTypeMove,
+				Webpage:   r.webpage,
+				At:        at,
+				Redirects: r.redirects,
+			})
+		}
+		return updates, false, nil
+	}
+}
+
+func (gg *GraphGenerator) modificationStep() evolutionStep {
+	return func(at time.Time) ([]UpdateMessage, bool, error) {
+		webpage, err := gg.modifyPage(at)
+		if errors.Is(err, ErrNoPageToModify) {
+			return nil, false, nil
+		}
+		if err != nil {
+			return nil, false, err
+		}
+
+		update := UpdateMessage{
+			Type:    UpdateTypeModify,
+			Webpage: webpage,
+			At:      at,
+		}
+		return []UpdateMessage{update}, false, nil
+	}
+}
+
+func (gg *GraphGenerator) crossLinkStep() evolutionStep {
+	return func(at time.Time) ([]UpdateMessage, bool, error) {
+		source, _, err := gg.CreateCrossLink()
+		if errors.Is(err, ErrNoPageToLink) {
+			return nil, false, nil
+		}
+		if err != nil {
+			return nil, false, err
+		}
+
+		update := UpdateMessage{
+			Type:    UpdateTypeLink,
+			Webpage: source,
+			At:      at,
+		}
+		return []UpdateMessage{update}, false, nil
+	}
+}
+
+func (gg *GraphGenerator) moveStep() evolutionStep {
+	return func(at time.Time) ([]UpdateMessage, bool, error) {
+		webpage, redirects, err := gg.MovePage()
+		if errors.Is(err, ErrNoPageToMove) {
+			return nil, false, nil
+		}
+		if err != nil {
+			return nil, false, err
+		}
+
+		update := UpdateMessage{
+			Type:      UpdateTypeMove,
+			Webpage:   webpage,
+			At:        at,
+			Redirects: redirects,
+		}
+		return []UpdateMessage{update}, false, nil
+	}
 }
@@ -2,14 +2,19 @@ package graphgenerator_test
 
 import (
 	"fmt"
+	"math"
+	"slices"
 	"sort"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/pkg/errors"
+	clk "github.com/sdqri/sequined/internal/clock"
 	"github.com/sdqri/sequined/internal/graphgenerator"
 	hr "github.com/sdqri/sequined/internal/hyperrenderer"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type RootGenerator func() *hr.Webpage
@@ -227,7 +232,7 @@ func TestStartGraphEvolution(t *testing.T) {
 			maxAuthCount:               5,
 			expectedError:              nil,
 			expectedCountUpdateMessage: 9 + 5,
-			waitFor:                    (9 + 5) * 2 * (time.Hour / 1_000_000),
+			waitFor:                    5 * time.Second,
 		},
 		{
 			name: "big generate",
@@ -239,7 +244,7 @@ func TestStartGraphEvolution(t *testing.T) {
 			maxAuthCount:               5000,
 			expectedError:              nil,
 			expectedCountUpdateMessage: 100 + 5000,
-			waitFor:                    (100 + 5000) * 2 * (time.Hour / 1_000_000),
+			waitFor:                    5 * time.Second,
 		},
 		{
 			name: "exceeded max generate",
@@ -259,14 +264,16 @@ func TestStartGraphEvolution(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			root := tc.rootGenerator()
-			gg := graphgenerator.New(root, tc.preferentialAttachment)
-			gg.Debug = true
+			clock := clk.NewManual(time.Now())
+			gg := graphgenerator.New(root, tc.preferentialAttachment, graphgenerator.WithClock(clock))
 			updateChan, errChan, err := gg.StartGraphEvolution(
 				tc.maxHubCount, tc.maxAuthCount,
 				1_000_000, 1_000_000,
 			)
 			assert.Equal(t, err, tc.expectedError, "Unexpected error while calling gg.Generate")
 			if tc.expectedError == nil {
+				// creation ends on its own, so a single advance covers it
+				go clock.Advance(time.Hour)
 				countUpdateMessage := 0
 			outerLoop:
 				for {
@@ -309,3 +316,618 @@ func TestStartGraphEvolution(t *testing.T) {
 		})
 	}
 }
+
+func TestStartGraphEvolutionInvalidRates(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         []graphgenerator.GraphGeneratorOption
+		creationRate float64
+	}{
+		{"zero creation rate", nil, 0},
+		{"negative creation rate", nil, -1},
+		{"NaN creation rate", nil, math.NaN()},
+		{"negative deletion rate", []graphgenerator.GraphGeneratorOption{graphgenerator.WithDeletionRates(0, -1)}, 1},
+		{"negative cross link rate", []graphgenerator.GraphGeneratorOption{graphgenerator.WithCrossLinks(0, -1)}, 1},
+		{"NaN move rate", []graphgenerator.GraphGeneratorOption{graphgenerator.WithMoves(math.NaN())}, 1},
+		{"vanishing move rate", []graphgenerator.GraphGeneratorOption{graphgenerator.WithMoves(1e-300)}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gg := graphgenerator.New(hr.NewWebpage(hr.WebpageTypeHub), 0.5, tt.opts...)
+			_, _, err := gg.StartGraphEvolution(1, 1, tt.creationRate, 1)
+			assert.ErrorIs(t, err, graphgenerator.ErrInvalidRate)
+		})
+	}
+}
+
+func TestStartGraphEvolutionWithExceededMax(t *testing.T) {
+	root := hr.NewWebpage(hr.WebpageTypeHub)
+	root.AddChild(hr.WebpageTypeAuthority).AddChild(hr.WebpageTypeAuthority)
+	root.AddChild(hr.WebpageTypeHub).AddChild(hr.WebpageTypeHub)
+
+	clock := clk.NewManual(time.Now())
+	gg := graphgenerator.New(root, 0.5, graphgenerator.WithClock(clock), graphgenerator.WithExceededMaxima())
+	updateChan, errChan, err := gg.StartGraphEvolution(2, 2, 1_000_000, 1_000_000)
+	require.NoError(t, err)
+
+	go clock.Advance(time.Hour)
+	for update := range updateChan {
+		assert.Fail(t, "unexpected update", "%+v", update)
+	}
+	assert.NoError(t, <-errChan)
+
+	hubCount, authCount := 0, 0
+	hr.Traverse(root, func(currentRenderer hr.HyperRenderer) bool {
+		if currentRenderer.(*hr.Webpage).Type == hr.WebpageTypeHub {
+			hubCount++
+		} else {
+			authCount++
+		}
+		return false
+	})
+	assert.Equal(t, 3, hubCount)
+	assert.Equal(t, 2, authCount)
+}
+
+func TestStartGraphEvolutionWithExceededMaxSteady(t *testing.T) {
+	root := hr.NewWebpage(hr.WebpageTypeHub)
+	for i := 0; i < 4; i++ {
+		root.AddChild(hr.WebpageTypeAuthority)
+	}
+
+	clock := clk.NewManual(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	gg := graphgenerator.New(root, 0.5,
+		graphgenerator.WithClock(clock),
+		graphgenerator.WithExceededMaxima(),
+		graphgenerator.WithDeletionRates(0, 60),
+	)
+	updateChan, errChan, err := gg.StartGraphEvolution(1, 2, 600, 600)
+	require.NoError(t, err)
+
+	types := make([]graphgenerator.UpdateType, 0)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for updateMsg := range updateChan {
+			types = append(types, updateMsg.Type)
+		}
+	}()
+	for i := 0; i < 5; i++ {
+		clock.BlockUntil(1)
+		clock.Advance(time.Minute)
+	}
+	clock.BlockUntil(1)
+	gg.StopGraphEvolution()
+	<-done
+	assert.NoError(t, <-errChan)
+
+	// deletions bring the four authorities under two before any is created
+	require.Contains(t, types, graphgenerator.UpdateTypeCreate)
+	firstCreate := slices.Index(types, graphgenerator.UpdateTypeCreate)
+	assert.Equal(t, []graphgenerator.UpdateType{
+		graphgenerator.UpdateTypeDelete, graphgenerator.UpdateTypeDelete, graphgenerator.UpdateTypeDelete,
+	}, types[:firstCreate])
+}
+
+func TestDeleteHubPage(t *testing.T) {
+	testCases := []struct {
+		name                 string
+		orphanPolicy         graphgenerator.OrphanPolicy
+		expectedDeletedCount int
+		expectedRootLinks    int
+		expectedHubCount     int
+		expectedAuthCount    int
+		expectedRedirects    int
+	}{
+		{
+			name:                 "reparent",
+			orphanPolicy:         graphgenerator.OrphanPolicyReparent,
+			expectedDeletedCount: 1,
+			expectedRootLinks:    2,
+			expectedHubCount:     1,
+			expectedAuthCount:    2,
+			expectedRedirects:    2,
+		},
+		{
+			name:                 "cascade",
+			orphanPolicy:         graphgenerator.OrphanPolicyCascade,
+			expectedDeletedCount: 3,
+			expectedRootLinks:    0,
+			expectedHubCount:     1,
+			expectedAuthCount:    0,
+		},
+		{
+			name:                 "dangle",
+			orphanPolicy:         graphgenerator.OrphanPolicyDangle,
+			expectedDeletedCount: 3,
+			expectedRootLinks:    1,
+			expectedHubCount:     1,
+			expectedAuthCount:    0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			root := hr.NewWebpage(hr.WebpageTypeHub)
+			childHub := root.AddChild(hr.WebpageTypeHub)
+			childHub.AddChild(hr.WebpageTypeAuthority)
+			childHub.AddChild(hr.WebpageTypeAuthority)
+
+			gg := graphgenerator.New(root, 0.5, graphgenerator.WithOrphanPolicy(tc.orphanPolicy))
+			deleted, redirects, err := gg.DeleteHubPage()
+			assert.NoError(t, err, "Error deleting hub page")
+			assert.Len(t, deleted, tc.expectedDeletedCount)
+			assert.Len(t, redirects, tc.expectedRedirects)
+			for _, redirect := range redirects {
+				assert.Equal(t, redirect.Webpage.GetPath(), redirect.To)
+				assert.NotEqual(t, redirect.From, redirect.To)
+			}
+			assert.Equal(t, childHub, deleted[0])
+			for _, webpage := range deleted {
+				assert.True(t, webpage.Deleted)
+			}
+			assert.Len(t, root.Links, tc.expectedRootLinks)
+
+			hubCount, authCount := 0, 0
+			hr.Traverse(root, func(currentRenderer hr.HyperRenderer) bool {
+				if currentRenderer.(*hr.Webpage).Type == hr.WebpageTypeHub {
+					hubCount++
+				} else {
+					authCount++
+				}
+				return false
+			})
+			assert.Equal(t, tc.expectedHubCount, hubCount)
+			assert.Equal(t, tc.expectedAuthCount, authCount)
+
+			_, _, err = gg.DeleteHubPage()
+			assert.ErrorIs(t, err, graphgenerator.ErrNoPageToDelete)
+		})
+	}
+}
+
+func TestStartGraphEvolutionWithDeletion(t *testing.T) {
+	root := hr.NewWebpage(hr.WebpageTypeHub)
+	gg := graphgenerator.New(root, 0.5, graphgenerator.WithDeletionRates(0, 100_000))
+	updateChan, errChan, err := gg.StartGraphEvolution(3, 5, 100_000, 100_000)
+	assert.NoError(t, err, "Unexpected error while calling gg.StartGraphEvolution")
+
+	countDeleteMessage := 0
+	timeout := time.After(5 * time.Second)
+outerLoop:
+	for countDeleteMessage < 5 {
+		select {
+		case updateMsg := <-updateChan:
+			if updateMsg.Type == graphgenerator.UpdateTypeDelete {
+				assert.True(t, updateMsg.Webpage.Deleted)
+				assert.Equal(t, hr.WebpageType(hr.WebpageTypeAuthority), updateMsg.Webpage.Type)
+				countDeleteMessage++
+			}
+		case err, ok := <-errChan:
+			if ok {
+				assert.FailNow(t, fmt.Sprintf("Received an unexpected error from errChan, err:%s", err.Error()))
+			}
+		case <-timeout:
+			assert.Fail(t, "Expected number of delete messages not reached in wait time")
+			break outerLoop
+		}
+	}
+
+	gg.StopGraphEvolution()
+	for range updateChan {
+	}
+	_, ok := <-errChan
+	assert.False(t, ok, "errChan should be closed after stopping evolution")
+}
+
+func TestModifyPage(t *testing.T) {
+	root := hr.NewWebpage(hr.WebpageTypeHub)
+	root.ChangeRate = 3
+	child := root.AddChild(hr.WebpageTypeAuthority)
+	child.ChangeRate = 1
+	root.AddChild(hr.WebpageTypeAuthority)
+
+	gg := graphgenerator.New(root, 0.5)
+	f, probabilitesChan := CreateMockSelectByProbability()
+	gg.SelectorFunc = f
+
+	webpage, err := gg.ModifyPage()
+	assert.NoError(t, err, "Error modifying page")
+	actualProbabilities := <-probabilitesChan
+	sort.Sort(sort.Float64Slice(actualProbabilities))
+	assert.Equal(t, []float64{1, 3}, actualProbabilities)
+	assert.Equal(t, uint64(1), webpage.Version)
+	close(probabilitesChan)
+
+	_, err = graphgenerator.New(hr.NewWebpage(hr.WebpageTypeHub), 0.5).ModifyPage()
+	assert.ErrorIs(t, err, graphgenerator.ErrNoPageToModify)
+}
+
+func TestStartGraphEvolutionWithModification(t *testing.T) {
+	root := hr.NewWebpage(hr.WebpageTypeHub)
+	start := time.Now().UTC()
+	clock := clk.NewManual(start)
+	gg := graphgenerator.New(root, 0.5,
+		graphgenerator.WithClock(clock),
+		graphgenerator.WithChangeRateDistribution(graphgenerator.ConstantRate(60)),
+	)
+	updateChan, errChan, err := gg.StartGraphEvolution(3, 5, 1, 1)
+	assert.NoError(t, err, "Unexpected error while calling gg.StartGraphEvolution")
+
+	advanceDone := make(chan struct{})
+	stopAdvance := make(chan struct{})
+	go func() {
+		defer close(advanceDone)
+		for {
+			select {
+			case <-stopAdvance:
+				return
+			default:
+				clock.Advance(time.Second)
+			}
+		}
+	}()
+
+	countModifyMessage := 0
+	timeout := time.After(5 * time.Second)
+outerLoop:
+	for countModifyMessage < 10 {
+		select {
+		case updateMsg := <-updateChan:
+			if updateMsg.Type == graphgenerator.UpdateTypeModify {
+				root.RLockGraph()
+				assert.Greater(t, updateMsg.Webpage.Version, uint64(0))
+				assert.True(t, updateMsg.Webpage.ModifiedAt.After(start), "Modifications should be stamped by the clock")
+				root.RUnlockGraph()
+				countModifyMessage++
+			}
+		case err, ok := <-errChan:
+			if ok {
+				assert.FailNow(t, fmt.Sprintf("Received an unexpected error from errChan, err:%s", err.Error()))
+			}
+		case <-timeout:
+			assert.Fail(t, "Expected number of modify messages not reached in wait time")
+			break outerLoop
+		}
+	}
+
+	gg.StopGraphEvolution()
+	for range updateChan {
+	}
+	close(stopAdvance)
+	<-advanceDone
+	assert.Equal(t, float64(60), root.ChangeRate, "Existing pages should get a change rate")
+}
+
+func TestSeededEvolutionIsReproducible(t *testing.T) {
+	run := func(seed int64) string {
+		start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		clock := clk.NewManual(start)
+		root := hr.NewWebpage(hr.WebpageTypeHub, hr.WithSeed(seed))
+		root.CreatedAt = start
+		gg := graphgenerator.New(root, 0.5,
+			graphgenerator.WithSeed(seed),
+			graphgenerator.WithClock(clock),
+			graphgenerator.WithDeletionRates(2, 10),
+			graphgenerator.WithChangeRateDistribution(graphgenerator.ExponentialRate(1)),
+		)
+		require.NoError(t, gg.Generate(3, 10))
+		updateChan, errChan, err := gg.StartGraphEvolution(10, 50, 60, 10)
+		require.NoError(t, err)
+
+		var timeline strings.Builder
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for updateMsg := range updateChan {
+				fmt.Fprintf(&timeline, "%s %s %s\n", updateMsg.Type, updateMsg.Webpage.GetID(), updateMsg.At.Format(time.RFC3339Nano))
+			}
+		}()
+		for i := 0; i < 24*60; i++ {
+			// the evolution waits on a single timer once caught up
+			clock.BlockUntil(1)
+			clock.Advance(time.Minute)
+		}
+		// events of the last minute are done once it waits again
+		clock.BlockUntil(1)
+		gg.StopGraphEvolution()
+		<-done
+		_, ok := <-errChan
+		assert.False(t, ok)
+
+		hr.Traverse(root, func(node hr.HyperRenderer) bool {
+			webpage := node.(*hr.Webpage)
+			fmt.Fprintf(&timeline, "%s %s %s %s %d %s %s\n",
+				webpage.GetID(), webpage.Type, webpage.GetPath(), webpage.Faker().City(), webpage.Version,
+				webpage.CreatedAt.Format(time.RFC3339Nano), webpage.ModifiedAt.Format(time.RFC3339Nano))
+			return false
+		})
+		return timeline.String()
+	}
+
+	first := run(42)
+	assert.Contains(t, first, string(graphgenerator.UpdateTypeDelete))
+	assert.Contains(t, first, string(graphgenerator.UpdateTypeModify))
+	assert.Equal(t, first, run(42), "Same seed should reproduce the evolution")
+	assert.NotEqual(t, first, run(43))
+}
+
+func TestCreateCrossLink(t *testing.T) {
+	root := hr.NewWebpage(hr.WebpageTypeHub)
+	hubA := root.AddChild(hr.WebpageTypeHub)
+	hubB := root.AddChild(hr.WebpageTypeHub)
+	auth1 := hubA.AddChild(hr.WebpageTypeAuthority)
+	auth2 := hubB.AddChild(hr.WebpageTypeAuthority)
+	hubA.AddLink(auth2)
+
+	gg := graphgenerator.New(root, 1)
+	calls := make([][]float64, 0)
+	gg.SelectorFunc = func(probabilities []float64) (int, error) {
+		calls = append(calls, probabilities)
+		return 0, nil
+	}
+
+	source, target, err := gg.CreateCrossLink()
+	require.NoError(t, err)
+	require.Len(t, calls, 2)
+	// root is chosen as source, auth1 and auth2 are left to link by in-degree
+	assert.InDeltaSlice(t, []float64{0.2, 0.4}, calls[1], 1e-9)
+	assert.Equal(t, root, source)
+	assert.Equal(t, auth1, target)
+	assert.True(t, root.HasLink(auth1))
+	assert.Equal(t, hubA, auth1.Parent, "Cross link changed the parent")
+}
+
+func TestCreateBackLink(t *testing.T) {
+	for seed := int64(0); seed < 20; seed++ {
+		root := hr.NewWebpage(hr.WebpageTypeHub)
+		gg := graphgenerator.New(root, 0.5, graphgenerator.WithBackLinkProbability(1), graphgenerator.WithSeed(seed))
+		require.NoError(t, gg.Generate(5, 20))
+
+		// no page of a tree links its ancestors yet
+		source, target, err := gg.CreateCrossLink()
+		require.NoError(t, err)
+		if source != root {
+			assert.True(t, target.IsAncestorOf(source), "Back link target is not an ancestor")
+		}
+	}
+}
+
+func TestGenerateWithCrossLinks(t *testing.T) {
+	root := hr.NewWebpage(hr.WebpageTypeHub)
+	gg := graphgenerator.New(root, 0.5,
+		graphgenerator.WithCrossLinks(1.5, 0),
+		graphgenerator.WithBackLinkProbability(0.3),
+	)
+	require.NoError(t, gg.Generate(5, 15))
+
+	pageCount, crossLinkCount := 0, 0
+	hr.Traverse(root, func(currentRenderer hr.HyperRenderer) bool {
+		currentPage := currentRenderer.(*hr.Webpage)
+		pageCount++
+		crossLinkCount += len(currentPage.Links) - len(currentPage.Children())
+		return false
+	})
+	assert.Equal(t, 20, pageCount)
+	assert.Equal(t, 30, crossLinkCount)
+	assert.Len(t, hr.CreatePathMap(root), 20, "Paths are not unique")
+}
+
+func TestDeleteWithCrossLinks(t *testing.T) {
+	testCases := []struct {
+		name                string
+		orphanPolicy        graphgenerator.OrphanPolicy
+		expectedDeleted     int
+		expectedOtherLinked bool
+	}{
+		{name: "reparent", orphanPolicy: graphgenerator.OrphanPolicyReparent, expectedDeleted: 1, expectedOtherLinked: true},
+		{name: "cascade", orphanPolicy: graphgenerator.OrphanPolicyCascade, expectedDeleted: 2, expectedOtherLinked: false},
+		{name: "dangle", orphanPolicy: graphgenerator.OrphanPolicyDangle, expectedDeleted: 2, expectedOtherLinked: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			root := hr.NewWebpage(hr.WebpageTypeHub)
+			hub := root.AddChild(hr.WebpageTypeHub)
+			authority := hub.AddChild(hr.WebpageTypeAuthority)
+			other := root.AddChild(hr.WebpageTypeAuthority)
+			other.AddLink(authority)
+			authority.AddLink(root)
+			hub.AddLink(other)
+
+			gg := graphgenerator.New(root, 0.5, graphgenerator.WithOrphanPolicy(tc.orphanPolicy))
+			deleted, _, err := gg.DeleteHubPage()
+			require.NoError(t, err)
+
+			assert.Len(t, deleted, tc.expectedDeleted)
+			assert.False(t, root.Deleted, "Deletion followed a back link")
+			assert.False(t, other.Deleted, "Deletion followed a cross link")
+			assert.Equal(t, tc.expectedOtherLinked, other.HasLink(authority))
+			if tc.orphanPolicy == graphgenerator.OrphanPolicyReparent {
+				assert.Equal(t, root, authority.Parent)
+				assert.False(t, authority.Deleted)
+			}
+		})
+	}
+}
+
+func TestStartGraphEvolutionWithCrossLinks(t *testing.T) {
+	root := hr.NewWebpage(hr.WebpageTypeHub)
+	clock := clk.NewManual(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	gg := graphgenerator.New(root, 0.5, graphgenerator.WithClock(clock), graphgenerator.WithCrossLinks(0, 60))
+	require.NoError(t, gg.Generate(3, 5))
+
+	updateChan, errChan, err := gg.StartGraphEvolution(3, 5, 60, 60)
+	require.NoError(t, err)
+	go func() {
+		// each timer must be waiting before the clock passes its deadline
+		for i := 0; i < 10; i++ {
+			clock.BlockUntil(1)
+			clock.Advance(time.Minute)
+		}
+	}()
+
+	linkCount := 0
+	timeout := time.After(5 * time.Second)
+	for linkCount < 10 {
+		select {
+		case updateMsg := <-updateChan:
+			if updateMsg.Type == graphgenerator.UpdateTypeLink {
+				linkCount++
+			}
+		case err := <-errChan:
+			require.NoError(t, err)
+		case <-timeout:
+			require.FailNow(t, "Expected number of link messages not reached in wait time")
+		}
+	}
+
+	gg.StopGraphEvolution()
+	for range updateChan {
+	}
+}
+
+func TestMovePage(t *testing.T) {
+	root := hr.NewWebpage(hr.WebpageTypeHub)
+	hubA := root.AddChild(hr.WebpageTypeHub)
+	hubB := root.AddChild(hr.WebpageTypeHub)
+	auth := hubA.AddChild(hr.WebpageTypeAuthority)
+	hubAPath, authPath := hubA.GetPath(), auth.GetPath()
+
+	gg := graphgenerator.New(root, 0.5, graphgenerator.WithMoves(1, 308))
+	// hubA is moved under hubB, the only hub other than its parent out of
+	// its subtree
+	gg.SelectorFunc = func(probabilities []float64) (int, error) {
+		return 0, nil
+	}
+
+	webpage, redirects, err := gg.MovePage()
+	require.NoError(t, err)
+	assert.Equal(t, hubA, webpage)
+	assert.Equal(t, hubB, hubA.Parent)
+	assert.False(t, root.HasLink(hubA), "Former parent still links the moved page")
+	assert.True(t, hubB.HasLink(hubA))
+	assert.Equal(t, []graphgenerator.Redirect{
+		{From: hubAPath, To: hubA.GetPath(), Status: 308, Webpage: hubA},
+		{From: authPath, To: auth.GetPath(), Status: 308, Webpage: auth},
+	}, redirects)
+	assert.True(t, strings.HasPrefix(auth.GetPath(), hubB.GetPath()), "Subtree did not move along")
+}
+
+func TestMovePageRedirectLoop(t *testing.T) {
+	root := hr.NewWebpage(hr.WebpageTypeHub)
+	root.AddChild(hr.WebpageTypeHub)
+	auth := root.AddChild(hr.WebpageTypeAuthority)
+	authPath := auth.GetPath()
+
+	gg := graphgenerator.New(root, 0.5, graphgenerator.WithRedirectLoopProbability(1))
+	// auth is moved under the hub
+	gg.SelectorFunc = func(probabilities []float64) (int, error) {
+		return len(probabilities) - 1, nil
+	}
+
+	_, redirects, err := gg.MovePage()
+	require.NoError(t, err)
+	require.Len(t, redirects, 2)
+	assert.Equal(t, authPath, redirects[0].From)
+	assert.Equal(t, redirects[0].To, redirects[1].From)
+	assert.Equal(t, authPath, redirects[1].To)
+	assert.Equal(t, 301, redirects[0].Status)
+}
+
+func TestMovePageWithoutTarget(t *testing.T) {
+	root := hr.NewWebpage(hr.WebpageTypeHub)
+	root.AddChild(hr.WebpageTypeAuthority)
+
+	gg := graphgenerator.New(root, 0.5)
+	_, _, err := gg.MovePage()
+	assert.ErrorIs(t, err, graphgenerator.ErrNoPageToMove)
+}
+
+func TestStartGraphEvolutionWithMoves(t *testing.T) {
+	root := hr.NewWebpage(hr.WebpageTypeHub)
+	clock := clk.NewManual(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	gg := graphgenerator.New(root, 0.5, graphgenerator.WithClock(clock), graphgenerator.WithMoves(60), graphgenerator.WithSeed(1))
+	require.NoError(t, gg.Generate(3, 5))
+
+	updateChan, errChan, err := gg.StartGraphEvolution(3, 5, 60, 60)
+	require.NoError(t, err)
+	go func() {
+		// each timer must be waiting before the clock passes its deadline
+		for i := 0; i < 10; i++ {
+			clock.BlockUntil(1)
+			clock.Advance(time.Minute)
+		}
+	}()
+
+	moveCount := 0
+	timeout := time.After(5 * time.Second)
+	for moveCount < 10 {
+		select {
+		case updateMsg := <-updateChan:
+			if updateMsg.Type == graphgenerator.UpdateTypeMove {
+				assert.NotEmpty(t, updateMsg.Redirects)
+				moveCount++
+			}
+		case err := <-errChan:
+			require.NoError(t, err)
+		case <-timeout:
+			require.FailNow(t, "Expected number of move messages not reached in wait time")
+		}
+	}
+
+	gg.StopGraphEvolution()
+	for range updateChan {
+	}
+}
+
+func TestGenerateWithDuplicates(t *testing.T) {
+	root := hr.NewWebpage(hr.WebpageTypeHub)
+	gg := graphgenerator.New(root, 0.5, graphgenerator.WithSeed(1), graphgenerator.WithDuplicates(1, 0.7))
+	require.NoError(t, gg.Generate(3, 10))
+
+	originals, duplicates := 0, 0
+	hr.Traverse(root, func(renderer hr.HyperRenderer) bool {
+		webpage := renderer.(*hr.Webpage)
+		switch {
+		case webpage.DuplicateOf != nil:
+			duplicates++
+			assert.Equal(t, hr.WebpageType(hr.WebpageTypeAuthority), webpage.Type)
+			assert.Nil(t, webpage.DuplicateOf.DuplicateOf, "Duplicates should not duplicate duplicates")
+			assert.Equal(t, 0.7, webpage.Similarity)
+		case webpage.Type == hr.WebpageTypeAuthority:
+			originals++
+		}
+		return false
+	})
+	// only the first authority page has nothing to duplicate
+	assert.Equal(t, 1, originals)
+	assert.Equal(t, 9, duplicates)
+}
+
+func TestGenerateWithURLVariants(t *testing.T) {
+	tests := []struct {
+		name             string
+		variants         []hr.URLVariant
+		expectedVariants []hr.URLVariant
+	}{
+		{"given variants", []hr.URLVariant{hr.URLVariantQuery}, []hr.URLVariant{hr.URLVariantQuery}},
+		{"every variant", nil, hr.URLVariants},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := hr.NewWebpage(hr.WebpageTypeHub)
+			gg := graphgenerator.New(root, 0.5, graphgenerator.WithURLVariants(1, tt.variants...))
+			require.NoError(t, gg.Generate(3, 5))
+
+			hr.Traverse(root, func(renderer hr.HyperRenderer) bool {
+				if webpage := renderer.(*hr.Webpage); webpage != root {
+					assert.Equal(t, tt.expectedVariants, webpage.Variants)
+				}
+				return false
+			})
+		})
+	}
+}
@@ -0,0 +1,425 @@
+package graphgenerator
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+
+	hr "github.com/sdqri/sequined/internal/hyperrenderer"
+)
+
+var (
+	ErrNoParent error = errors.New("graph model found no parent for the new page")
+)
+
+// GraphModel decides where the pages created by the generator go, making the
+// topology of the graph.
+type GraphModel interface {
+	// Attach chooses where a new page of the given type goes. It is called with
+	// the graph locked and must not modify it.
+	Attach(graph *Graph, webpageType hr.WebpageType) (Attachment, error)
+}
+
+// Attachment is where a new page goes in the graph.
+type Attachment struct {
+	// Parent becomes the parent of the new page and links it.
+	Parent *hr.Webpage
+	// Links are the pages the new page links.
+	Links []*hr.Webpage
+	// LinkedFrom are the pages linking the new page besides Parent.
+	LinkedFrom []*hr.Webpage
+}
+
+// Graph is the view of the graph graph models decide on.
+type Graph struct {
+	Root *hr.Webpage
+	// Pages are the live pages in traversal order.
+	Pages []*hr.Webpage
+
+	inLinks  map[*hr.Webpage][]*hr.Webpage
+	depths   map[*hr.Webpage]int
+	rng      *rand.Rand
+	selector SelectorFunc
+}
+
+// graph returns the view of the graph, the graph must be locked.
+func (gg *GraphGenerator) graph() (*Graph, error) {
+	graph := &Graph{
+		Root:     gg.Root,
+		Pages:    make([]*hr.Webpage, 0),
+		inLinks:  make(map[*hr.Webpage][]*hr.Webpage),
+		depths:   make(map[*hr.Webpage]int),
+		rng:      gg.rng,
+		selector: gg.SelectorFunc,
+	}
+
+	var err error
+	hr.Traverse(gg.Root, func(currentRenderer hr.HyperRenderer) bool {
+		currentPage, ok := currentRenderer.(*hr.Webpage)
+		if !ok {
+			err = ErrUnexpectedNodeType
+			return true
+		}
+
+		graph.Pages = append(graph.Pages, currentPage)
+		for _, link := range currentPage.Links {
+			if !link.Deleted {
+				graph.inLinks[link] = append(graph.inLinks[link], currentPage)
+			}
+		}
+		return false
+	})
+	return graph, err
+}
+
+// Select chooses an index with probability proportional to its weight using
+// the selector of the generator.
+func (graph *Graph) Select(probabilities []float64) (int, error) {
+	return graph.selector(probabilities)
+}
+
+// Rand returns the random source of the generator, see WithSeed.
+func (graph *Graph) Rand() *rand.Rand {
+	return graph.rng
+}
+
+// InLinks returns the live pages linking the page.
+func (graph *Graph) InLinks(page *hr.Webpage) []*hr.Webpage {
+	return graph.inLinks[page]
+}
+
+// OutLinks returns the live pages the page links.
+func (graph *Graph) OutLinks(page *hr.Webpage) []*hr.Webpage {
+	links := make([]*hr.Webpage, 0, len(page.Links))
+	for _, link := range page.Links {
+		if !link.Deleted {
+			links = append(links, link)
+		}
+	}
+	return links
+}
+
+// Depth returns the number of parents between the page and the root.
+func (graph *Graph) Depth(page *hr.Webpage) int {
+	if depth, ok := graph.depths[page]; ok {
+		return depth
+	}
+	depth := 0
+	for current := page.Parent; current != nil; current = current.Parent {
+		depth++
+	}
+	graph.depths[page] = depth
+	return depth
+}
+
+// PagesOfType returns the live pages of the given type in traversal order.
+func (graph *Graph) PagesOfType(webpageType hr.WebpageType) []*hr.Webpage {
+	pages := make([]*hr.Webpage, 0)
+	for _, page := range graph.Pages {
+		if page.Type == webpageType {
+			pages = append(pages, page)
+		}
+	}
+	return pages
+}
+
+// SelectUniformly chooses one of the pages with equal probability.
+func (graph *Graph) SelectUniformly(pages []*hr.Webpage) (*hr.Webpage, error) {
+	if len(pages) == 0 {
+		return nil, ErrNoProbabilities
+	}
+	probabilities := make([]float64, len(pages))
+	for i := range probabilities {
+		probabilities[i] = 1 / float64(len(pages))
+	}
+	index, err := graph.Select(probabilities)
+	if err != nil {
+		return nil, err
+	}
+	return pages[index], nil
+}
+
+// HubAuthorityModel attaches hubs to hubs and authorities to hubs, choosing
+// the parent by a mix of preferential attachment on out-degree and uniform
+// choice. It is the default model of the generator.
+type HubAuthorityModel struct {
+	// PreferentialAttachment is the weight of preferential attachment against
+	// uniform choice, in [0, 1].
+	PreferentialAttachment float64
+}
+
+func (model HubAuthorityModel) Attach(graph *Graph, webpageType hr.WebpageType) (Attachment, error) {
+	hubNodes := graph.PagesOfType(hr.WebpageTypeHub)
+	if len(hubNodes) == 0 {
+		return Attachment{Parent: graph.Root}, nil
+	}
+
+	totalLinksCount := 0
+	for _, node := range hubNodes {
+		totalLinksCount += len(node.Links)
+	}
+
+	totalHubsCount := len(hubNodes)
+	probabilities := make([]float64, 0, totalHubsCount)
+	for _, node := range hubNodes {
+		linkCount := len(node.Links)
+		probability := float64(1) / float64(totalHubsCount)
+		if totalLinksCount != 0 {
+			probability = (float64(linkCount)/float64(totalLinksCount))*
+				model.PreferentialAttachment +
+				(1-model.PreferentialAttachment)*(1/float64(totalHubsCount))
+		}
+		probabilities = append(probabilities, probability)
+	}
+
+	hubIndex, err := graph.Select(probabilities)
+	if err != nil {
+		return Attachment{}, err
+	}
+	return Attachment{Parent: hubNodes[hubIndex]}, nil
+}
+
+// BarabasiAlbertModel attaches a new page to M existing pages chosen with
+// probability proportional to their degree, so that degrees follow a power
+// law. The first one becomes the parent, the new page links the others.
+type BarabasiAlbertModel struct {
+	M int
+}
+
+func (model BarabasiAlbertModel) Attach(graph *Graph, webpageType hr.WebpageType) (Attachment, error) {
+	// pages without links would never be chosen otherwise
+	weights := make([]float64, len(graph.Pages))
+	for i, page := range graph.Pages {
+		weights[i] = float64(len(graph.InLinks(page))+len(graph.OutLinks(page))) + 1
+	}
+
+	chosen, err := selectDistinct(graph, graph.Pages, weights, max(model.M, 1))
+	if err != nil {
+		return Attachment{}, err
+	}
+	return Attachment{Parent: chosen[0], Links: chosen[1:]}, nil
+}
+
+// selectDistinct chooses up to n distinct pages with probability proportional
+// to their weights.
+func selectDistinct(graph *Graph, pages []*hr.Webpage, weights []float64, n int) ([]*hr.Webpage, error) {
+	weights = append([]float64(nil), weights...)
+	chosen := make([]*hr.Webpage, 0, n)
+	for len(chosen) < n && len(chosen) < len(pages) {
+		index, err := graph.Select(weights)
+		if err != nil {
+			return nil, err
+		}
+		chosen = append(chosen, pages[index])
+		weights[index] = 0
+	}
+	if len(chosen) == 0 {
+		return nil, ErrNoParent
+	}
+	return chosen, nil
+}
+
+// CopyingModel makes a new page a sibling of a uniformly chosen prototype.
+// Each of its Links links copies the corresponding link of the prototype, or
+// is a uniformly chosen page with probability Beta, so that pages on the same
+// topic link the same pages.
+type CopyingModel struct {
+	Beta  float64
+	Links int
+}
+
+func (model CopyingModel) Attach(graph *Graph, webpageType hr.WebpageType) (Attachment, error) {
+	prototype, err := graph.SelectUniformly(graph.Pages)
+	if err != nil {
+		return Attachment{}, err
+	}
+
+	parent := prototype.Parent
+	if parent == nil || parent.Deleted {
+		parent = prototype
+	}
+
+	prototypeLinks := graph.OutLinks(prototype)
+	links := make([]*hr.Webpage, 0, model.Links)
+	for i := 0; i < model.Links; i++ {
+		var link *hr.Webpage
+		if i < len(prototypeLinks) && graph.Rand().Float64() >= model.Beta {
+			link = prototypeLinks[i]
+		} else {
+			link, err = graph.SelectUniformly(graph.Pages)
+			if err != nil {
+				return Attachment{}, err
+			}
+		}
+		if !contains(links, link) {
+			links = append(links, link)
+		}
+	}
+	return Attachment{Parent: parent, Links: links}, nil
+}
+
+// ErdosRenyiModel makes a random directed graph: the new page becomes the
+// child of a uniformly chosen page, then links and is linked by every other
+// page with probability P independently.
+type ErdosRenyiModel struct {
+	P float64
+}
+
+func (model ErdosRenyiModel) Attach(graph *Graph, webpageType hr.WebpageType) (Attachment, error) {
+	parent, err := graph.SelectUniformly(graph.Pages)
+	if err != nil {
+		return Attachment{}, err
+	}
+
+	attachment := Attachment{Parent: parent}
+	for _, page := range graph.Pages {
+		if graph.Rand().Float64() < model.P {
+			attachment.Links = append(attachment.Links, page)
+		}
+		if page != parent && graph.Rand().Float64() < model.P {
+			attachment.LinkedFrom = append(attachment.LinkedFrom, page)
+		}
+	}
+	return attachment, nil
+}
+
+// ForestFireModel makes the new page the child of a uniformly chosen
+// ambassador and links pages reached by burning through the graph from it, as
+// described by Leskovec et al. Every burnt page burns a geometrically
+// distributed number of its out-links with mean p/(1-p), p being
+// ForwardBurning, and of its in-links with p being ForwardBurning times
+// BackwardBurning. The new page links every burnt page, the ambassador
+// included, giving densifying graphs with shrinking diameters.
+type ForestFireModel struct {
+	ForwardBurning  float64
+	BackwardBurning float64
+}
+
+func (model ForestFireModel) Attach(graph *Graph, webpageType hr.WebpageType) (Attachment, error) {
+	ambassador, err := graph.SelectUniformly(graph.Pages)
+	if err != nil {
+		return Attachment{}, err
+	}
+
+	burnt := map[*hr.Webpage]struct{}{ambassador: {}}
+	links := []*hr.Webpage{ambassador}
+	queue := []*hr.Webpage{ambassador}
+	for len(queue) > 0 {
+		page := queue[0]
+		queue = queue[1:]
+
+		neighbours := [][]*hr.Webpage{graph.OutLinks(page), graph.InLinks(page)}
+		probabilities := []float64{model.ForwardBurning, model.ForwardBurning * model.BackwardBurning}
+		for i, candidates := range neighbours {
+			unburnt := make([]*hr.Webpage, 0, len(candidates))
+			for _, candidate := range candidates {
+				if _, ok := burnt[candidate]; !ok {
+					unburnt = append(unburnt, candidate)
+				}
+			}
+
+			count := geometric(graph.Rand(), probabilities[i])
+			for _, index := range graph.Rand().Perm(len(unburnt)) {
+				if count == 0 {
+					break
+				}
+				count--
+				burnt[unburnt[index]] = struct{}{}
+				links = append(links, unburnt[index])
+				queue = append(queue, unburnt[index])
+			}
+		}
+	}
+	return Attachment{Parent: ambassador, Links: links}, nil
+}
+
+// geometric draws the number of failures before the first success of trials
+// failing with probability p, its mean being p/(1-p).
+func geometric(rng *rand.Rand, p float64) int {
+	if p <= 0 {
+		return 0
+	}
+	if p >= 1 {
+		return math.MaxInt
+	}
+	return int(math.Floor(math.Log(1-rng.Float64()) / math.Log(p)))
+}
+
+// HierarchicalModel makes a site of categories, listings and details: hubs
+// are categories and listings down to depth Depth-1 and authorities are
+// details at depth Depth at most, attached to the deepest hubs. Hubs get
+// Fanout hub children at most, zero leaving it unbounded, and details link
+// their ancestors like breadcrumbs do.
+type HierarchicalModel struct {
+	Depth  int
+	Fanout int
+}
+
+func (model HierarchicalModel) Attach(graph *Graph, webpageType hr.WebpageType) (Attachment, error) {
+	depth := max(model.Depth, 2)
+
+	hubs := make([]*hr.Webpage, 0)
+	for _, hub := range graph.PagesOfType(hr.WebpageTypeHub) {
+		if graph.Depth(hub) < depth {
+			hubs = append(hubs, hub)
+		}
+	}
+
+	if webpageType == hr.WebpageTypeAuthority {
+		deepest := 0
+		for _, hub := range hubs {
+			deepest = max(deepest, graph.Depth(hub))
+		}
+		listings := make([]*hr.Webpage, 0)
+		for _, hub := range hubs {
+			if graph.Depth(hub) == deepest {
+				listings = append(listings, hub)
+			}
+		}
+
+		parent, err := graph.SelectUniformly(listings)
+		if err != nil {
+			return Attachment{}, ErrNoParent
+		}
+		links := make([]*hr.Webpage, 0, deepest+1)
+		for ancestor := parent; ancestor != nil; ancestor = ancestor.Parent {
+			links = append(links, ancestor)
+		}
+		return Attachment{Parent: parent, Links: links}, nil
+	}
+
+	categories := make([]*hr.Webpage, 0)
+	for _, hub := range hubs {
+		if graph.Depth(hub) < depth-1 && (model.Fanout <= 0 || countHubChildren(hub) < model.Fanout) {
+			categories = append(categories, hub)
+		}
+	}
+	if len(categories) == 0 {
+		return Attachment{}, ErrNoParent
+	}
+
+	parent, err := graph.SelectUniformly(categories)
+	if err != nil {
+		return Attachment{}, err
+	}
+	return Attachment{Parent: parent}, nil
+}
+
+func countHubChildren(webpage *hr.Webpage) int {
+	count := 0
+	for _, child := range webpage.Children() {
+		if child.Type == hr.WebpageTypeHub && !child.Deleted {
+			count++
+		}
+	}
+	return count
+}
+
+func contains(pages []*hr.Webpage, page *hr.Webpage) bool {
+	for _, p := range pages {
+		if p == page {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,134 @@
+package graphgenerator_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sdqri/sequined/internal/graphgenerator"
+	hr "github.com/sdqri/sequined/internal/hyperrenderer"
+)
+
+func generateWithModel(t *testing.T, model graphgenerator.GraphModel, hubCount, authCount int) (*hr.Webpage, []*hr.Webpage) {
+	t.Helper()
+
+	root := hr.NewWebpage(hr.WebpageTypeHub, hr.WithSeed(1))
+	gg := graphgenerator.New(root, 0.5, graphgenerator.WithModel(model), graphgenerator.WithSeed(1))
+	require.NoError(t, gg.Generate(hubCount, authCount))
+
+	pages := make([]*hr.Webpage, 0)
+	hr.Traverse(root, func(currentRenderer hr.HyperRenderer) bool {
+		pages = append(pages, currentRenderer.(*hr.Webpage))
+		return false
+	})
+	require.Len(t, hr.CreatePathMap(root), len(pages), "Paths are not unique")
+	return root, pages
+}
+
+func crossLinks(page *hr.Webpage) int {
+	return len(page.Links) - len(page.Children())
+}
+
+func TestBarabasiAlbertModel(t *testing.T) {
+	_, pages := generateWithModel(t, graphgenerator.BarabasiAlbertModel{M: 3}, 10, 20)
+	require.Len(t, pages, 30)
+
+	total := 0
+	for _, page := range pages {
+		assert.LessOrEqual(t, crossLinks(page), 2)
+		total += crossLinks(page)
+	}
+	// every page but the first two has two pages to link besides its parent
+	assert.Equal(t, 1+2*27, total)
+}
+
+func TestCopyingModel(t *testing.T) {
+	_, pages := generateWithModel(t, graphgenerator.CopyingModel{Beta: 0.5, Links: 2}, 10, 20)
+	require.Len(t, pages, 30)
+
+	for _, page := range pages {
+		assert.LessOrEqual(t, crossLinks(page), 2)
+	}
+}
+
+func TestErdosRenyiModel(t *testing.T) {
+	testCases := []struct {
+		name          string
+		p             float64
+		expectedLinks int
+	}{
+		{name: "P=0 makes a tree", p: 0, expectedLinks: 5},
+		{name: "P=1 makes a complete graph", p: 1, expectedLinks: 6 * 5},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, pages := generateWithModel(t, graphgenerator.ErdosRenyiModel{P: tc.p}, 3, 3)
+			require.Len(t, pages, 6)
+
+			links := 0
+			for _, page := range pages {
+				links += len(page.Links)
+			}
+			assert.Equal(t, tc.expectedLinks, links)
+		})
+	}
+}
+
+func TestForestFireModel(t *testing.T) {
+	_, pages := generateWithModel(t, graphgenerator.ForestFireModel{ForwardBurning: 0}, 5, 10)
+	for _, page := range pages {
+		if page.Parent == nil {
+			continue
+		}
+		// without burning only the ambassador, the parent, is linked
+		assert.Equal(t, 1, crossLinks(page))
+		assert.True(t, page.HasLink(page.Parent))
+	}
+
+	_, pages = generateWithModel(t, graphgenerator.ForestFireModel{ForwardBurning: 0.6, BackwardBurning: 0.3}, 10, 30)
+	total := 0
+	for _, page := range pages {
+		total += crossLinks(page)
+	}
+	assert.Greater(t, total, len(pages)-1, "Fire did not spread")
+}
+
+func TestHierarchicalModel(t *testing.T) {
+	_, pages := generateWithModel(t, graphgenerator.HierarchicalModel{Depth: 3, Fanout: 2}, 1+2+4, 20)
+
+	hubCount := 0
+	for _, page := range pages {
+		depth := 0
+		for ancestor := page.Parent; ancestor != nil; ancestor = ancestor.Parent {
+			depth++
+		}
+
+		switch page.Type {
+		case hr.WebpageTypeHub:
+			hubCount++
+			assert.LessOrEqual(t, depth, 2)
+			hubChildren := 0
+			for _, child := range page.Children() {
+				if child.Type == hr.WebpageTypeHub {
+					hubChildren++
+				}
+			}
+			assert.LessOrEqual(t, hubChildren, 2)
+		case hr.WebpageTypeAuthority:
+			assert.Equal(t, 3, depth, "Details are not at the bottom of the hierarchy")
+			// breadcrumbs
+			for ancestor := page.Parent; ancestor != nil; ancestor = ancestor.Parent {
+				assert.True(t, page.HasLink(ancestor))
+			}
+		}
+	}
+	assert.Equal(t, 1+2+4, hubCount)
+
+	root := hr.NewWebpage(hr.WebpageTypeHub, hr.WithSeed(1))
+	gg := graphgenerator.New(root, 0.5, graphgenerator.WithModel(graphgenerator.HierarchicalModel{Depth: 3, Fanout: 2}))
+	err := gg.Generate(10, 20)
+	assert.ErrorIs(t, err, graphgenerator.ErrNoParent, "Fanout does not bound the hierarchy")
+	assert.EqualError(t, err, "generating hub 8 of 10: "+graphgenerator.ErrNoParent.Error())
+}
@@ -3,7 +3,6 @@ package graphgenerator
 import (
 	"errors"
 	"math/rand"
-	"time"
 )
 
 var (
@@ -13,13 +12,22 @@ var (
 )
 
 func SelectByProbability(probabilities []float64) (int, error) {
+	return selectByProbability(rand.Float64, probabilities)
+}
+
+// NewProbabilitySelector returns a SelectorFunc like SelectByProbability
+// drawing from rng.
+func NewProbabilitySelector(rng *rand.Rand) SelectorFunc {
+	return func(probabilities []float64) (int, error) {
+		return selectByProbability(rng.Float64, probabilities)
+	}
+}
+
+func selectByProbability(float64Func func() float64, probabilities []float64) (int, error) {
 	if len(probabilities) == 0 {
 		return -1, ErrNoProbabilities
 	}
 
-	source := rand.NewSource(time.Now().UnixNano())
-	rng := rand.New(source)
-
 	// Calculate the sum of probabilities
 	var sum float64 = 0
 	for _, prob := range probabilities {
@@ -31,7 +39,7 @@ func SelectByProbability(probabilities []float64) (int, error) {
 	}
 
 	// Generate a random number between 0 and the sum of probabilities
-	r := rng.Float64() * sum
+	r := float64Func() * sum
 
 	var cumulativeProb float64 = 0
 	for i, prob := range probabilities {
@@ -43,3 +51,26 @@ func SelectByProbability(probabilities []float64) (int, error) {
 
 	return -1, ErrFailedToSelectIndex
 }
+
+// RateDistribution draws a per-page rate, e.g. changes per hour, from rng.
+type RateDistribution func(rng *rand.Rand) float64
+
+func ConstantRate(rate float64) RateDistribution {
+	return func(*rand.Rand) float64 {
+		return rate
+	}
+}
+
+// UniformRate draws rates uniformly from [min, max).
+func UniformRate(min, max float64) RateDistribution {
+	return func(rng *rand.Rand) float64 {
+		return min + rng.Float64()*(max-min)
+	}
+}
+
+// ExponentialRate draws rates from an exponential distribution with the given mean.
+func ExponentialRate(mean float64) RateDistribution {
+	return func(rng *rand.Rand) float64 {
+		return rng.ExpFloat64() * mean
+	}
+}
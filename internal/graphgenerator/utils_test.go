@@ -1,10 +1,12 @@
 package graphgenerator_test
 
 import (
+	"math/rand"
 	"testing"
 
 	"github.com/sdqri/sequined/internal/graphgenerator"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestSelectByProbability(t *testing.T) {
@@ -57,3 +59,33 @@ func sum(nums []float64) float64 {
 	}
 	return total
 }
+
+func TestRateDistributions(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	assert.Equal(t, 2.5, graphgenerator.ConstantRate(2.5)(rng))
+
+	for i := 0; i < 100; i++ {
+		rate := graphgenerator.UniformRate(1, 2)(rng)
+		assert.GreaterOrEqual(t, rate, float64(1))
+		assert.Less(t, rate, float64(2))
+
+		assert.GreaterOrEqual(t, graphgenerator.ExponentialRate(1)(rng), float64(0))
+	}
+}
+
+func TestNewProbabilitySelector(t *testing.T) {
+	probabilities := []float64{0.2, 0.3, 0.5}
+	draw := func(seed int64) []int {
+		selector := graphgenerator.NewProbabilitySelector(rand.New(rand.NewSource(seed)))
+		indexes := make([]int, 0, 20)
+		for i := 0; i < 20; i++ {
+			index, err := selector(probabilities)
+			require.NoError(t, err)
+			indexes = append(indexes, index)
+		}
+		return indexes
+	}
+
+	assert.Equal(t, draw(7), draw(7), "Same seed should select the same indexes")
+	assert.NotEqual(t, draw(7), draw(8))
+}
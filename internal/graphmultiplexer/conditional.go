@@ -0,0 +1,75 @@
+package graphmultiplexer
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	hyr "github.com/sdqri/sequined/internal/hyperrenderer"
+)
+
+type validator struct {
+	etag         string
+	lastModified time.Time
+}
+
+// validators returns the ETag and Last-Modified of the page, the graph must be
+// read locked. The rendered links of a page change without a content
+// modification, e.g. when a child is added, so Last-Modified moves to now
+// whenever the ETag changes at the same content version.
+func (mux *GraphMux) validators(page hyr.HyperRenderer) (string, time.Time, bool) {
+	webpage, ok := page.(*hyr.Webpage)
+	if !ok {
+		return "", time.Time{}, false
+	}
+
+	etag := webpage.ETag()
+	lastModified := webpage.LastModified()
+
+	mux.validatorMu.Lock()
+	defer mux.validatorMu.Unlock()
+
+	cached, ok := mux.validatorCache[webpage.GetID()]
+	switch {
+	case ok && cached.etag == etag:
+		lastModified = cached.lastModified
+	case ok:
+		if now := mux.Clock.Now().UTC(); now.After(lastModified) {
+			lastModified = now
+		}
+	}
+	if mux.validatorCache == nil {
+		mux.validatorCache = make(map[string]validator)
+	}
+	mux.validatorCache[webpage.GetID()] = validator{etag: etag, lastModified: lastModified}
+	return etag, lastModified, true
+}
+
+// notModified evaluates the conditional headers of the request. If-None-Match
+// takes precedence over If-Modified-Since as required by RFC 9110.
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		for _, candidate := range strings.Split(ifNoneMatch, ",") {
+			candidate = strings.TrimSpace(candidate)
+			// weak comparison
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+				return true
+			}
+		}
+		return false
+	}
+
+	if ifModifiedSince := r.Header.Get("If-Modified-Since"); ifModifiedSince != "" {
+		since, err := http.ParseTime(ifModifiedSince)
+		if err != nil {
+			return false
+		}
+		// Last-Modified has a resolution of seconds
+		return !lastModified.Truncate(time.Second).After(since)
+	}
+	return false
+}
@@ -0,0 +1,142 @@
+package graphmultiplexer_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	gmx "github.com/sdqri/sequined/internal/graphmultiplexer"
+	hyr "github.com/sdqri/sequined/internal/hyperrenderer"
+	obs "github.com/sdqri/sequined/internal/observer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConditionalGet(t *testing.T) {
+	root := hyr.NewWebpage(hyr.WebpageTypeHub)
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	root.CreatedAt = createdAt
+
+	mx, err := gmx.New(root)
+	require.NoError(t, err)
+
+	r := httptest.NewRecorder()
+	mx.ServeHTTP(r, httptest.NewRequest(http.MethodGet, "/", strings.NewReader("")))
+	require.Equal(t, http.StatusOK, r.Code)
+	etag := r.Header().Get("ETag")
+	assert.Equal(t, root.ETag(), etag)
+	assert.Equal(t, createdAt.Format(http.TimeFormat), r.Header().Get("Last-Modified"))
+
+	tests := []struct {
+		name           string
+		header         map[string]string
+		expectedStatus int
+	}{
+		{"unconditional", nil, http.StatusOK},
+		{"matching etag", map[string]string{"If-None-Match": etag}, http.StatusNotModified},
+		{"weak matching etag in list", map[string]string{"If-None-Match": `"other", W/` + etag}, http.StatusNotModified},
+		{"wildcard", map[string]string{"If-None-Match": "*"}, http.StatusNotModified},
+		{"other etag", map[string]string{"If-None-Match": `"other"`}, http.StatusOK},
+		{"not modified since", map[string]string{"If-Modified-Since": createdAt.Format(http.TimeFormat)}, http.StatusNotModified},
+		{"modified since", map[string]string{"If-Modified-Since": createdAt.Add(-time.Second).Format(http.TimeFormat)}, http.StatusOK},
+		{"etag takes precedence", map[string]string{
+			"If-None-Match":     `"other"`,
+			"If-Modified-Since": createdAt.Format(http.TimeFormat),
+		}, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", strings.NewReader(""))
+			for key, value := range tt.header {
+				req.Header.Set(key, value)
+			}
+			r := httptest.NewRecorder()
+			mx.ServeHTTP(r, req)
+			assert.Equal(t, tt.expectedStatus, r.Code)
+			assert.Equal(t, etag, r.Header().Get("ETag"))
+			if tt.expectedStatus == http.StatusNotModified {
+				assert.Empty(t, r.Body.String())
+			}
+		})
+	}
+}
+
+func TestConditionalGetAfterChange(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(root *hyr.Webpage)
+	}{
+		{"modification", func(root *hyr.Webpage) { root.Modify(time.Now().UTC()) }},
+		{"new link", func(root *hyr.Webpage) { root.AddChild(hyr.WebpageTypeAuthority) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := hyr.NewWebpage(hyr.WebpageTypeHub)
+			root.CreatedAt = time.Now().UTC().Add(-time.Hour)
+			mx, err := gmx.New(root)
+			require.NoError(t, err)
+
+			r := httptest.NewRecorder()
+			mx.ServeHTTP(r, httptest.NewRequest(http.MethodGet, "/", strings.NewReader("")))
+			etag, lastModified := r.Header().Get("ETag"), r.Header().Get("Last-Modified")
+
+			tt.change(root)
+
+			for header, value := range map[string]string{"If-None-Match": etag, "If-Modified-Since": lastModified} {
+				req := httptest.NewRequest(http.MethodGet, "/", strings.NewReader(""))
+				req.Header.Set(header, value)
+				r = httptest.NewRecorder()
+				mx.ServeHTTP(r, req)
+				assert.Equal(t, http.StatusOK, r.Code, header)
+				assert.NotEqual(t, etag, r.Header().Get("ETag"))
+			}
+		})
+	}
+}
+
+func TestConditionalGetAfterCanonicalChange(t *testing.T) {
+	root := hyr.NewWebpage(hyr.WebpageTypeHub, hyr.WithCanonicalTag())
+	hub := root.AddChild(hyr.WebpageTypeHub)
+	original := root.AddChild(hyr.WebpageTypeAuthority)
+	duplicate := root.AddChild(hyr.WebpageTypeAuthority)
+	duplicate.DuplicateOf, duplicate.Similarity = original, 1
+	mx, err := gmx.New(root)
+	require.NoError(t, err)
+
+	r := httptest.NewRecorder()
+	mx.ServeHTTP(r, httptest.NewRequest(http.MethodGet, duplicate.GetPath(), strings.NewReader("")))
+	etag := r.Header().Get("ETag")
+	require.Contains(t, r.Body.String(), `<link rel="canonical" href="`+original.GetPath()+`">`)
+
+	root.RemoveLink(original)
+	hub.Adopt(original)
+
+	req := httptest.NewRequest(http.MethodGet, duplicate.GetPath(), strings.NewReader(""))
+	req.Header.Set("If-None-Match", etag)
+	r = httptest.NewRecorder()
+	mx.ServeHTTP(r, req)
+	assert.Equal(t, http.StatusOK, r.Code)
+	assert.NotEqual(t, etag, r.Header().Get("ETag"))
+	assert.Contains(t, r.Body.String(), `<link rel="canonical" href="`+original.GetPath()+`">`)
+}
+
+func TestRevalidationLogging(t *testing.T) {
+	root := hyr.NewWebpage(hyr.WebpageTypeHub)
+	observer := obs.New()
+	mx, err := gmx.New(root, gmx.WithObserver(observer))
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/", strings.NewReader(""))
+	mx.ServeHTTP(httptest.NewRecorder(), req)
+	req = httptest.NewRequest(http.MethodGet, "/", strings.NewReader(""))
+	req.Header.Set("If-None-Match", root.ETag())
+	mx.ServeHTTP(httptest.NewRecorder(), req)
+
+	fullFetches, revalidations := observer.GetFetchCounts(gmx.IdentifyByIP(req), time.Now().UTC())
+	assert.Equal(t, 1, fullFetches)
+	assert.Equal(t, 1, revalidations)
+}
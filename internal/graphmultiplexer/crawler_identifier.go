@@ -0,0 +1,56 @@
+package graphmultiplexer
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// CrawlerIdentifier extracts the identity of the crawler that sent a request.
+// An empty identity makes the multiplexer fall back to the remote IP.
+type CrawlerIdentifier func(req *http.Request) string
+
+// IdentifyByIP identifies crawlers by the IP of the remote address.
+func IdentifyByIP(req *http.Request) string {
+	ip, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return ip
+}
+
+// IdentifyByUserAgent identifies crawlers by their User-Agent header.
+func IdentifyByUserAgent(req *http.Request) string {
+	return req.UserAgent()
+}
+
+// IdentifyByForwardedFor identifies crawlers by the client IP, the first
+// address, of the X-Forwarded-For header.
+func IdentifyByForwardedFor(req *http.Request) string {
+	forwardedFor := req.Header.Get("X-Forwarded-For")
+	clientIP, _, _ := strings.Cut(forwardedFor, ",")
+	return strings.TrimSpace(clientIP)
+}
+
+// IdentifyByHeader identifies crawlers by a custom header, e.g. X-Crawler-ID.
+func IdentifyByHeader(name string) CrawlerIdentifier {
+	return func(req *http.Request) string {
+		return req.Header.Get(name)
+	}
+}
+
+// IdentifyByQueryParam identifies crawlers by a token in the query string.
+func IdentifyByQueryParam(name string) CrawlerIdentifier {
+	return func(req *http.Request) string {
+		return req.URL.Query().Get(name)
+	}
+}
+
+func (mux *GraphMux) identifyCrawler(req *http.Request) string {
+	if mux.CrawlerIdentifier != nil {
+		if crawlerID := mux.CrawlerIdentifier(req); crawlerID != "" {
+			return crawlerID
+		}
+	}
+	return IdentifyByIP(req)
+}
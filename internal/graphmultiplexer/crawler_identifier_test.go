@@ -0,0 +1,55 @@
+package graphmultiplexer_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	gmx "github.com/sdqri/sequined/internal/graphmultiplexer"
+	hyr "github.com/sdqri/sequined/internal/hyperrenderer"
+	"github.com/sdqri/sequined/internal/observer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCrawlerIdentifiers(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/?token=crawler-a", strings.NewReader(""))
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set("User-Agent", "test-bot/1.0")
+	req.Header.Set("X-Crawler-ID", "crawler-b")
+	req.Header.Set("X-Forwarded-For", "192.168.1.1, 10.0.0.2")
+
+	testCases := []struct {
+		name       string
+		identifier gmx.CrawlerIdentifier
+		expectedID string
+	}{
+		{name: "ip", identifier: gmx.IdentifyByIP, expectedID: "10.0.0.1"},
+		{name: "user agent", identifier: gmx.IdentifyByUserAgent, expectedID: "test-bot/1.0"},
+		{name: "forwarded for", identifier: gmx.IdentifyByForwardedFor, expectedID: "192.168.1.1"},
+		{name: "header", identifier: gmx.IdentifyByHeader("X-Crawler-ID"), expectedID: "crawler-b"},
+		{name: "query param", identifier: gmx.IdentifyByQueryParam("token"), expectedID: "crawler-a"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expectedID, tc.identifier(req))
+		})
+	}
+}
+
+func TestVisitLoggedByCrawlerID(t *testing.T) {
+	root := hyr.NewWebpage(hyr.WebpageTypeHub)
+	o := observer.New()
+	mx, err := gmx.New(root, gmx.WithObserver(o), gmx.WithCrawlerIdentifier(gmx.IdentifyByHeader("X-Crawler-ID")))
+	assert.NoErrorf(t, err, "Error while creating root")
+
+	for _, crawlerID := range []string{"crawler-a", "crawler-b", ""} {
+		req := httptest.NewRequest(http.MethodGet, "/", strings.NewReader(""))
+		req.Header.Set("X-Crawler-ID", crawlerID)
+		mx.GraphHandlerFunc(httptest.NewRecorder(), req)
+	}
+
+	// the request without header falls back to the remote IP
+	assert.Equal(t, []observer.CrawlerID{"crawler-a", "crawler-b", "192.0.2.1"}, o.GetCrawlerIDs())
+}
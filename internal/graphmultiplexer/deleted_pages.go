@@ -0,0 +1,154 @@
+package graphmultiplexer
+
+import (
+	"html/template"
+	"net/http"
+	"sort"
+	"time"
+
+	hyr "github.com/sdqri/sequined/internal/hyperrenderer"
+)
+
+// DeletedPageResponse is how the paths of deleted pages are answered.
+type DeletedPageResponse string
+
+const (
+	// DeletedPageNotFound answers 404 Not Found, as for paths never served.
+	DeletedPageNotFound DeletedPageResponse = "404"
+	// DeletedPageGone answers 410 Gone.
+	DeletedPageGone DeletedPageResponse = "410"
+	// DeletedPageSoft404 answers 200 OK with a page telling the page was not
+	// found, which crawlers must learn to recognize.
+	DeletedPageSoft404 DeletedPageResponse = "soft-404"
+)
+
+// DefaultDeletedPageRetention is how long the paths of deleted pages are
+// answered as such unless WithDeletedPageRetention is given.
+const DefaultDeletedPageRetention = 30 * 24 * time.Hour
+
+// deletedPage is a page deleted during evolution and the time it was deleted.
+type deletedPage struct {
+	webpage   *hyr.Webpage
+	deletedAt time.Time
+}
+
+// deletedPath is an entry of the queue of deleted paths, in order of deletion.
+// Entries whose path was deleted again since are stale.
+type deletedPath struct {
+	path      string
+	deletedAt time.Time
+}
+
+// WithDeletedPageResponse sets how the paths of pages deleted during evolution
+// are answered, DeletedPageNotFound by default. Requests for them are logged
+// to the observer as visits of deleted nodes.
+func WithDeletedPageResponse(response DeletedPageResponse) GraphMuxOption {
+	return func(mux *GraphMux) {
+		mux.deletedPageResponse = response
+	}
+}
+
+// WithDeletedPageRetention sets how long after their deletion the paths of
+// deleted pages are answered as such, on the clock of the multiplexer. They
+// are then forgotten like paths never served, so that their number stays
+// bounded. Zero keeps them forever.
+func WithDeletedPageRetention(retention time.Duration) GraphMuxOption {
+	return func(mux *GraphMux) {
+		mux.deletedPageRetention = retention
+	}
+}
+
+var soft404Tmpl = template.Must(template.New("soft-404").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="UTF-8">
+  <title>Page not found</title>
+</head>
+<body>
+  <h1>Page not found</h1>
+  <p>Sorry, the page you are looking for does not exist or is no longer available.</p>
+  <a href="{{.}}">Back to the home page</a>
+</body>
+</html>
+`))
+
+// DeletedPage returns the page deleted during evolution that was served on
+// path, unless a page is served there again or the retention of deleted pages
+// elapsed.
+func (mux *GraphMux) DeletedPage(path string) (*hyr.Webpage, bool) {
+	now := mux.Clock.Now()
+
+	mux.routeMu.RLock()
+	defer mux.routeMu.RUnlock()
+
+	deleted, ok := mux.deletedPages[path]
+	if !ok || mux.deletedPageExpired(deleted.deletedAt, now) {
+		return nil, false
+	}
+	return deleted.webpage, true
+}
+
+// deletedPageExpired tells whether the retention of a page deleted at
+// deletedAt elapsed at now.
+func (mux *GraphMux) deletedPageExpired(deletedAt, now time.Time) bool {
+	return mux.deletedPageRetention > 0 && !now.Before(deletedAt.Add(mux.deletedPageRetention))
+}
+
+func (mux *GraphMux) addDeletedPage(webpage *hyr.Webpage, at time.Time) {
+	mux.Root.RLockGraph()
+	path := webpage.GetPath()
+	mux.Root.RUnlockGraph()
+
+	mux.routeMu.Lock()
+	defer mux.routeMu.Unlock()
+	mux.expireDeletedPages(at)
+	mux.deletedPages[path] = deletedPage{webpage: webpage, deletedAt: at}
+	mux.deletedQueue = append(mux.deletedQueue, deletedPath{path: path, deletedAt: at})
+}
+
+// expireDeletedPages forgets the deleted pages whose retention elapsed at now.
+// Pages are deleted in order, expired ones are at the front of the queue. The
+// routes must be write locked.
+func (mux *GraphMux) expireDeletedPages(now time.Time) {
+	if mux.deletedPageRetention <= 0 {
+		return
+	}
+	for len(mux.deletedQueue) > 0 && mux.deletedPageExpired(mux.deletedQueue[0].deletedAt, now) {
+		entry := mux.deletedQueue[0]
+		mux.deletedQueue[0] = deletedPath{}
+		mux.deletedQueue = mux.deletedQueue[1:]
+		if deleted, ok := mux.deletedPages[entry.path]; ok && deleted.deletedAt.Equal(entry.deletedAt) {
+			delete(mux.deletedPages, entry.path)
+		}
+	}
+}
+
+// queueDeletedPages rebuilds the queue of deleted paths from the deleted pages.
+// The routes must be write locked.
+func (mux *GraphMux) queueDeletedPages() {
+	mux.deletedQueue = make([]deletedPath, 0, len(mux.deletedPages))
+	for path, deleted := range mux.deletedPages {
+		mux.deletedQueue = append(mux.deletedQueue, deletedPath{path: path, deletedAt: deleted.deletedAt})
+	}
+	sort.Slice(mux.deletedQueue, func(i, j int) bool {
+		return mux.deletedQueue[i].deletedAt.Before(mux.deletedQueue[j].deletedAt)
+	})
+}
+
+func (mux *GraphMux) serveDeletedPage(w http.ResponseWriter, r *http.Request) {
+	switch mux.deletedPageResponse {
+	case DeletedPageGone:
+		http.Error(w, http.StatusText(http.StatusGone), http.StatusGone)
+	case DeletedPageSoft404:
+		mux.Root.RLockGraph()
+		rootPath := mux.Root.GetPath()
+		mux.Root.RUnlockGraph()
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := soft404Tmpl.Execute(w, rootPath); err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+	default:
+		http.NotFound(w, r)
+	}
+}
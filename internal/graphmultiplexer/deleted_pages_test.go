@@ -0,0 +1,117 @@
+package graphmultiplexer_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	clk "github.com/sdqri/sequined/internal/clock"
+	ggr "github.com/sdqri/sequined/internal/graphgenerator"
+	gmx "github.com/sdqri/sequined/internal/graphmultiplexer"
+	hyr "github.com/sdqri/sequined/internal/hyperrenderer"
+	obs "github.com/sdqri/sequined/internal/observer"
+)
+
+func TestDeletedPageResponses(t *testing.T) {
+	tests := []struct {
+		name           string
+		response       gmx.DeletedPageResponse
+		expectedStatus int
+		expectedBody   string
+	}{
+		{"not found", gmx.DeletedPageNotFound, http.StatusNotFound, "404 page not found"},
+		{"gone", gmx.DeletedPageGone, http.StatusGone, "Gone"},
+		{"soft 404", gmx.DeletedPageSoft404, http.StatusOK, "Page not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := hyr.NewWebpage(hyr.WebpageTypeHub)
+			child := root.AddChild(hyr.WebpageTypeAuthority)
+			o := obs.New()
+			mx, err := gmx.New(root, gmx.WithObserver(o), gmx.WithDeletedPageResponse(tt.response))
+			require.NoError(t, err)
+
+			root.RemoveLink(child)
+			child.Deleted = true
+			updateChan := make(chan ggr.UpdateMessage, 1)
+			updateChan <- ggr.UpdateMessage{Type: ggr.UpdateTypeDelete, Webpage: child}
+			close(updateChan)
+			errChan := make(chan error)
+			close(errChan)
+			mx.SyncGraph(updateChan, errChan)
+
+			r := httptest.NewRecorder()
+			mx.ServeHTTP(r, httptest.NewRequest(http.MethodGet, child.GetPath(), nil))
+			assert.Equal(t, tt.expectedStatus, r.Code)
+			assert.Contains(t, r.Body.String(), tt.expectedBody)
+
+			require.Len(t, o.VisitHistory, 1)
+			assert.Equal(t, obs.NodeID(child.GetID()), o.VisitHistory[0].NodeID)
+			assert.True(t, o.VisitHistory[0].Deleted)
+			staleNodes, _ := o.GetStaleIndex(string(o.VisitHistory[0].CrawlerID), o.Now())
+			assert.Equal(t, 1, staleNodes)
+
+			// paths never served are not visits
+			r = httptest.NewRecorder()
+			mx.ServeHTTP(r, httptest.NewRequest(http.MethodGet, "/never-served", nil))
+			assert.Equal(t, http.StatusNotFound, r.Code)
+			assert.Len(t, o.VisitHistory, 1)
+		})
+	}
+}
+
+func TestDeletedPageRetention(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := clk.NewManual(start)
+
+	root := hyr.NewWebpage(hyr.WebpageTypeHub, hyr.WithSeed(1))
+	first := root.AddChild(hyr.WebpageTypeAuthority)
+	second := root.AddChild(hyr.WebpageTypeAuthority)
+	mx, err := gmx.New(root, gmx.WithClock(clock), gmx.WithDeletedPageResponse(gmx.DeletedPageGone),
+		gmx.WithDeletedPageRetention(time.Hour))
+	require.NoError(t, err)
+
+	deletePage := func(webpage *hyr.Webpage, at time.Time) string {
+		path := webpage.GetPath()
+		root.RemoveLink(webpage)
+		webpage.Deleted = true
+		updateChan := make(chan ggr.UpdateMessage, 1)
+		updateChan <- ggr.UpdateMessage{Type: ggr.UpdateTypeDelete, Webpage: webpage, At: at}
+		close(updateChan)
+		errChan := make(chan error)
+		close(errChan)
+		mx.SyncGraph(updateChan, errChan)
+		return path
+	}
+	status := func(path string) int {
+		r := httptest.NewRecorder()
+		mx.ServeHTTP(r, httptest.NewRequest(http.MethodGet, path, nil))
+		return r.Code
+	}
+
+	firstPath := deletePage(first, start)
+	clock.Advance(30 * time.Minute)
+	secondPath := deletePage(second, clock.Now())
+	assert.Equal(t, http.StatusGone, status(firstPath))
+	assert.Equal(t, http.StatusGone, status(secondPath))
+
+	// paths are forgotten once retained for an hour, snapshots included
+	clock.Advance(30 * time.Minute)
+	assert.Equal(t, http.StatusNotFound, status(firstPath))
+	assert.Equal(t, http.StatusGone, status(secondPath))
+	snapshot, err := mx.Snapshot()
+	require.NoError(t, err)
+	require.Len(t, snapshot.DeletedPaths, 1)
+	assert.Equal(t, secondPath, snapshot.DeletedPaths[0].Path)
+	assert.Equal(t, start.Add(30*time.Minute), snapshot.DeletedPaths[0].DeletedAt)
+
+	clock.Advance(30 * time.Minute)
+	assert.Equal(t, http.StatusNotFound, status(secondPath))
+	_, ok := mx.DeletedPage(secondPath)
+	assert.False(t, ok)
+}
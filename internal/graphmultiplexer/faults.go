@@ -0,0 +1,152 @@
+package graphmultiplexer
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	obs "github.com/sdqri/sequined/internal/observer"
+)
+
+// FaultRule injects a fault into a fraction of the responses.
+type FaultRule struct {
+	Type obs.FaultType
+	// Rate is the probability of the fault per request, 1 faulting every
+	// request in scope, e.g. for an outage.
+	Rate float64
+	// Paths scopes the rule to path prefixes, it applies to all paths if
+	// empty.
+	Paths []string
+	// From and Until scope the rule to a window of the simulation clock, zero
+	// bounds leaving the window open.
+	From, Until time.Time
+	// RetryAfter is sent with 503 responses, zero omits it.
+	RetryAfter time.Duration
+}
+
+type FaultConfig struct {
+	// Rules are tried in order, the first drawn applies.
+	Rules []FaultRule
+}
+
+// WithFaults injects faults into the pages served to test retries and
+// backoff of crawlers. Faults are logged to the observer and faulted requests
+// are not logged as visits.
+func WithFaults(config FaultConfig) GraphMuxOption {
+	return func(mux *GraphMux) {
+		mux.faults = &config
+	}
+}
+
+func (rule *FaultRule) applies(path string, at time.Time) bool {
+	if !rule.From.IsZero() && at.Before(rule.From) {
+		return false
+	}
+	if !rule.Until.IsZero() && !at.Before(rule.Until) {
+		return false
+	}
+	return len(rule.Paths) == 0 || matchesAny(rule.Paths, path)
+}
+
+// drawFault returns the rule of the fault to inject into the response for
+// path, if any.
+func (mux *GraphMux) drawFault(path string) (*FaultRule, bool) {
+	now := mux.Clock.Now()
+	for i := range mux.faults.Rules {
+		rule := &mux.faults.Rules[i]
+		if rule.applies(path, now) && mux.rng.Float64() < rule.Rate {
+			return rule, true
+		}
+	}
+	return nil, false
+}
+
+func (mux *GraphMux) logFault(req *http.Request, faultType obs.FaultType) {
+	if mux.Observer == nil {
+		return
+	}
+	mux.Observer.LogFault(obs.FaultLog{
+		CrawlerID: obs.CrawlerID(mux.identifyCrawler(req)),
+		Type:      faultType,
+		Path:      req.URL.Path,
+		At:        mux.Clock.Now().UTC(),
+	})
+}
+
+var faultStatuses = map[obs.FaultType]int{
+	obs.FaultTypeInternalServerError: http.StatusInternalServerError,
+	obs.FaultTypeBadGateway:          http.StatusBadGateway,
+	obs.FaultTypeServiceUnavailable:  http.StatusServiceUnavailable,
+}
+
+// resetConnection closes the connection of the response, with a TCP reset if
+// possible.
+func resetConnection(w http.ResponseWriter) {
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		panic(http.ErrAbortHandler)
+	}
+	conn, _, err := hijacker.Hijack()
+	if err != nil {
+		panic(http.ErrAbortHandler)
+	}
+	if tcpConn, ok := conn.(*net.TCPConn); ok {
+		tcpConn.SetLinger(0)
+	}
+	conn.Close()
+}
+
+// truncatingWriter announces the full length of the body but writes only half
+// of it, so that the connection is closed short of its Content-Length.
+type truncatingWriter struct {
+	http.ResponseWriter
+	truncated bool
+}
+
+func (writer *truncatingWriter) Write(p []byte) (int, error) {
+	if writer.truncated {
+		return 0, io.ErrShortWrite
+	}
+	writer.truncated = true
+
+	writer.Header().Set("Content-Length", strconv.Itoa(len(p)))
+	n, err := writer.ResponseWriter.Write(p[:len(p)/2])
+	if err != nil {
+		return n, err
+	}
+	return n, io.ErrShortWrite
+}
+
+func FaultMiddleware(mux *GraphMux) Middleware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			rule, ok := mux.drawFault(r.URL.Path)
+			if !ok {
+				next(w, r)
+				return
+			}
+			markFailed(r)
+			mux.logFault(r, rule.Type)
+
+			switch rule.Type {
+			case obs.FaultTypeTimeout:
+				<-r.Context().Done()
+			case obs.FaultTypeReset:
+				resetConnection(w)
+			case obs.FaultTypeTruncate:
+				next(&truncatingWriter{ResponseWriter: w}, r)
+			default:
+				status, ok := faultStatuses[rule.Type]
+				if !ok {
+					status = http.StatusInternalServerError
+				}
+				if status == http.StatusServiceUnavailable && rule.RetryAfter > 0 {
+					w.Header().Set("Retry-After", strconv.Itoa(int(rule.RetryAfter.Round(time.Second).Seconds())))
+				}
+				http.Error(w, http.StatusText(status), status)
+			}
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package graphmultiplexer_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	clk "github.com/sdqri/sequined/internal/clock"
+	gmx "github.com/sdqri/sequined/internal/graphmultiplexer"
+	hyr "github.com/sdqri/sequined/internal/hyperrenderer"
+	obs "github.com/sdqri/sequined/internal/observer"
+)
+
+func TestFaultStatuses(t *testing.T) {
+	root := hyr.NewWebpage(hyr.WebpageTypeHub)
+
+	tests := []struct {
+		name               string
+		rule               gmx.FaultRule
+		expectedStatus     int
+		expectedRetryAfter string
+	}{
+		{"500", gmx.FaultRule{Type: obs.FaultTypeInternalServerError, Rate: 1}, http.StatusInternalServerError, ""},
+		{"502", gmx.FaultRule{Type: obs.FaultTypeBadGateway, Rate: 1}, http.StatusBadGateway, ""},
+		{"503", gmx.FaultRule{Type: obs.FaultTypeServiceUnavailable, Rate: 1, RetryAfter: 2 * time.Minute}, http.StatusServiceUnavailable, "120"},
+		{"no fault", gmx.FaultRule{Type: obs.FaultTypeServiceUnavailable, Rate: 0}, http.StatusOK, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := obs.New()
+			mx, err := gmx.New(root, gmx.WithObserver(o), gmx.WithFaults(gmx.FaultConfig{Rules: []gmx.FaultRule{tt.rule}}))
+			require.NoError(t, err)
+
+			r := httptest.NewRecorder()
+			mx.ServeHTTP(r, httptest.NewRequest(http.MethodGet, root.GetPath(), nil))
+			assert.Equal(t, tt.expectedStatus, r.Code)
+			assert.Equal(t, tt.expectedRetryAfter, r.Header().Get("Retry-After"))
+
+			if tt.expectedStatus == http.StatusOK {
+				assert.Len(t, o.VisitHistory, 1)
+				assert.Empty(t, o.FaultHistory)
+				return
+			}
+			// faulted requests are faults, not visits
+			assert.Empty(t, o.VisitHistory)
+			require.Len(t, o.FaultHistory, 1)
+			assert.Equal(t, tt.rule.Type, o.FaultHistory[0].Type)
+			assert.Equal(t, root.GetPath(), o.FaultHistory[0].Path)
+		})
+	}
+}
+
+func TestFaultScope(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := clk.NewManual(start)
+
+	root := hyr.NewWebpage(hyr.WebpageTypeHub)
+	hub := root.AddChild(hyr.WebpageTypeHub)
+	page := hub.AddChild(hyr.WebpageTypeAuthority)
+
+	// a ten minute outage of the hub subtree
+	outage := gmx.FaultRule{
+		Type:  obs.FaultTypeServiceUnavailable,
+		Rate:  1,
+		Paths: []string{hub.GetPath()},
+		From:  start.Add(time.Hour),
+		Until: start.Add(time.Hour + 10*time.Minute),
+	}
+	mx, err := gmx.New(root, gmx.WithClock(clock), gmx.WithFaults(gmx.FaultConfig{Rules: []gmx.FaultRule{outage}}))
+	require.NoError(t, err)
+
+	status := func(path string) int {
+		r := httptest.NewRecorder()
+		mx.ServeHTTP(r, httptest.NewRequest(http.MethodGet, path, nil))
+		return r.Code
+	}
+
+	assert.Equal(t, http.StatusOK, status(page.GetPath()), "before the outage")
+	clock.Advance(time.Hour)
+	assert.Equal(t, http.StatusServiceUnavailable, status(page.GetPath()), "during the outage")
+	assert.Equal(t, http.StatusServiceUnavailable, status(hub.GetPath()), "during the outage")
+	assert.Equal(t, http.StatusOK, status(root.GetPath()), "out of the outage paths")
+	clock.Advance(10 * time.Minute)
+	assert.Equal(t, http.StatusOK, status(page.GetPath()), "after the outage")
+}
+
+func TestFaultRate(t *testing.T) {
+	root := hyr.NewWebpage(hyr.WebpageTypeHub)
+	rule := gmx.FaultRule{Type: obs.FaultTypeInternalServerError, Rate: 0.3}
+	mx, err := gmx.New(root, gmx.WithSeed(1), gmx.WithFaults(gmx.FaultConfig{Rules: []gmx.FaultRule{rule}}))
+	require.NoError(t, err)
+
+	faults := 0
+	for i := 0; i < 1000; i++ {
+		r := httptest.NewRecorder()
+		mx.ServeHTTP(r, httptest.NewRequest(http.MethodGet, root.GetPath(), nil))
+		if r.Code == http.StatusInternalServerError {
+			faults++
+		}
+	}
+	assert.InDelta(t, 300, faults, 50)
+}
+
+func TestConnectionFaults(t *testing.T) {
+	root := hyr.NewWebpage(hyr.WebpageTypeHub)
+
+	tests := []struct {
+		name      string
+		faultType obs.FaultType
+	}{
+		{"timeout", obs.FaultTypeTimeout},
+		{"reset", obs.FaultTypeReset},
+		{"truncate", obs.FaultTypeTruncate},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := obs.New()
+			rule := gmx.FaultRule{Type: tt.faultType, Rate: 1}
+			mx, err := gmx.New(root, gmx.WithObserver(o), gmx.WithFaults(gmx.FaultConfig{Rules: []gmx.FaultRule{rule}}))
+			require.NoError(t, err)
+
+			server := httptest.NewServer(mx)
+			client := http.Client{Timeout: 100 * time.Millisecond}
+			resp, err := client.Get(server.URL + root.GetPath())
+			if err == nil {
+				_, err = io.ReadAll(resp.Body)
+				resp.Body.Close()
+			}
+			assert.Error(t, err)
+			// waits for the handler to return
+			server.Close()
+
+			assert.Empty(t, o.VisitHistory)
+			require.Len(t, o.FaultHistory, 1)
+			assert.Equal(t, tt.faultType, o.FaultHistory[0].Type)
+		})
+	}
+}
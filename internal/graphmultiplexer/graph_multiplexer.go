@@ -1,23 +1,81 @@
 package graphmultiplexer
 
 import (
+	"bufio"
+	"bytes"
+	"context"
 	"fmt"
+	"math/rand"
 	"net"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 
+	clk "github.com/sdqri/sequined/internal/clock"
 	dsh "github.com/sdqri/sequined/internal/dashboard"
 	ggr "github.com/sdqri/sequined/internal/graphgenerator"
 	hyr "github.com/sdqri/sequined/internal/hyperrenderer"
+	"github.com/sdqri/sequined/internal/lockedrand"
 	obs "github.com/sdqri/sequined/internal/observer"
 )
 
 type Middleware func(http.HandlerFunc) http.HandlerFunc
 
 type GraphMux struct {
-	Root     *hyr.Webpage
+	Root *hyr.Webpage
+	// RouteMap is replaced as a whole on graph updates, use Route to read it
+	// concurrently.
 	RouteMap map[string]hyr.HyperRenderer
+	// variants are the URL variants of pages keyed by path, routes take
+	// precedence over them.
+	variants map[string]variantRoute
+	// routes index the pages and their URL variants by normalized path, see
+	// URLNormalization.
+	routes        map[string]route
+	normalization urlNormalization
+	// redirects are left behind by moved pages, keyed by former path. Routes
+	// take precedence over them.
+	redirects map[string]ggr.Redirect
+	// deletedPages are the pages deleted during evolution keyed by path, and
+	// deletedQueue their paths in order of deletion to expire them.
+	deletedPages map[string]deletedPage
+	deletedQueue []deletedPath
+	// traps are the spider traps linked from the graph, resolved after routes,
+	// redirects and deleted pages.
+	traps    []*hyr.Trap
+	routeMu  sync.RWMutex
 	Observer *obs.Observer
+	// CrawlerIdentifier keys visits in the observer, crawlers are identified
+	// by IP when it is nil.
+	CrawlerIdentifier CrawlerIdentifier
+	// Clock stamps logged events, it should be the clock of the generator
+	// evolving the graph.
+	Clock clk.Clock
+
+	sitemap *sitemap
+	robots  *robots
+	latency *LatencyConfig
+	faults  *FaultConfig
+
+	rateLimiter *rateLimiter
+
+	deletedPageResponse  DeletedPageResponse
+	deletedPageRetention time.Duration
+
+	// snapshot is the snapshot the graph was built from, see WithSnapshot.
+	snapshot *hyr.Snapshot
+
+	// rng draws injected delays and faults, it is safe for concurrent use.
+	rng *rand.Rand
+
+	validatorMu    sync.Mutex
+	validatorCache map[string]validator
+
+	// redirectHits holds the crawlers redirected to a node, keyed by
+	// redirectHitKey, until they visit it.
+	redirectHitMu sync.Mutex
+	redirectHits  map[string]struct{}
 
 	*http.ServeMux
 	middlewareChain  []Middleware
@@ -27,11 +85,25 @@ type GraphMux struct {
 type GraphMuxOption func(*GraphMux)
 
 func New(root *hyr.Webpage, opts ...GraphMuxOption) (*GraphMux, error) {
+	root.RLockGraph()
 	routeMap := hyr.CreatePathMap(root)
+	variants := collectVariants(root)
+	traps := collectTraps(root)
+	root.RUnlockGraph()
 
 	mux := GraphMux{
-		Root:     root,
-		RouteMap: routeMap,
+		Root:         root,
+		RouteMap:     routeMap,
+		variants:     variants,
+		traps:        traps,
+		redirects:    make(map[string]ggr.Redirect),
+		deletedPages: make(map[string]deletedPage),
+		redirectHits: make(map[string]struct{}),
+		Clock:        clk.Real(),
+		rng:          lockedrand.New(time.Now().UnixNano()),
+
+		deletedPageResponse:  DeletedPageNotFound,
+		deletedPageRetention: DefaultDeletedPageRetention,
 
 		ServeMux:        http.NewServeMux(),
 		middlewareChain: make([]Middleware, 0),
@@ -40,20 +112,53 @@ func New(root *hyr.Webpage, opts ...GraphMuxOption) (*GraphMux, error) {
 	for _, opt := range opts {
 		opt(&mux)
 	}
+	if mux.rateLimiter != nil {
+		if err := mux.rateLimiter.config.validate(); err != nil {
+			return nil, err
+		}
+	}
+	root.RLockGraph()
+	mux.routes = mux.normalization.buildRoutes(routeMap, variants)
+	root.RUnlockGraph()
+
+	if mux.snapshot != nil {
+		if err := mux.restoreSnapshot(); err != nil {
+			return nil, err
+		}
+	}
+
+	if mux.latency != nil {
+		mux.middlewareChain = append(mux.middlewareChain, LatencyMiddleware(&mux))
+	}
+	if mux.faults != nil {
+		mux.middlewareChain = append(mux.middlewareChain, FaultMiddleware(&mux))
+	}
+	if mux.rateLimiter != nil {
+		mux.middlewareChain = append(mux.middlewareChain, RateLimitMiddleware(&mux))
+	}
+
+	if mux.robots != nil {
+		mux.syncRobots()
+		if mux.Observer != nil {
+			mux.middlewareChain = append(mux.middlewareChain, RobotsMiddleware(&mux))
+		}
+	}
 
 	if mux.Observer != nil {
 		mux.middlewareChain = append(mux.middlewareChain, VisitLoggerMiddleware(&mux))
 
 		var err error
+		mux.Root.RLockGraph()
 		hyr.Traverse(mux.Root, func(node hyr.HyperRenderer) bool {
 			currentPage, ok := node.(*hyr.Webpage)
 			if !ok {
 				err = ggr.ErrUnexpectedNodeType
 				return true
 			}
-			mux.logNodeCreation(currentPage)
+			mux.logNodeCreation(currentPage, mux.Clock.Now())
 			return false
 		})
+		mux.Root.RUnlockGraph()
 		if err != nil {
 			return nil, err
 		}
@@ -68,6 +173,13 @@ func New(root *hyr.Webpage, opts ...GraphMuxOption) (*GraphMux, error) {
 	}
 
 	mux.Handle("/", mux.GraphHandlerFunc)
+	if mux.sitemap != nil {
+		mux.HandleFunc("/sitemap.xml", mux.HandleSitemap)
+		mux.HandleFunc("/sitemaps/{name}", mux.HandleSitemapPart)
+	}
+	if mux.robots != nil {
+		mux.HandleFunc("/robots.txt", mux.HandleRobots)
+	}
 
 	return &mux, nil
 }
@@ -78,6 +190,26 @@ func WithObserver(observer *obs.Observer) GraphMuxOption {
 	}
 }
 
+func WithCrawlerIdentifier(identifier CrawlerIdentifier) GraphMuxOption {
+	return func(mux *GraphMux) {
+		mux.CrawlerIdentifier = identifier
+	}
+}
+
+func WithClock(clock clk.Clock) GraphMuxOption {
+	return func(mux *GraphMux) {
+		mux.Clock = clock
+	}
+}
+
+// WithSeed makes the behavior the multiplexer draws at random, e.g. injected
+// latencies, reproducible.
+func WithSeed(seed int64) GraphMuxOption {
+	return func(mux *GraphMux) {
+		mux.rng = lockedrand.New(seed)
+	}
+}
+
 func WithMiddleware(mw Middleware) GraphMuxOption {
 	return func(mux *GraphMux) {
 		mux.middlewareChain = append(mux.middlewareChain, mw)
@@ -88,84 +220,333 @@ func (mux *GraphMux) Use(mw Middleware) {
 	mux.GraphHandlerFunc = mw(mux.GraphHandlerFunc)
 }
 
+// Route returns the page served on path.
+func (mux *GraphMux) Route(path string) (hyr.HyperRenderer, bool) {
+	mux.routeMu.RLock()
+	defer mux.routeMu.RUnlock()
+
+	page, ok := mux.RouteMap[path]
+	return page, ok
+}
+
+// syncRouteMap rebuilds the route map, the URL variants and the traps from the graph and swaps
+// them in.
+func (mux *GraphMux) syncRouteMap() {
+	mux.Root.RLockGraph()
+	routeMap := hyr.CreatePathMap(mux.Root)
+	variants := collectVariants(mux.Root)
+	routes := mux.normalization.buildRoutes(routeMap, variants)
+	traps := collectTraps(mux.Root)
+	mux.Root.RUnlockGraph()
+
+	mux.routeMu.Lock()
+	mux.RouteMap = routeMap
+	mux.variants = variants
+	mux.routes = routes
+	mux.traps = traps
+	mux.routeMu.Unlock()
+
+	if mux.robots != nil {
+		mux.syncRobots()
+	}
+}
+
+// Redirect returns the redirect left behind on path by a moved page.
+func (mux *GraphMux) Redirect(path string) (ggr.Redirect, bool) {
+	mux.routeMu.RLock()
+	defer mux.routeMu.RUnlock()
+
+	redirect, ok := mux.redirects[path]
+	return redirect, ok
+}
+
+func (mux *GraphMux) addRedirects(redirects []ggr.Redirect) {
+	mux.routeMu.Lock()
+	defer mux.routeMu.Unlock()
+
+	for _, redirect := range redirects {
+		mux.redirects[redirect.From] = redirect
+	}
+}
+
+func redirectHitKey(crawlerID, nodeID string) string {
+	return crawlerID + "\x00" + nodeID
+}
+
+// recordRedirectHit remembers that the crawler was redirected to the page, for
+// its next visit of the page to be logged as redirected.
+func (mux *GraphMux) recordRedirectHit(r *http.Request, webpage *hyr.Webpage) {
+	if mux.Observer == nil {
+		return
+	}
+	key := redirectHitKey(mux.identifyCrawler(r), webpage.GetID())
+
+	mux.redirectHitMu.Lock()
+	mux.redirectHits[key] = struct{}{}
+	mux.redirectHitMu.Unlock()
+}
+
+// takeRedirectHit reports and forgets whether the crawler was redirected to
+// the node since its last visit.
+func (mux *GraphMux) takeRedirectHit(crawlerID, nodeID string) bool {
+	key := redirectHitKey(crawlerID, nodeID)
+
+	mux.redirectHitMu.Lock()
+	defer mux.redirectHitMu.Unlock()
+
+	_, ok := mux.redirectHits[key]
+	delete(mux.redirectHits, key)
+	return ok
+}
+
 func (mux *GraphMux) HandleGraphHttpRequest(w http.ResponseWriter, r *http.Request) {
-	page, ok := mux.RouteMap[r.URL.Path]
-	if ok {
-		err := page.Render(w)
-		if err != nil {
-			fmt.Println(err.Error())
+	page, _, pageNumber, ok := mux.resolvePage(r.URL)
+	if !ok {
+		if redirect, ok := mux.Redirect(r.URL.Path); ok {
+			mux.recordRedirectHit(r, redirect.Webpage)
+			http.Redirect(w, r, redirect.To, redirect.Status)
+			return
+		}
+		if _, ok := mux.DeletedPage(r.URL.Path); ok {
+			mux.serveDeletedPage(w, r)
+			return
+		}
+		if trapPage, ok := mux.TrapPage(r.URL); ok {
+			mux.serveTrapPage(w, r, trapPage)
+			return
 		}
-	} else {
 		http.NotFound(w, r)
+		return
+	}
+
+	// render under the graph lock into a buffer, so that slow clients
+	// do not hold back graph updates
+	var buf bytes.Buffer
+	var err error
+	mux.Root.RLockGraph()
+	etag, lastModified, hasValidators := mux.validators(page)
+	if pageNumber > 1 {
+		// pages of a hub are distinct representations
+		etag = strings.TrimSuffix(etag, `"`) + fmt.Sprintf(`-p%d"`, pageNumber)
+	}
+	isNotModified := hasValidators && notModified(r, etag, lastModified)
+	if !isNotModified {
+		if webpage, ok := page.(*hyr.Webpage); ok {
+			err = webpage.RenderPage(&buf, pageNumber)
+		} else {
+			err = page.Render(&buf)
+		}
+	}
+	mux.Root.RUnlockGraph()
+	if err != nil {
+		fmt.Println(err.Error())
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	if hasValidators {
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+	if isNotModified {
+		w.WriteHeader(http.StatusNotModified)
+		return
 	}
+	w.Write(buf.Bytes())
 }
 
+// SyncGraph applies update messages of a running graph evolution to the route
+// map and the observer. It returns once both channels are closed.
 func (mux *GraphMux) SyncGraph(updateChan chan ggr.UpdateMessage, errChan chan error) {
-	for {
+	for updateChan != nil || errChan != nil {
 		select {
 		case updateMsg, ok := <-updateChan:
 			if !ok {
-				//TODO: not do
+				updateChan = nil
+				continue
+			}
+			at := updateMsg.At
+			if at.IsZero() {
+				at = mux.Clock.Now()
 			}
-			mux.RouteMap = hyr.CreatePathMap(mux.Root)
 			switch updateMsg.Type {
 			case ggr.UpdateTypeCreate:
-				mux.logNodeCreation(updateMsg.Webpage)
+				mux.syncRouteMap()
+				mux.logNodeCreation(updateMsg.Webpage, at)
 			case ggr.UpdateTypeDelete:
-				mux.logNodeDeletion(updateMsg.Webpage)
+				mux.syncRouteMap()
+				mux.addDeletedPage(updateMsg.Webpage, at)
+				mux.logNodeDeletion(updateMsg.Webpage, at)
+			case ggr.UpdateTypeModify:
+				mux.logNodeModification(updateMsg.Webpage, at)
+			case ggr.UpdateTypeMove:
+				mux.syncRouteMap()
+				mux.addRedirects(updateMsg.Redirects)
+				mux.logNodeMoves(updateMsg.Redirects, at)
 			}
-			// case err, ok <- errChan:
-
+		case err, ok := <-errChan:
+			if !ok {
+				errChan = nil
+				continue
+			}
+			fmt.Println(err.Error())
 		}
 	}
 }
 
-func (mux *GraphMux) logNodeCreation(webpage hyr.HyperRenderer) {
+func (mux *GraphMux) logNodeCreation(webpage hyr.HyperRenderer, at time.Time) {
+	if mux.Observer == nil {
+		return
+	}
+	nodeLog := obs.NodeLog{
+		ID:        obs.NodeID(webpage.GetID()),
+		CreatedAt: at.UTC(),
+		DeletedAt: nil,
+	}
+	if currentPage, ok := webpage.(*hyr.Webpage); ok && currentPage.DuplicateOf != nil {
+		nodeLog.DuplicateOf = obs.NodeID(currentPage.DuplicateOf.GetID())
+		nodeLog.Similarity = currentPage.Similarity
+	}
+	mux.Observer.LogNode(nodeLog)
+}
+
+func (mux *GraphMux) logNodeDeletion(webpage hyr.HyperRenderer, at time.Time) {
 	if mux.Observer != nil {
-		mux.Observer.LogNode(obs.NodeLog{
-			ID:        obs.NodeID(webpage.GetID()),
-			CreatedAt: time.Now().UTC(),
-			DeletedAt: nil,
-		})
+		mux.Observer.LogNodeDeletion(obs.NodeID(webpage.GetID()), at.UTC())
 	}
 }
 
-func (mux *GraphMux) logNodeDeletion(webpage hyr.HyperRenderer) {
+func (mux *GraphMux) logNodeModification(webpage hyr.HyperRenderer, at time.Time) {
 	if mux.Observer != nil {
-		now := time.Now().UTC()
-		if logNode, ok := mux.Observer.NodeLogMap[obs.NodeID(webpage.GetID())]; ok {
-			logNode.DeletedAt = &now
-		}
+		mux.Observer.LogNodeModification(obs.NodeID(webpage.GetID()), at.UTC())
 	}
 }
 
-func (mux *GraphMux) logVisit(req *http.Request) {
+// logNodeMoves logs a move for every page the redirects of a move lead to.
+func (mux *GraphMux) logNodeMoves(redirects []ggr.Redirect, at time.Time) {
 	if mux.Observer == nil {
 		return
 	}
+	moved := make(map[*hyr.Webpage]struct{}, len(redirects))
+	for _, redirect := range redirects {
+		if _, ok := moved[redirect.Webpage]; ok {
+			continue
+		}
+		moved[redirect.Webpage] = struct{}{}
+		mux.Observer.LogNodeMove(obs.NodeID(redirect.Webpage.GetID()), at.UTC())
+	}
+}
+
+func (mux *GraphMux) logVisit(req *http.Request, status int, visit *visitRecord) {
+	if mux.Observer == nil || visit.failed {
+		return
+	}
 
-	ip, _, _ := net.SplitHostPort(req.RemoteAddr)
-	if node, ok := mux.RouteMap[req.URL.Path]; ok {
+	crawlerID := mux.identifyCrawler(req)
+	if node, variant, pageNumber, ok := mux.resolvePage(req.URL); ok {
 		if currentPage, ok := node.(*hyr.Webpage); ok {
+			if pageNumber == 1 {
+				pageNumber = 0
+			}
 			mux.Observer.LogVisit(obs.VisitLog{
-				RemoteAddr: obs.IPAddr(ip),
-				NodeID:     obs.NodeID(currentPage.GetID()),
-				VisitedAt:  time.Now().UTC(),
+				CrawlerID:   obs.CrawlerID(crawlerID),
+				NodeID:      obs.NodeID(currentPage.GetID()),
+				VisitedAt:   mux.Clock.Now().UTC(),
+				Revalidated: status == http.StatusNotModified,
+				Delay:       visit.delay,
+				Redirected:  mux.takeRedirectHit(crawlerID, currentPage.GetID()),
+				Variant:     string(variant),
+				Page:        pageNumber,
 			})
 		}
+		return
+	}
+	if visit.trapPage != nil {
+		mux.Observer.LogVisit(obs.VisitLog{
+			CrawlerID: obs.CrawlerID(crawlerID),
+			NodeID:    obs.NodeID(visit.trapPage.GetID()),
+			VisitedAt: mux.Clock.Now().UTC(),
+			Delay:     visit.delay,
+			Trap:      string(visit.trapPage.Trap.Type),
+		})
+		return
+	}
+	if deletedPage, ok := mux.DeletedPage(req.URL.Path); ok {
+		mux.Observer.LogVisit(obs.VisitLog{
+			CrawlerID:  obs.CrawlerID(crawlerID),
+			NodeID:     obs.NodeID(deletedPage.GetID()),
+			VisitedAt:  mux.Clock.Now().UTC(),
+			Delay:      visit.delay,
+			Redirected: mux.takeRedirectHit(crawlerID, deletedPage.GetID()),
+			Deleted:    true,
+		})
 	}
 }
 
 func VisitLoggerMiddleware(mux *GraphMux) Middleware {
 	return func(next http.HandlerFunc) http.HandlerFunc {
 		return func(w http.ResponseWriter, r *http.Request) {
-			next(w, r)
+			visit := &visitRecord{}
+			r = r.WithContext(context.WithValue(r.Context(), visitContextKey{}, visit))
+			recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next(recorder, r)
 
-			mux.logVisit(r)
+			mux.logVisit(r, recorder.status, visit)
 		}
 	}
 }
 
+type visitContextKey struct{}
+
+// visitRecord collects what the middlewares the visit logger wraps inject into
+// a visit.
+type visitRecord struct {
+	delay time.Duration
+	// failed visits did not fetch the page.
+	failed bool
+	// trapPage is the page served if it belongs to a trap.
+	trapPage *hyr.TrapPage
+}
+
+// recordDelay adds an injected delay to the visit of the request, if logged.
+func recordDelay(r *http.Request, delay time.Duration) {
+	if visit, ok := r.Context().Value(visitContextKey{}).(*visitRecord); ok {
+		visit.delay += delay
+	}
+}
+
+// markFailed keeps the visit of the request from being logged.
+func markFailed(r *http.Request) {
+	if visit, ok := r.Context().Value(visitContextKey{}).(*visitRecord); ok {
+		visit.failed = true
+	}
+}
+
+// statusRecorder remembers the status code written through it.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (recorder *statusRecorder) WriteHeader(status int) {
+	recorder.status = status
+	recorder.ResponseWriter.WriteHeader(status)
+}
+
+func (recorder *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := recorder.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	return hijacker.Hijack()
+}
+
+func (recorder *statusRecorder) Flush() {
+	if flusher, ok := recorder.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
 func (mux *GraphMux) ActivateDashboard(dashboard *dsh.Dashboard) {
 	dashboard.HandleBy(mux.ServeMux)
 }
@@ -1,16 +1,24 @@
 package graphmultiplexer_test
 
 import (
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	clk "github.com/sdqri/sequined/internal/clock"
+	dsh "github.com/sdqri/sequined/internal/dashboard"
+	ggr "github.com/sdqri/sequined/internal/graphgenerator"
 	gmx "github.com/sdqri/sequined/internal/graphmultiplexer"
 	hyr "github.com/sdqri/sequined/internal/hyperrenderer"
 	"github.com/sdqri/sequined/internal/observer"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func init() {
@@ -114,3 +122,271 @@ func TestObserverOnMux(t *testing.T) {
 	}
 
 }
+
+func TestSyncGraphDeletion(t *testing.T) {
+	root := hyr.NewWebpage(hyr.WebpageTypeHub)
+	child := root.AddChild(hyr.WebpageTypeAuthority)
+	o := observer.New()
+	mx, err := gmx.New(root, gmx.WithObserver(o))
+	assert.NoErrorf(t, err, "Error while creating root")
+
+	root.RemoveLink(child)
+	child.Deleted = true
+
+	updateChan := make(chan ggr.UpdateMessage, 1)
+	errChan := make(chan error)
+	updateChan <- ggr.UpdateMessage{Type: ggr.UpdateTypeDelete, Webpage: child}
+	close(updateChan)
+	close(errChan)
+	mx.SyncGraph(updateChan, errChan)
+
+	r := httptest.NewRecorder()
+	mx.GraphHandlerFunc(r, httptest.NewRequest(http.MethodGet, child.GetPath(), strings.NewReader("")))
+	assert.Equal(t, http.StatusNotFound, r.Result().StatusCode)
+	assert.NotNil(t, o.NodeLogMap[observer.NodeID(child.GetID())].DeletedAt, "DeletedAt should be logged")
+}
+
+func TestSyncGraphMoves(t *testing.T) {
+	root := hyr.NewWebpage(hyr.WebpageTypeHub)
+	root.AddChild(hyr.WebpageTypeHub)
+	hubB := root.AddChild(hyr.WebpageTypeHub)
+	hubC := root.AddChild(hyr.WebpageTypeHub)
+	auth := root.Links[0].AddChild(hyr.WebpageTypeAuthority)
+	firstPath := auth.GetPath()
+
+	gg := ggr.New(root, 0.5, ggr.WithMoves(1, http.StatusPermanentRedirect))
+	// auth is moved under hubB, then under hubC
+	choices := []int{1, 1, 2, 2}
+	gg.SelectorFunc = func(probabilities []float64) (int, error) {
+		choice := choices[0]
+		choices = choices[1:]
+		return choice, nil
+	}
+
+	o := observer.New()
+	mx, err := gmx.New(root, gmx.WithObserver(o))
+	require.NoError(t, err)
+
+	updateChan := make(chan ggr.UpdateMessage, 2)
+	for i := 0; i < 2; i++ {
+		webpage, redirects, err := gg.MovePage()
+		require.NoError(t, err)
+		updateChan <- ggr.UpdateMessage{Type: ggr.UpdateTypeMove, Webpage: webpage, Redirects: redirects}
+	}
+	close(updateChan)
+	errChan := make(chan error)
+	close(errChan)
+	mx.SyncGraph(updateChan, errChan)
+	require.True(t, hubC.IsAncestorOf(auth))
+	assert.False(t, hubB.IsAncestorOf(auth))
+	assert.Len(t, o.NodeLogMap[observer.NodeID(auth.GetID())].MovedAt, 2)
+
+	// the first path redirects to the second, which redirects to the current
+	r := httptest.NewRecorder()
+	mx.ServeHTTP(r, httptest.NewRequest(http.MethodGet, firstPath, nil))
+	assert.Equal(t, http.StatusPermanentRedirect, r.Code)
+	secondPath := r.Header().Get("Location")
+	r = httptest.NewRecorder()
+	mx.ServeHTTP(r, httptest.NewRequest(http.MethodGet, secondPath, nil))
+	assert.Equal(t, http.StatusPermanentRedirect, r.Code)
+	assert.Equal(t, auth.GetPath(), r.Header().Get("Location"))
+
+	server := httptest.NewServer(mx)
+	defer server.Close()
+	get := func(path string) {
+		resp, err := http.Get(server.URL + path)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+
+	get(firstPath)
+	updated, outdated := o.GetURLUpdates("127.0.0.1", o.Now())
+	assert.Equal(t, 0, updated)
+	assert.Equal(t, 1, outdated, "Visit through redirects should be outdated")
+
+	get(auth.GetPath())
+	updated, outdated = o.GetURLUpdates("127.0.0.1", o.Now())
+	assert.Equal(t, 1, updated, "Visit at the current path should be updated")
+	assert.Equal(t, 0, outdated)
+}
+
+func TestSyncGraphReparenting(t *testing.T) {
+	clock := clk.NewManual(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	root := hyr.NewWebpage(hyr.WebpageTypeHub)
+	hub := root.AddChild(hyr.WebpageTypeHub)
+	auth := hub.AddChild(hyr.WebpageTypeAuthority)
+	formerPath := auth.GetPath()
+
+	o := observer.New()
+	mx, err := gmx.New(root, gmx.WithObserver(o), gmx.WithClock(clock))
+	require.NoError(t, err)
+
+	// the hub is deleted after a minute, auth being adopted by root
+	gg := ggr.New(root, 0.5, ggr.WithClock(clock), ggr.WithDeletionRates(60, 0))
+	updateChan, errChan, err := gg.StartGraphEvolution(2, 1, 1, 1)
+	require.NoError(t, err)
+	synced := make(chan struct{})
+	go func() {
+		defer close(synced)
+		mx.SyncGraph(updateChan, errChan)
+	}()
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	clock.BlockUntil(1)
+	gg.StopGraphEvolution()
+	<-synced
+
+	require.True(t, hub.Deleted)
+	require.Equal(t, root, auth.Parent)
+	assert.Len(t, o.NodeLogMap[observer.NodeID(auth.GetID())].MovedAt, 1)
+
+	r := httptest.NewRecorder()
+	mx.ServeHTTP(r, httptest.NewRequest(http.MethodGet, formerPath, nil))
+	assert.Equal(t, http.StatusMovedPermanently, r.Code)
+	assert.Equal(t, auth.GetPath(), r.Header().Get("Location"))
+}
+
+func TestRedirectLoop(t *testing.T) {
+	root := hyr.NewWebpage(hyr.WebpageTypeHub)
+	root.AddChild(hyr.WebpageTypeHub)
+	auth := root.AddChild(hyr.WebpageTypeAuthority)
+	formerPath := auth.GetPath()
+
+	gg := ggr.New(root, 0.5, ggr.WithRedirectLoopProbability(1))
+	// auth is moved under the hub
+	gg.SelectorFunc = func(probabilities []float64) (int, error) {
+		return len(probabilities) - 1, nil
+	}
+	mx, err := gmx.New(root)
+	require.NoError(t, err)
+
+	webpage, redirects, err := gg.MovePage()
+	require.NoError(t, err)
+	updateChan := make(chan ggr.UpdateMessage, 1)
+	updateChan <- ggr.UpdateMessage{Type: ggr.UpdateTypeMove, Webpage: webpage, Redirects: redirects}
+	close(updateChan)
+	errChan := make(chan error)
+	close(errChan)
+	mx.SyncGraph(updateChan, errChan)
+
+	server := httptest.NewServer(mx)
+	defer server.Close()
+	_, err = http.Get(server.URL + formerPath)
+	assert.ErrorContains(t, err, "stopped after 10 redirects")
+
+	// the page itself is still served at its new path
+	resp, err := http.Get(server.URL + auth.GetPath())
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+// TestConcurrentServingDuringEvolution is meant to be run with -race: it serves
+// the graph and the dashboard to concurrent clients while the graph evolves.
+func TestConcurrentServingDuringEvolution(t *testing.T) {
+	root := hyr.NewWebpage(hyr.WebpageTypeHub)
+	gg := ggr.New(root, 0.5,
+		ggr.WithDeletionRates(20_000, 50_000),
+		ggr.WithOrphanPolicy(ggr.OrphanPolicyDangle),
+		ggr.WithChangeRateDistribution(ggr.ConstantRate(50_000)),
+		ggr.WithMoves(20_000, http.StatusMovedPermanently, http.StatusFound),
+	)
+	assert.NoError(t, gg.Generate(5, 20))
+
+	o := observer.New()
+	mx, err := gmx.New(root, gmx.WithObserver(o))
+	assert.NoErrorf(t, err, "Error while creating root")
+	mx.ActivateDashboard(dsh.NewDashboard(root, o))
+
+	updateChan, errChan, err := gg.StartGraphEvolution(20, 100, 100_000, 100_000)
+	assert.NoError(t, err)
+	syncDone := make(chan struct{})
+	go func() {
+		mx.SyncGraph(updateChan, errChan)
+		close(syncDone)
+	}()
+
+	server := httptest.NewServer(mx)
+	defer server.Close()
+
+	paths := []string{"/", "/charts/tree", "/charts/freshness?bucket-duration=1m&duration=5m&crawler=127.0.0.1"}
+	deadline := time.Now().Add(1 * time.Second)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := i; time.Now().Before(deadline); j++ {
+				path := paths[j%len(paths)]
+				if j%2 == 0 {
+					// follow a link of the root page
+					root.RLockGraph()
+					links := root.GetLinks()
+					if len(links) > 0 {
+						path = links[j%len(links)].GetPath()
+					}
+					root.RUnlockGraph()
+				}
+				resp, err := http.Get(server.URL + path)
+				if !assert.NoError(t, err) {
+					return
+				}
+				io.Copy(io.Discard, resp.Body)
+				resp.Body.Close()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	gg.StopGraphEvolution()
+	<-syncDone
+	assert.NotEmpty(t, o.GetCrawlerIDs())
+}
+
+func TestMuxClock(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := clk.NewManual(start)
+	root := hyr.NewWebpage(hyr.WebpageTypeHub)
+	o := observer.New(observer.WithClock(clock))
+	mx, err := gmx.New(root, gmx.WithObserver(o), gmx.WithClock(clock))
+	assert.NoError(t, err)
+
+	clock.Advance(time.Hour)
+	mx.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", strings.NewReader("")))
+
+	assert.Equal(t, start, o.NodeLogMap[observer.NodeID(root.GetID())].CreatedAt)
+	assert.Len(t, o.VisitHistory, 1)
+	assert.Equal(t, start.Add(time.Hour), o.VisitHistory[0].VisitedAt)
+	assert.Equal(t, start.Add(time.Hour), o.Now())
+}
+
+func TestEvolveImportedGraphWithEscapedPaths(t *testing.T) {
+	root, err := hyr.ReadEdgeList(strings.NewReader("/café /café/menu\n/100%25-cotton /100%25-cotton/shirt\n"), hyr.WithSeed(1))
+	require.NoError(t, err)
+
+	gg := ggr.New(root, 0.5, ggr.WithSeed(1))
+	parents := make(map[string]int)
+	for i := 0; i < 30; i++ {
+		webpage, err := gg.CreateAuthorityPage()
+		require.NoError(t, err)
+		parents[webpage.Parent.GetPath()]++
+	}
+	require.Positive(t, parents["/café"])
+	require.Positive(t, parents["/100%-cotton"])
+
+	mx, err := gmx.New(root)
+	require.NoError(t, err)
+	hyr.Traverse(root, func(currentRenderer hyr.HyperRenderer) bool {
+		webpage := currentRenderer.(*hyr.Webpage)
+		if webpage.Parent != nil {
+			assert.True(t, strings.HasPrefix(webpage.GetPath(), webpage.Parent.GetPath()))
+		}
+
+		escapedPath := (&url.URL{Path: webpage.GetPath()}).EscapedPath()
+		r := httptest.NewRecorder()
+		mx.GraphHandlerFunc(r, httptest.NewRequest(http.MethodGet, escapedPath, nil))
+		assert.Equal(t, http.StatusOK, r.Result().StatusCode, webpage.GetPath())
+		return false
+	})
+}
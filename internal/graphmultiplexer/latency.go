@@ -0,0 +1,194 @@
+package graphmultiplexer
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// LatencyDistribution draws the latency of a response from rng.
+type LatencyDistribution func(rng *rand.Rand) time.Duration
+
+// FixedLatency delays every response by latency.
+func FixedLatency(latency time.Duration) LatencyDistribution {
+	return func(*rand.Rand) time.Duration {
+		return latency
+	}
+}
+
+// NormalLatency draws latencies from a normal distribution, negative draws
+// adding no latency.
+func NormalLatency(mean, stddev time.Duration) LatencyDistribution {
+	return func(rng *rand.Rand) time.Duration {
+		return max(0, time.Duration(rng.NormFloat64()*float64(stddev))+mean)
+	}
+}
+
+// LongTailLatency draws latencies from a log-normal distribution with the
+// given median, the larger sigma the longer its tail, e.g. sigma 1 makes one
+// response in twenty over five times the median.
+func LongTailLatency(median time.Duration, sigma float64) LatencyDistribution {
+	return func(rng *rand.Rand) time.Duration {
+		return time.Duration(float64(median) * math.Exp(rng.NormFloat64()*sigma))
+	}
+}
+
+// LoadPattern returns the load factor of the server at a time of the
+// simulation, latencies are multiplied and bandwidth divided by it.
+type LoadPattern func(t time.Time) float64
+
+// HourlyLoad takes the load factor of every hour of the day, in UTC.
+func HourlyLoad(factors [24]float64) LoadPattern {
+	return func(t time.Time) float64 {
+		return factors[t.UTC().Hour()]
+	}
+}
+
+// DailyLoad varies the load factor smoothly from offPeak to peak at peakHour
+// of the day, in UTC, and back.
+func DailyLoad(peakHour, offPeak, peak float64) LoadPattern {
+	return func(t time.Time) float64 {
+		t = t.UTC()
+		hour := float64(t.Hour()) + float64(t.Minute())/60 + float64(t.Second())/3600
+		return offPeak + (peak-offPeak)*(1+math.Cos(2*math.Pi*(hour-peakHour)/24))/2
+	}
+}
+
+type LatencyConfig struct {
+	// Distribution draws the latency of every page, nil adds none.
+	Distribution LatencyDistribution
+	// Slowdowns multiplies the latency of subtrees by path prefix, the longest
+	// matching prefix applying, e.g. {"/archive": 5}.
+	Slowdowns map[string]float64
+	// Bandwidth throttles page bodies to bytes per second, zero leaves them
+	// unthrottled.
+	Bandwidth int
+	// Load varies latency and bandwidth with the time of the simulation, nil
+	// keeps them constant.
+	Load LoadPattern
+}
+
+// WithLatency delays and throttles the pages served like a slow server would.
+// Delays are real time whatever the clock of the simulation, only the load
+// pattern follows it. The injected delay of visits is logged to the observer.
+func WithLatency(config LatencyConfig) GraphMuxOption {
+	return func(mux *GraphMux) {
+		mux.latency = &config
+	}
+}
+
+// slowdown returns the factor of the longest prefix of path in Slowdowns.
+func (config *LatencyConfig) slowdown(path string) float64 {
+	factor, longest := 1.0, -1
+	for prefix, prefixFactor := range config.Slowdowns {
+		if strings.HasPrefix(path, prefix) && len(prefix) > longest {
+			factor, longest = prefixFactor, len(prefix)
+		}
+	}
+	return factor
+}
+
+func (config *LatencyConfig) load(at time.Time) float64 {
+	if config.Load == nil {
+		return 1
+	}
+	return config.Load(at)
+}
+
+// delay returns the latency and the bandwidth of a response for path.
+func (mux *GraphMux) delay(path string) (time.Duration, float64) {
+	config := mux.latency
+	load := config.load(mux.Clock.Now())
+
+	var latency time.Duration
+	if config.Distribution != nil {
+		latency = time.Duration(float64(config.Distribution(mux.rng)) * config.slowdown(path) * load)
+	}
+
+	bandwidth := float64(config.Bandwidth)
+	if load > 0 {
+		bandwidth /= load
+	}
+	return latency, bandwidth
+}
+
+// sleep waits for d, returning early with false if ctx is done.
+func sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// throttledWriter writes bodies in chunks at a bandwidth in bytes per second.
+type throttledWriter struct {
+	http.ResponseWriter
+	ctx       context.Context
+	bandwidth float64
+	// waited is the time spent throttling.
+	waited time.Duration
+	// canceled tells whether the request was canceled before the body was
+	// sent.
+	canceled bool
+}
+
+func (writer *throttledWriter) Write(p []byte) (int, error) {
+	// ten chunks per second
+	chunkSize := max(1, int(writer.bandwidth/10))
+	written := 0
+	for written < len(p) {
+		chunk := p[written:min(written+chunkSize, len(p))]
+		n, err := writer.ResponseWriter.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		if flusher, ok := writer.ResponseWriter.(http.Flusher); ok {
+			flusher.Flush()
+		}
+
+		wait := time.Duration(float64(n) / writer.bandwidth * float64(time.Second))
+		writer.waited += wait
+		if !sleep(writer.ctx, wait) {
+			writer.canceled = true
+			return written, writer.ctx.Err()
+		}
+	}
+	return written, nil
+}
+
+func LatencyMiddleware(mux *GraphMux) Middleware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			latency, bandwidth := mux.delay(r.URL.Path)
+			recordDelay(r, latency)
+			// pages the client left before are not visits
+			if !sleep(r.Context(), latency) {
+				markFailed(r)
+				return
+			}
+
+			if bandwidth <= 0 {
+				next(w, r)
+				return
+			}
+			writer := &throttledWriter{ResponseWriter: w, ctx: r.Context(), bandwidth: bandwidth}
+			next(writer, r)
+			recordDelay(r, writer.waited)
+			if writer.canceled {
+				markFailed(r)
+			}
+		}
+	}
+}
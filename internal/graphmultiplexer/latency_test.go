@@ -0,0 +1,185 @@
+package graphmultiplexer_test
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	gmx "github.com/sdqri/sequined/internal/graphmultiplexer"
+	hyr "github.com/sdqri/sequined/internal/hyperrenderer"
+	obs "github.com/sdqri/sequined/internal/observer"
+)
+
+func TestLatencyDistributions(t *testing.T) {
+	tests := []struct {
+		name           string
+		distribution   gmx.LatencyDistribution
+		expectedMedian time.Duration
+		delta          time.Duration
+	}{
+		{"fixed", gmx.FixedLatency(100 * time.Millisecond), 100 * time.Millisecond, 0},
+		{"normal", gmx.NormalLatency(100*time.Millisecond, 20*time.Millisecond), 100 * time.Millisecond, 5 * time.Millisecond},
+		{"long tail", gmx.LongTailLatency(100*time.Millisecond, 1), 100 * time.Millisecond, 10 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rng := rand.New(rand.NewSource(1))
+			latencies := make([]time.Duration, 10_000)
+			for i := range latencies {
+				latencies[i] = tt.distribution(rng)
+				require.GreaterOrEqual(t, latencies[i], time.Duration(0))
+			}
+			sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+			assert.InDelta(t, tt.expectedMedian, latencies[len(latencies)/2], float64(tt.delta))
+		})
+	}
+
+	// the tail of the log-normal distribution is much longer than the normal one
+	rng := rand.New(rand.NewSource(1))
+	longTail := gmx.LongTailLatency(100*time.Millisecond, 1)
+	slow := 0
+	for i := 0; i < 10_000; i++ {
+		if longTail(rng) > 500*time.Millisecond {
+			slow++
+		}
+	}
+	assert.InDelta(t, 500, slow, 100)
+}
+
+func TestLoadPatterns(t *testing.T) {
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var factors [24]float64
+	factors[9] = 3
+	hourly := gmx.HourlyLoad(factors)
+	assert.Equal(t, 3.0, hourly(day.Add(9*time.Hour+30*time.Minute)))
+	assert.Equal(t, 0.0, hourly(day.Add(10*time.Hour)))
+
+	daily := gmx.DailyLoad(12, 1, 4)
+	assert.InDelta(t, 4, daily(day.Add(12*time.Hour)), 1e-9)
+	assert.InDelta(t, 1, daily(day), 1e-9)
+	assert.InDelta(t, 2.5, daily(day.Add(6*time.Hour)), 1e-9)
+}
+
+func TestLatencyMiddleware(t *testing.T) {
+	root := hyr.NewWebpage(hyr.WebpageTypeHub)
+	slowHub := root.AddChild(hyr.WebpageTypeHub)
+	slowPage := slowHub.AddChild(hyr.WebpageTypeAuthority)
+
+	tests := []struct {
+		name          string
+		config        gmx.LatencyConfig
+		path          string
+		expectedDelay time.Duration
+	}{
+		{
+			name:          "fixed latency",
+			config:        gmx.LatencyConfig{Distribution: gmx.FixedLatency(20 * time.Millisecond)},
+			path:          root.GetPath(),
+			expectedDelay: 20 * time.Millisecond,
+		},
+		{
+			name: "slow subtree",
+			config: gmx.LatencyConfig{
+				Distribution: gmx.FixedLatency(10 * time.Millisecond),
+				Slowdowns:    map[string]float64{slowHub.GetPath(): 3},
+			},
+			path:          slowPage.GetPath(),
+			expectedDelay: 30 * time.Millisecond,
+		},
+		{
+			name: "load",
+			config: gmx.LatencyConfig{
+				Distribution: gmx.FixedLatency(10 * time.Millisecond),
+				Load:         func(time.Time) float64 { return 2 },
+			},
+			path:          root.GetPath(),
+			expectedDelay: 20 * time.Millisecond,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := obs.New()
+			mx, err := gmx.New(root, gmx.WithObserver(o), gmx.WithLatency(tt.config))
+			require.NoError(t, err)
+
+			start := time.Now()
+			r := httptest.NewRecorder()
+			mx.ServeHTTP(r, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			assert.Equal(t, http.StatusOK, r.Code)
+			assert.GreaterOrEqual(t, time.Since(start), tt.expectedDelay)
+
+			require.Len(t, o.VisitHistory, 1)
+			assert.Equal(t, tt.expectedDelay, o.VisitHistory[0].Delay)
+		})
+	}
+}
+
+func TestLatencyMiddlewareBandwidth(t *testing.T) {
+	root := hyr.NewWebpage(hyr.WebpageTypeHub)
+	o := obs.New()
+	mx, err := gmx.New(root, gmx.WithObserver(o))
+	require.NoError(t, err)
+
+	r := httptest.NewRecorder()
+	mx.ServeHTTP(r, httptest.NewRequest(http.MethodGet, root.GetPath(), nil))
+	size := r.Body.Len()
+
+	// the body takes a tenth of a second to send
+	mx, err = gmx.New(root, gmx.WithObserver(o), gmx.WithLatency(gmx.LatencyConfig{Bandwidth: size * 10}))
+	require.NoError(t, err)
+	start := time.Now()
+	throttled := httptest.NewRecorder()
+	mx.ServeHTTP(throttled, httptest.NewRequest(http.MethodGet, root.GetPath(), nil))
+	assert.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond)
+	assert.Equal(t, r.Body.String(), throttled.Body.String())
+
+	require.Len(t, o.VisitHistory, 2)
+	assert.InDelta(t, 100*time.Millisecond, o.VisitHistory[1].Delay, float64(10*time.Millisecond))
+}
+
+func TestLatencyMiddlewareCanceled(t *testing.T) {
+	root := hyr.NewWebpage(hyr.WebpageTypeHub)
+	mx, err := gmx.New(root, gmx.WithLatency(gmx.LatencyConfig{Distribution: gmx.FixedLatency(time.Hour)}))
+	require.NoError(t, err)
+
+	server := httptest.NewServer(mx)
+	defer server.Close()
+	client := http.Client{Timeout: 50 * time.Millisecond}
+	_, err = client.Get(server.URL + root.GetPath())
+	assert.Error(t, err)
+}
+
+func TestLatencyMiddlewareCanceledNotVisited(t *testing.T) {
+	tests := []struct {
+		name   string
+		config gmx.LatencyConfig
+	}{
+		{"latency", gmx.LatencyConfig{Distribution: gmx.FixedLatency(time.Hour)}},
+		{"bandwidth", gmx.LatencyConfig{Bandwidth: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := hyr.NewWebpage(hyr.WebpageTypeHub)
+			o := obs.New()
+			mx, err := gmx.New(root, gmx.WithObserver(o), gmx.WithLatency(tt.config))
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			req := httptest.NewRequest(http.MethodGet, root.GetPath(), nil).WithContext(ctx)
+			mx.ServeHTTP(httptest.NewRecorder(), req)
+			assert.Empty(t, o.VisitHistory)
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package graphmultiplexer
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	hyr "github.com/sdqri/sequined/internal/hyperrenderer"
+)
+
+var ErrUnknownURLNormalization = errors.New("unknown URL normalization")
+
+// URLNormalization is a rule applied to request URLs before matching them
+// against the paths of pages and their URL variants. Without any, paths are
+// matched once percent-decoded and query strings are ignored.
+type URLNormalization string
+
+const (
+	// URLNormalizationStrict makes percent-encoding and query strings
+	// significant: URLs must match the path of a page as escaped, without
+	// query string unless it is the one of the query variant of the page.
+	URLNormalizationStrict URLNormalization = "strict"
+	// URLNormalizationLenient resolves dot segments and duplicate slashes and
+	// ignores trailing slashes.
+	URLNormalizationLenient URLNormalization = "lenient"
+	// URLNormalizationCaseInsensitive matches paths regardless of case.
+	URLNormalizationCaseInsensitive URLNormalization = "case-insensitive"
+	// URLNormalizationIgnoreTrackingParams drops tracking parameters such as
+	// utm_source from query strings, which only matter to strict matching.
+	URLNormalizationIgnoreTrackingParams URLNormalization = "ignore-tracking-params"
+)
+
+// URLNormalizations are the known URL normalization rules.
+var URLNormalizations = []URLNormalization{
+	URLNormalizationStrict,
+	URLNormalizationLenient,
+	URLNormalizationCaseInsensitive,
+	URLNormalizationIgnoreTrackingParams,
+}
+
+// ParseURLNormalization returns the URL normalization rule named name.
+func ParseURLNormalization(name string) (URLNormalization, error) {
+	for _, rule := range URLNormalizations {
+		if string(rule) == name {
+			return rule, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %q", ErrUnknownURLNormalization, name)
+}
+
+// WithURLNormalization applies the rules to request URLs, which combine. It
+// changes which URLs serve pages only, redirects, deleted pages and traps are
+// matched as requested.
+func WithURLNormalization(rules ...URLNormalization) GraphMuxOption {
+	return func(mux *GraphMux) {
+		for _, rule := range rules {
+			switch rule {
+			case URLNormalizationStrict:
+				mux.normalization.strict = true
+			case URLNormalizationLenient:
+				mux.normalization.lenient = true
+			case URLNormalizationCaseInsensitive:
+				mux.normalization.caseInsensitive = true
+			case URLNormalizationIgnoreTrackingParams:
+				mux.normalization.ignoreTrackingParams = true
+			}
+		}
+	}
+}
+
+type urlNormalization struct {
+	strict               bool
+	lenient              bool
+	caseInsensitive      bool
+	ignoreTrackingParams bool
+}
+
+// trackingParams are dropped by URLNormalizationIgnoreTrackingParams along
+// with parameters prefixed by utm_.
+var trackingParams = map[string]struct{}{
+	"gclid":   {},
+	"fbclid":  {},
+	"msclkid": {},
+	"mc_cid":  {},
+	"mc_eid":  {},
+}
+
+func isTrackingParam(name string) bool {
+	if strings.HasPrefix(name, "utm_") {
+		return true
+	}
+	_, ok := trackingParams[name]
+	return ok
+}
+
+// route is a page served on a normalized path, see routeKey.
+type route struct {
+	page hyr.HyperRenderer
+	// variant is the URL variant the path belongs to, empty for the path of
+	// the page.
+	variant hyr.URLVariant
+	// path is the escaped path the route was created for.
+	path string
+	// query is the query string of the query variant of the page, if any.
+	query string
+}
+
+func escapePath(p string) string {
+	return (&url.URL{Path: p}).EscapedPath()
+}
+
+// normalizePath applies the path rules to an escaped path in strict mode and
+// to a decoded path otherwise.
+func (normalization urlNormalization) normalizePath(p string) string {
+	if normalization.lenient {
+		if p == "" {
+			p = "/"
+		}
+		p = path.Clean(p)
+	}
+	if normalization.caseInsensitive {
+		p = strings.ToLower(p)
+	}
+	return p
+}
+
+// routeKey returns the key the page served on u is routed by.
+func (normalization urlNormalization) routeKey(u *url.URL) string {
+	if normalization.strict {
+		return normalization.normalizePath(u.EscapedPath())
+	}
+	return normalization.normalizePath(u.Path)
+}
+
+// normalizeQuery returns the query string compared in strict mode.
+func (normalization urlNormalization) normalizeQuery(rawQuery string) string {
+	if !normalization.ignoreTrackingParams || rawQuery == "" {
+		return rawQuery
+	}
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return rawQuery
+	}
+	for name := range query {
+		if isTrackingParam(name) {
+			query.Del(name)
+		}
+	}
+	return query.Encode()
+}
+
+// acceptsQuery reports whether the query string of a request matches the
+// route in strict mode. Other modes ignore query strings.
+func (normalization urlNormalization) acceptsQuery(r route, rawQuery string) bool {
+	if !normalization.strict {
+		return true
+	}
+	query := normalization.normalizeQuery(rawQuery)
+	return query == "" || (r.query != "" && query == normalization.normalizeQuery(r.query))
+}
+
+// buildRoutes indexes the pages of the route map and their URL variants by
+// normalized path, paths of pages taking precedence over variants. The graph
+// must be read locked.
+func (normalization urlNormalization) buildRoutes(routeMap map[string]hyr.HyperRenderer, variants map[string]variantRoute) map[string]route {
+	routes := make(map[string]route, len(routeMap)+len(variants))
+	key := func(p string) string {
+		if normalization.strict {
+			return normalization.normalizePath(escapePath(p))
+		}
+		return normalization.normalizePath(p)
+	}
+
+	for p, page := range routeMap {
+		r := route{page: page, path: escapePath(p)}
+		if webpage, ok := page.(*hyr.Webpage); ok {
+			for _, variant := range webpage.Variants {
+				if variant != hyr.URLVariantQuery {
+					continue
+				}
+				if variantPath, ok := webpage.VariantPath(variant); ok {
+					_, r.query, _ = strings.Cut(variantPath, "?")
+				}
+			}
+		}
+		// of pages normalized to the same key, the one whose path it is wins
+		if existing, ok := routes[key(p)]; ok && existing.path == key(p) {
+			continue
+		}
+		routes[key(p)] = r
+	}
+	for p, variant := range variants {
+		if _, ok := routes[key(p)]; ok {
+			continue
+		}
+		routes[key(p)] = route{page: variant.webpage, variant: variant.variant, path: escapePath(p)}
+	}
+	return routes
+}
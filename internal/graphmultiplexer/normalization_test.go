@@ -0,0 +1,83 @@
+package graphmultiplexer_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	gmx "github.com/sdqri/sequined/internal/graphmultiplexer"
+	hyr "github.com/sdqri/sequined/internal/hyperrenderer"
+	obs "github.com/sdqri/sequined/internal/observer"
+)
+
+func TestURLNormalization(t *testing.T) {
+	strict := []gmx.URLNormalization{gmx.URLNormalizationStrict}
+	lenient := []gmx.URLNormalization{gmx.URLNormalizationLenient}
+	caseInsensitive := []gmx.URLNormalization{gmx.URLNormalizationCaseInsensitive}
+
+	tests := []struct {
+		name            string
+		rules           []gmx.URLNormalization
+		url             string
+		expectedStatus  int
+		expectedVariant string
+	}{
+		{"default path", nil, "/news/lyon", http.StatusOK, ""},
+		{"default trailing slash", nil, "/news/lyon/", http.StatusNotFound, ""},
+		{"default case", nil, "/NEWS/LYON", http.StatusNotFound, ""},
+		{"default percent-encoding", nil, "/news/ly%6Fn", http.StatusOK, "normalized"},
+		{"default query", nil, "/news/lyon?page=2", http.StatusOK, "query"},
+		{"strict path", strict, "/news/lyon", http.StatusOK, ""},
+		{"strict percent-encoding", strict, "/news/ly%6Fn", http.StatusNotFound, ""},
+		{"strict query", strict, "/news/lyon?page=2", http.StatusNotFound, ""},
+		{"strict query variant", strict, "/news/paris?utm_source=sequined&utm_medium=referral", http.StatusOK, "query"},
+		{"strict other tracking params", strict, "/news/paris?utm_source=newsletter", http.StatusNotFound, ""},
+		{"strict trailing slash variant", strict, "/news/paris/", http.StatusOK, "trailing-slash"},
+		{"ignored tracking params", []gmx.URLNormalization{gmx.URLNormalizationStrict, gmx.URLNormalizationIgnoreTrackingParams}, "/news/lyon?utm_source=newsletter&gclid=1", http.StatusOK, ""},
+		{"ignored tracking params and others", []gmx.URLNormalization{gmx.URLNormalizationIgnoreTrackingParams}, "/news/lyon?page=2&utm_source=newsletter", http.StatusOK, "query"},
+		{"ignored tracking params not strict", []gmx.URLNormalization{gmx.URLNormalizationIgnoreTrackingParams}, "/news/lyon?utm_source=newsletter", http.StatusOK, ""},
+		{"ignored tracking params only", []gmx.URLNormalization{gmx.URLNormalizationStrict, gmx.URLNormalizationIgnoreTrackingParams}, "/news/lyon?page=2&utm_source=newsletter", http.StatusNotFound, ""},
+		{"lenient trailing slash", lenient, "/news/lyon/", http.StatusOK, "normalized"},
+		{"lenient query", lenient, "/news/lyon?page=2", http.StatusOK, "query"},
+		{"lenient trailing slash variant", lenient, "/news/paris/", http.StatusOK, "trailing-slash"},
+		{"case-insensitive", caseInsensitive, "/NEWS/Lyon", http.StatusOK, "normalized"},
+		{"case-insensitive trailing slash", caseInsensitive, "/news/lyon/", http.StatusNotFound, ""},
+		{"lenient and case-insensitive", []gmx.URLNormalization{gmx.URLNormalizationLenient, gmx.URLNormalizationCaseInsensitive}, "/News/Lyon/", http.StatusOK, "normalized"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := hyr.NewWebpage(hyr.WebpageTypeHub)
+			paris := root.AddChild(hyr.WebpageTypeAuthority, hyr.WithPathGenerator(func(*hyr.Webpage) string { return "/news/paris" }))
+			paris.Variants = []hyr.URLVariant{hyr.URLVariantQuery, hyr.URLVariantTrailingSlash}
+			lyon := root.AddChild(hyr.WebpageTypeAuthority, hyr.WithPathGenerator(func(*hyr.Webpage) string { return "/news/lyon" }))
+
+			o := obs.New()
+			mx, err := gmx.New(root, gmx.WithObserver(o), gmx.WithURLNormalization(tt.rules...))
+			require.NoError(t, err)
+
+			r := httptest.NewRecorder()
+			mx.ServeHTTP(r, httptest.NewRequest(http.MethodGet, tt.url, nil))
+			assert.Equal(t, tt.expectedStatus, r.Code)
+			if tt.expectedStatus != http.StatusOK {
+				assert.Empty(t, o.VisitHistory)
+				return
+			}
+			require.Len(t, o.VisitHistory, 1)
+			assert.Contains(t, []obs.NodeID{obs.NodeID(paris.GetID()), obs.NodeID(lyon.GetID())}, o.VisitHistory[0].NodeID)
+			assert.Equal(t, tt.expectedVariant, o.VisitHistory[0].Variant)
+		})
+	}
+}
+
+func TestParseURLNormalization(t *testing.T) {
+	rule, err := gmx.ParseURLNormalization("ignore-tracking-params")
+	require.NoError(t, err)
+	assert.Equal(t, gmx.URLNormalizationIgnoreTrackingParams, rule)
+
+	_, err = gmx.ParseURLNormalization("loose")
+	assert.ErrorIs(t, err, gmx.ErrUnknownURLNormalization)
+}
@@ -0,0 +1,54 @@
+package graphmultiplexer
+
+import (
+	"net/url"
+	"strconv"
+
+	hyr "github.com/sdqri/sequined/internal/hyperrenderer"
+)
+
+// resolvePage returns the page served on u like resolve, along with the page
+// number for pages of paginated hubs, 1 otherwise. Page numbers beyond the
+// last page of a hub are not found.
+func (mux *GraphMux) resolvePage(u *url.URL) (hyr.HyperRenderer, hyr.URLVariant, int, bool) {
+	if base, n, ok := hyr.SplitPagePath(u.Path); ok {
+		hubURL := *u
+		hubURL.Path, hubURL.RawPath = base, ""
+		if page, variant, ok := mux.resolve(&hubURL); ok && mux.isPaginatedIn(page, hyr.PaginationPath) {
+			return page, variant, n, mux.hasPage(page, n)
+		}
+	}
+
+	if query := u.Query(); query.Has(hyr.PageParam) {
+		number := query.Get(hyr.PageParam)
+		query.Del(hyr.PageParam)
+		hubURL := *u
+		hubURL.RawQuery = query.Encode()
+		if page, variant, ok := mux.resolve(&hubURL); ok && mux.isPaginatedIn(page, hyr.PaginationQuery) {
+			n, err := strconv.Atoi(number)
+			return page, variant, n, err == nil && mux.hasPage(page, n)
+		}
+	}
+
+	page, variant, ok := mux.resolve(u)
+	return page, variant, 1, ok
+}
+
+func (mux *GraphMux) isPaginatedIn(page hyr.HyperRenderer, style hyr.PaginationStyle) bool {
+	webpage, ok := page.(*hyr.Webpage)
+	if !ok {
+		return false
+	}
+	mux.Root.RLockGraph()
+	defer mux.Root.RUnlockGraph()
+
+	return webpage.IsPaginated() && webpage.PaginationStyle == style
+}
+
+func (mux *GraphMux) hasPage(page hyr.HyperRenderer, n int) bool {
+	webpage := page.(*hyr.Webpage)
+	mux.Root.RLockGraph()
+	defer mux.Root.RUnlockGraph()
+
+	return n >= 1 && n <= webpage.PageCount()
+}
@@ -0,0 +1,96 @@
+package graphmultiplexer_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	gmx "github.com/sdqri/sequined/internal/graphmultiplexer"
+	hyr "github.com/sdqri/sequined/internal/hyperrenderer"
+	obs "github.com/sdqri/sequined/internal/observer"
+)
+
+func TestPaginatedHubs(t *testing.T) {
+	tests := []struct {
+		name           string
+		style          hyr.PaginationStyle
+		url            string
+		expectedStatus int
+		expectedPage   int
+	}{
+		{"first page", hyr.PaginationQuery, "/", http.StatusOK, 0},
+		{"query page", hyr.PaginationQuery, "/?page=2", http.StatusOK, 2},
+		{"query last page", hyr.PaginationQuery, "/?page=3", http.StatusOK, 3},
+		{"query page beyond the last", hyr.PaginationQuery, "/?page=4", http.StatusNotFound, 0},
+		{"query page not a number", hyr.PaginationQuery, "/?page=next", http.StatusNotFound, 0},
+		{"path page of query style", hyr.PaginationQuery, "/page/2", http.StatusNotFound, 0},
+		{"path page", hyr.PaginationPath, "/page/2", http.StatusOK, 2},
+		{"path page zero", hyr.PaginationPath, "/page/0", http.StatusNotFound, 0},
+		{"query page of path style", hyr.PaginationPath, "/?page=2", http.StatusOK, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := hyr.NewWebpage(hyr.WebpageTypeHub, hyr.WithPagination(2, tt.style))
+			createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			children := make([]*hyr.Webpage, 0)
+			for i := 0; i < 5; i++ {
+				child := root.AddChild(hyr.WebpageTypeAuthority)
+				child.CreatedAt = createdAt.Add(time.Duration(i) * time.Minute)
+				children = append(children, child)
+			}
+
+			o := obs.New()
+			mx, err := gmx.New(root, gmx.WithObserver(o))
+			require.NoError(t, err)
+
+			r := httptest.NewRecorder()
+			mx.ServeHTTP(r, httptest.NewRequest(http.MethodGet, tt.url, nil))
+			assert.Equal(t, tt.expectedStatus, r.Code)
+			if tt.expectedStatus != http.StatusOK {
+				assert.Empty(t, o.VisitHistory)
+				return
+			}
+
+			page := max(tt.expectedPage, 1)
+			for _, link := range root.PageLinks(page) {
+				assert.Contains(t, r.Body.String(), `href="`+link.GetPath()+`"`)
+			}
+			if page == 1 {
+				assert.NotContains(t, r.Body.String(), `href="`+children[0].GetPath()+`"`, "Oldest link should be on the last page")
+			}
+			require.Len(t, o.VisitHistory, 1)
+			assert.Equal(t, obs.NodeID(root.GetID()), o.VisitHistory[0].NodeID)
+			assert.Equal(t, tt.expectedPage, o.VisitHistory[0].Page)
+		})
+	}
+}
+
+func TestPaginatedHubValidators(t *testing.T) {
+	root := hyr.NewWebpage(hyr.WebpageTypeHub, hyr.WithPagination(1, hyr.PaginationQuery))
+	root.AddChild(hyr.WebpageTypeAuthority)
+	root.AddChild(hyr.WebpageTypeAuthority)
+	mx, err := gmx.New(root)
+	require.NoError(t, err)
+
+	get := func(url, etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		r := httptest.NewRecorder()
+		mx.ServeHTTP(r, req)
+		return r
+	}
+
+	first := get("/", "").Header().Get("ETag")
+	second := get("/?page=2", "").Header().Get("ETag")
+	require.NotEmpty(t, first)
+	assert.NotEqual(t, first, second)
+	assert.Equal(t, http.StatusOK, get("/?page=2", first).Code)
+	assert.Equal(t, http.StatusNotModified, get("/?page=2", second).Code)
+}
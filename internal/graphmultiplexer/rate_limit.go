@@ -0,0 +1,193 @@
+package graphmultiplexer
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	obs "github.com/sdqri/sequined/internal/observer"
+)
+
+var ErrInvalidRateLimit = errors.New("invalid rate limit")
+
+type RateLimitConfig struct {
+	// Rate is the number of requests per second a crawler may make on
+	// average, it must be positive.
+	Rate float64
+	// Burst is the number of requests a crawler may make at once, at least 1.
+	Burst int
+	// BanThreshold is the number of requests sooner than Retry-After that get
+	// a crawler banned, zero never bans.
+	BanThreshold int
+	// BanDuration is how long banned crawlers are answered 403 Forbidden.
+	BanDuration time.Duration
+}
+
+// tokenBucket is the rate limiting state of a crawler.
+type tokenBucket struct {
+	tokens    float64
+	updatedAt time.Time
+	// retryAt is the end of the Retry-After of the last 429.
+	retryAt time.Time
+	// ignored counts the requests sooner than Retry-After since the last
+	// allowed request.
+	ignored     int
+	bannedUntil time.Time
+}
+
+type rateLimiter struct {
+	config RateLimitConfig
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	// sweptAt is when idle buckets were last evicted.
+	sweptAt time.Time
+}
+
+// WithRateLimit limits the requests of every crawler with a token bucket,
+// answering 429 Too Many Requests with Retry-After when exceeded and banning
+// crawlers that ignore it. Limits follow the clock of the multiplexer, rates
+// are per second of the simulation. Throttled requests are logged to the
+// observer as faults and requests ignoring Retry-After as violations. New
+// fails with ErrInvalidRateLimit if the rate is not positive.
+func WithRateLimit(config RateLimitConfig) GraphMuxOption {
+	return func(mux *GraphMux) {
+		config.Burst = max(1, config.Burst)
+		mux.rateLimiter = &rateLimiter{
+			config:  config,
+			buckets: make(map[string]*tokenBucket),
+		}
+	}
+}
+
+func (config RateLimitConfig) validate() error {
+	if !(config.Rate > 0) || math.IsInf(config.Rate, 0) {
+		return fmt.Errorf("%w: rate %v is not positive", ErrInvalidRateLimit, config.Rate)
+	}
+	if config.BanDuration < 0 {
+		return fmt.Errorf("%w: negative ban duration %v", ErrInvalidRateLimit, config.BanDuration)
+	}
+	return nil
+}
+
+// refillDuration is the time an empty bucket takes to fill up.
+func (config RateLimitConfig) refillDuration() time.Duration {
+	return time.Duration(float64(config.Burst) / config.Rate * float64(time.Second))
+}
+
+// sweep evicts the buckets that filled up again and are neither banned nor
+// waiting for Retry-After, which would be recreated as they are, at most once
+// per refill duration so that the cost is spread over requests. The limiter
+// must be locked.
+func (limiter *rateLimiter) sweep(now time.Time) {
+	config := limiter.config
+	if now.Sub(limiter.sweptAt) < config.refillDuration() {
+		return
+	}
+	limiter.sweptAt = now
+
+	for crawlerID, bucket := range limiter.buckets {
+		if now.Before(bucket.bannedUntil) || now.Before(bucket.retryAt) {
+			continue
+		}
+		elapsed := now.Sub(bucket.updatedAt).Seconds()
+		if bucket.tokens+elapsed*config.Rate >= float64(config.Burst) {
+			delete(limiter.buckets, crawlerID)
+		}
+	}
+}
+
+// allow takes a token from the bucket of the crawler. It returns the status to
+// answer instead of the page if none is left, zero otherwise, the time to
+// retry after and whether the request ignored an earlier Retry-After.
+func (limiter *rateLimiter) allow(crawlerID string, now time.Time) (status int, retryAfter time.Duration, ignored bool) {
+	config := limiter.config
+
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	limiter.sweep(now)
+	bucket, ok := limiter.buckets[crawlerID]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(config.Burst), updatedAt: now}
+		limiter.buckets[crawlerID] = bucket
+	}
+
+	if now.Before(bucket.bannedUntil) {
+		return http.StatusForbidden, bucket.bannedUntil.Sub(now), false
+	}
+
+	elapsed := now.Sub(bucket.updatedAt).Seconds()
+	bucket.tokens = math.Min(float64(config.Burst), bucket.tokens+elapsed*config.Rate)
+	bucket.updatedAt = now
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		bucket.ignored = 0
+		return 0, 0, false
+	}
+
+	if now.Before(bucket.retryAt) {
+		ignored = true
+		bucket.ignored++
+		if config.BanThreshold > 0 && bucket.ignored >= config.BanThreshold {
+			bucket.ignored = 0
+			bucket.bannedUntil = now.Add(config.BanDuration)
+			return http.StatusForbidden, config.BanDuration, true
+		}
+	}
+
+	retryAfter = time.Duration((1 - bucket.tokens) / config.Rate * float64(time.Second))
+	bucket.retryAt = now.Add(retryAfter)
+	return http.StatusTooManyRequests, retryAfter, ignored
+}
+
+func (mux *GraphMux) logThrottle(req *http.Request, crawlerID string, status int, ignored bool) {
+	if mux.Observer == nil {
+		return
+	}
+
+	now := mux.Clock.Now().UTC()
+	faultType := obs.FaultTypeRateLimited
+	if status == http.StatusForbidden {
+		faultType = obs.FaultTypeBanned
+	}
+	mux.Observer.LogFault(obs.FaultLog{
+		CrawlerID: obs.CrawlerID(crawlerID),
+		Type:      faultType,
+		Path:      req.URL.Path,
+		At:        now,
+	})
+	if ignored {
+		mux.Observer.LogViolation(obs.ViolationLog{
+			CrawlerID: obs.CrawlerID(crawlerID),
+			Type:      obs.ViolationTypeRetryAfter,
+			Path:      req.URL.Path,
+			At:        now,
+		})
+	}
+}
+
+func RateLimitMiddleware(mux *GraphMux) Middleware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			crawlerID := mux.identifyCrawler(r)
+			status, retryAfter, ignored := mux.rateLimiter.allow(crawlerID, mux.Clock.Now())
+			if status == 0 {
+				next(w, r)
+				return
+			}
+			markFailed(r)
+			mux.logThrottle(r, crawlerID, status, ignored)
+
+			// Retry-After has a resolution of seconds, rounding up keeps
+			// crawlers that honor it from being throttled again
+			seconds := int(math.Ceil(retryAfter.Seconds()))
+			w.Header().Set("Retry-After", strconv.Itoa(max(1, seconds)))
+			http.Error(w, http.StatusText(status), status)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package graphmultiplexer_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	clk "github.com/sdqri/sequined/internal/clock"
+	gmx "github.com/sdqri/sequined/internal/graphmultiplexer"
+	hyr "github.com/sdqri/sequined/internal/hyperrenderer"
+	obs "github.com/sdqri/sequined/internal/observer"
+)
+
+func TestRateLimit(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := clk.NewManual(start)
+
+	root := hyr.NewWebpage(hyr.WebpageTypeHub)
+	o := obs.New()
+	config := gmx.RateLimitConfig{Rate: 20, Burst: 2, BanThreshold: 2, BanDuration: 100 * time.Millisecond}
+	mx, err := gmx.New(root, gmx.WithObserver(o), gmx.WithClock(clock), gmx.WithRateLimit(config))
+	require.NoError(t, err)
+
+	request := func(remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, root.GetPath(), nil)
+		req.RemoteAddr = remoteAddr
+		r := httptest.NewRecorder()
+		mx.ServeHTTP(r, req)
+		return r
+	}
+
+	// the burst passes, the next request is throttled
+	assert.Equal(t, http.StatusOK, request("1.1.1.1:1").Code)
+	assert.Equal(t, http.StatusOK, request("1.1.1.1:1").Code)
+	throttled := request("1.1.1.1:1")
+	assert.Equal(t, http.StatusTooManyRequests, throttled.Code)
+	assert.Equal(t, "1", throttled.Header().Get("Retry-After"))
+
+	// crawlers have buckets of their own
+	assert.Equal(t, http.StatusOK, request("2.2.2.2:1").Code)
+
+	// ignoring Retry-After gets the crawler banned
+	assert.Equal(t, http.StatusTooManyRequests, request("1.1.1.1:1").Code)
+	assert.Equal(t, http.StatusForbidden, request("1.1.1.1:1").Code)
+	clock.Advance(60 * time.Millisecond)
+	assert.Equal(t, http.StatusForbidden, request("1.1.1.1:1").Code, "the ban outlasts the bucket refill")
+	clock.Advance(60 * time.Millisecond)
+	assert.Equal(t, http.StatusOK, request("1.1.1.1:1").Code)
+
+	assert.Len(t, o.VisitHistory, 4)
+	now := start.Add(time.Hour)
+	assert.Equal(t, map[obs.FaultType]int{obs.FaultTypeRateLimited: 2, obs.FaultTypeBanned: 2}, o.GetFaultCounts("1.1.1.1", now))
+	assert.Equal(t, map[obs.ViolationType]int{obs.ViolationTypeRetryAfter: 2}, o.GetViolationCounts("1.1.1.1", now))
+	assert.Empty(t, o.GetFaultCounts("2.2.2.2", now))
+}
+
+func TestRateLimitHonored(t *testing.T) {
+	clock := clk.NewManual(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	root := hyr.NewWebpage(hyr.WebpageTypeHub)
+	o := obs.New()
+	mx, err := gmx.New(root, gmx.WithObserver(o), gmx.WithClock(clock), gmx.WithRateLimit(gmx.RateLimitConfig{Rate: 20, BanThreshold: 1, BanDuration: time.Hour}))
+	require.NoError(t, err)
+
+	statuses := make([]int, 0)
+	for i := 0; i < 3; i++ {
+		r := httptest.NewRecorder()
+		mx.ServeHTTP(r, httptest.NewRequest(http.MethodGet, root.GetPath(), nil))
+		statuses = append(statuses, r.Code)
+		if r.Code == http.StatusTooManyRequests {
+			// waits for the token rather than the rounded Retry-After
+			clock.Advance(60 * time.Millisecond)
+		}
+	}
+	assert.Equal(t, []int{http.StatusOK, http.StatusTooManyRequests, http.StatusOK}, statuses)
+	assert.Empty(t, o.ViolationHistory)
+}
+
+func TestRateLimitIdleCrawlers(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := clk.NewManual(start)
+
+	root := hyr.NewWebpage(hyr.WebpageTypeHub)
+	config := gmx.RateLimitConfig{Rate: 1, Burst: 2, BanThreshold: 1, BanDuration: time.Minute}
+	mx, err := gmx.New(root, gmx.WithClock(clock), gmx.WithRateLimit(config))
+	require.NoError(t, err)
+
+	request := func(remoteAddr string) int {
+		req := httptest.NewRequest(http.MethodGet, root.GetPath(), nil)
+		req.RemoteAddr = remoteAddr
+		r := httptest.NewRecorder()
+		mx.ServeHTTP(r, req)
+		return r.Code
+	}
+
+	// one crawler gets banned, the other empties its bucket
+	assert.Equal(t, http.StatusOK, request("1.1.1.1:1"))
+	assert.Equal(t, http.StatusOK, request("1.1.1.1:1"))
+	assert.Equal(t, http.StatusTooManyRequests, request("1.1.1.1:1"))
+	assert.Equal(t, http.StatusForbidden, request("1.1.1.1:1"))
+	assert.Equal(t, http.StatusOK, request("2.2.2.2:1"))
+	assert.Equal(t, http.StatusOK, request("2.2.2.2:1"))
+
+	// requests of other crawlers sweep idle buckets, bans are kept
+	clock.Advance(30 * time.Second)
+	assert.Equal(t, http.StatusOK, request("3.3.3.3:1"))
+	assert.Equal(t, http.StatusForbidden, request("1.1.1.1:1"))
+	assert.Equal(t, http.StatusOK, request("2.2.2.2:1"))
+	assert.Equal(t, http.StatusOK, request("2.2.2.2:1"))
+	assert.Equal(t, http.StatusTooManyRequests, request("2.2.2.2:1"), "swept buckets start full, not beyond")
+
+	clock.Advance(time.Minute)
+	assert.Equal(t, http.StatusOK, request("1.1.1.1:1"))
+}
+
+func TestRateLimitInvalid(t *testing.T) {
+	for _, config := range []gmx.RateLimitConfig{
+		{Rate: 0},
+		{Rate: -1},
+		{Rate: 1, BanDuration: -time.Second},
+	} {
+		_, err := gmx.New(hyr.NewWebpage(hyr.WebpageTypeHub), gmx.WithRateLimit(config))
+		assert.ErrorIs(t, err, gmx.ErrInvalidRateLimit, "%+v", config)
+	}
+}
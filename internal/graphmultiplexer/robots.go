@@ -0,0 +1,168 @@
+package graphmultiplexer
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	hyr "github.com/sdqri/sequined/internal/hyperrenderer"
+	obs "github.com/sdqri/sequined/internal/observer"
+)
+
+type RobotsConfig struct {
+	// Disallow lists path prefixes disallowed for all crawlers.
+	Disallow []string
+	// DisallowFraction of hub subtrees, chosen by ID so that the same
+	// subtrees stay disallowed, is disallowed in addition to Disallow.
+	DisallowFraction float64
+	// CrawlDelay crawlers are asked to wait between requests, zero omits it.
+	CrawlDelay time.Duration
+}
+
+type robots struct {
+	config RobotsConfig
+
+	mu       sync.RWMutex
+	disallow []string
+	// lastRequests holds the time of the latest request of every crawler.
+	lastRequests map[string]time.Time
+}
+
+// WithRobots serves /robots.txt disallowing chosen subtrees of the graph and
+// asking for a crawl delay. Violations are logged to the observer.
+func WithRobots(config RobotsConfig) GraphMuxOption {
+	return func(mux *GraphMux) {
+		mux.robots = &robots{
+			config:       config,
+			lastRequests: make(map[string]time.Time),
+		}
+	}
+}
+
+// inFraction reports whether the ID falls in the given fraction of all IDs.
+func inFraction(id string, fraction float64) bool {
+	if fraction <= 0 {
+		return false
+	}
+	hash := fnv.New64a()
+	hash.Write([]byte(id))
+	return float64(hash.Sum64()%10_000)/10_000 < fraction
+}
+
+// syncRobots recomputes the disallow rules from the graph, paths of subtrees
+// change when pages are reparented.
+func (mux *GraphMux) syncRobots() {
+	rb := mux.robots
+	disallow := make([]string, 0, len(rb.config.Disallow))
+	disallow = append(disallow, rb.config.Disallow...)
+
+	mux.Root.RLockGraph()
+	hyr.Traverse(mux.Root, func(node hyr.HyperRenderer) bool {
+		webpage, ok := node.(*hyr.Webpage)
+		if !ok || webpage == mux.Root || webpage.Type != hyr.WebpageTypeHub {
+			return false
+		}
+		path := webpage.GetPath()
+		if inFraction(webpage.GetID(), rb.config.DisallowFraction) && !matchesAny(disallow, path) {
+			disallow = append(disallow, path)
+		}
+		return false
+	})
+	mux.Root.RUnlockGraph()
+
+	rb.mu.Lock()
+	rb.disallow = disallow
+	rb.mu.Unlock()
+}
+
+func matchesAny(prefixes []string, path string) bool {
+	for _, prefix := range prefixes {
+		if prefix != "" && strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Disallowed reports whether robots.txt disallows the path, rules match by
+// prefix as in the robots exclusion protocol.
+func (mux *GraphMux) Disallowed(path string) bool {
+	if mux.robots == nil {
+		return false
+	}
+	mux.robots.mu.RLock()
+	defer mux.robots.mu.RUnlock()
+
+	return matchesAny(mux.robots.disallow, path)
+}
+
+func (mux *GraphMux) HandleRobots(w http.ResponseWriter, r *http.Request) {
+	rb := mux.robots
+
+	var sb strings.Builder
+	sb.WriteString("User-agent: *\n")
+	rb.mu.RLock()
+	for _, path := range rb.disallow {
+		fmt.Fprintf(&sb, "Disallow: %s\n", path)
+	}
+	rb.mu.RUnlock()
+	if rb.config.CrawlDelay > 0 {
+		fmt.Fprintf(&sb, "Crawl-delay: %s\n", strconv.FormatFloat(rb.config.CrawlDelay.Seconds(), 'f', -1, 64))
+	}
+	if mux.sitemap != nil {
+		fmt.Fprintf(&sb, "\nSitemap: %s/sitemap.xml\n", mux.sitemapBaseURL(r))
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte(sb.String()))
+}
+
+// checkRobots logs the violations of robots.txt the request commits.
+func (mux *GraphMux) checkRobots(req *http.Request) {
+	if mux.Observer == nil {
+		return
+	}
+
+	now := mux.Clock.Now().UTC()
+	crawlerID := mux.identifyCrawler(req)
+
+	// crawlers wait the crawl delay in real time, whatever the clock of the
+	// simulation
+	requestedAt := time.Now()
+	rb := mux.robots
+	rb.mu.Lock()
+	lastRequest, ok := rb.lastRequests[crawlerID]
+	rb.lastRequests[crawlerID] = requestedAt
+	rb.mu.Unlock()
+
+	if ok && requestedAt.Sub(lastRequest) < rb.config.CrawlDelay {
+		mux.Observer.LogViolation(obs.ViolationLog{
+			CrawlerID: obs.CrawlerID(crawlerID),
+			Type:      obs.ViolationTypeCrawlDelay,
+			Path:      req.URL.Path,
+			At:        now,
+		})
+	}
+	if mux.Disallowed(req.URL.Path) {
+		mux.Observer.LogViolation(obs.ViolationLog{
+			CrawlerID: obs.CrawlerID(crawlerID),
+			Type:      obs.ViolationTypeDisallowed,
+			Path:      req.URL.Path,
+			At:        now,
+		})
+	}
+}
+
+func RobotsMiddleware(mux *GraphMux) Middleware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			mux.checkRobots(r)
+
+			next(w, r)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package graphmultiplexer_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	gmx "github.com/sdqri/sequined/internal/graphmultiplexer"
+	hyr "github.com/sdqri/sequined/internal/hyperrenderer"
+	obs "github.com/sdqri/sequined/internal/observer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRobots(t *testing.T) {
+	root := hyr.NewWebpage(hyr.WebpageTypeHub)
+	hub := root.AddChild(hyr.WebpageTypeHub)
+	hubChild := hub.AddChild(hyr.WebpageTypeAuthority)
+	authority := root.AddChild(hyr.WebpageTypeAuthority)
+
+	tests := []struct {
+		name       string
+		config     gmx.RobotsConfig
+		sitemap    bool
+		wantLines  []string
+		disallowed []string
+		allowed    []string
+	}{
+		{
+			name:      "no rules",
+			config:    gmx.RobotsConfig{},
+			wantLines: []string{"User-agent: *"},
+			allowed:   []string{root.GetPath(), hub.GetPath(), hubChild.GetPath()},
+		},
+		{
+			name:       "explicit disallow and crawl delay",
+			config:     gmx.RobotsConfig{Disallow: []string{hub.GetPath()}, CrawlDelay: 1500 * time.Millisecond},
+			wantLines:  []string{"User-agent: *", "Disallow: " + hub.GetPath(), "Crawl-delay: 1.5"},
+			disallowed: []string{hub.GetPath(), hubChild.GetPath()},
+			allowed:    []string{root.GetPath(), authority.GetPath()},
+		},
+		{
+			name:       "all hub subtrees",
+			config:     gmx.RobotsConfig{DisallowFraction: 1},
+			wantLines:  []string{"User-agent: *", "Disallow: " + hub.GetPath()},
+			disallowed: []string{hub.GetPath(), hubChild.GetPath()},
+			allowed:    []string{root.GetPath(), authority.GetPath()},
+		},
+		{
+			name:      "sitemap",
+			config:    gmx.RobotsConfig{},
+			sitemap:   true,
+			wantLines: []string{"User-agent: *", "", "Sitemap: http://example.com/sitemap.xml"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := []gmx.GraphMuxOption{gmx.WithRobots(tt.config)}
+			if tt.sitemap {
+				opts = append(opts, gmx.WithSitemap(gmx.SitemapConfig{}))
+			}
+			mx, err := gmx.New(root, opts...)
+			require.NoError(t, err)
+
+			r := httptest.NewRecorder()
+			mx.ServeHTTP(r, httptest.NewRequest(http.MethodGet, "http://example.com/robots.txt", strings.NewReader("")))
+			assert.Equal(t, http.StatusOK, r.Code)
+			assert.Equal(t, tt.wantLines, strings.Split(strings.TrimSuffix(r.Body.String(), "\n"), "\n"))
+
+			for _, path := range tt.disallowed {
+				assert.True(t, mx.Disallowed(path), path)
+			}
+			for _, path := range tt.allowed {
+				assert.False(t, mx.Disallowed(path), path)
+			}
+		})
+	}
+}
+
+func TestRobotsViolations(t *testing.T) {
+	root := hyr.NewWebpage(hyr.WebpageTypeHub)
+	hub := root.AddChild(hyr.WebpageTypeHub)
+	observer := obs.New()
+
+	mx, err := gmx.New(root,
+		gmx.WithObserver(observer),
+		gmx.WithCrawlerIdentifier(gmx.IdentifyByUserAgent),
+		gmx.WithRobots(gmx.RobotsConfig{Disallow: []string{hub.GetPath()}, CrawlDelay: time.Hour}),
+	)
+	require.NoError(t, err)
+
+	get := func(path, userAgent string) {
+		req := httptest.NewRequest(http.MethodGet, path, strings.NewReader(""))
+		req.Header.Set("User-Agent", userAgent)
+		mx.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	get("/robots.txt", "polite")
+	get(root.GetPath(), "polite")
+	get(root.GetPath(), "rude")
+	get(hub.GetPath(), "rude")
+
+	now := time.Now().UTC()
+	assert.Empty(t, observer.GetViolationCounts("polite", now))
+	assert.Equal(t, map[obs.ViolationType]int{
+		obs.ViolationTypeDisallowed: 1,
+		obs.ViolationTypeCrawlDelay: 1,
+	}, observer.GetViolationCounts("rude", now))
+	// disallowed pages are still served and visited
+	assert.Len(t, observer.VisitHistory, 3)
+}
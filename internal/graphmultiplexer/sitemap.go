@@ -0,0 +1,237 @@
+package graphmultiplexer
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	hyr "github.com/sdqri/sequined/internal/hyperrenderer"
+)
+
+const (
+	sitemapXmlns = "http://www.sitemaps.org/schemas/sitemap/0.9"
+	// MaxSitemapURLs is the limit of URLs in a single sitemap set by the protocol.
+	MaxSitemapURLs = 50_000
+)
+
+type SitemapConfig struct {
+	// BaseURL is prepended to page paths, derived from the request when empty.
+	BaseURL string
+	// MaxURLs per sitemap, beyond it a sitemap index is served. Defaults to
+	// MaxSitemapURLs.
+	MaxURLs int
+	// RefreshInterval makes the sitemap stale: it is regenerated from the
+	// graph at most once per interval. Zero regenerates on every request.
+	RefreshInterval time.Duration
+	// OmitFraction of pages, chosen by ID so that the same pages are always
+	// missing, is left out of the sitemap to make it incomplete.
+	OmitFraction float64
+	// IgnoreModifications reports creation times as lastmod.
+	IgnoreModifications bool
+}
+
+type sitemapEntry struct {
+	Path       string
+	LastMod    time.Time
+	ChangeFreq string
+}
+
+type sitemap struct {
+	config SitemapConfig
+
+	mu          sync.Mutex
+	entries     []sitemapEntry
+	generatedAt time.Time
+}
+
+// WithSitemap serves /sitemap.xml generated from the live graph, split into
+// /sitemaps/{n}.xml behind a sitemap index when it exceeds MaxURLs.
+func WithSitemap(config SitemapConfig) GraphMuxOption {
+	return func(mux *GraphMux) {
+		if config.MaxURLs <= 0 || config.MaxURLs > MaxSitemapURLs {
+			config.MaxURLs = MaxSitemapURLs
+		}
+		mux.sitemap = &sitemap{config: config}
+	}
+}
+
+// ChangeFreq maps a change rate in changes per hour to a sitemap changefreq.
+func ChangeFreq(changeRate float64) string {
+	switch {
+	case changeRate <= 0:
+		return "never"
+	case changeRate >= 60:
+		return "always"
+	case changeRate >= 1:
+		return "hourly"
+	case changeRate >= 1.0/24:
+		return "daily"
+	case changeRate >= 1.0/(24*7):
+		return "weekly"
+	case changeRate >= 1.0/(24*30):
+		return "monthly"
+	default:
+		return "yearly"
+	}
+}
+
+// omitted reports whether the page falls in the omitted fraction of the sitemap.
+func (config SitemapConfig) omitted(webpage *hyr.Webpage) bool {
+	return inFraction(webpage.GetID(), config.OmitFraction)
+}
+
+// getSitemapEntries returns the sitemap entries sorted by path, regenerating them if
+// they are older than the refresh interval.
+func (mux *GraphMux) getSitemapEntries() []sitemapEntry {
+	sm := mux.sitemap
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	now := mux.Clock.Now()
+	if sm.entries != nil && now.Sub(sm.generatedAt) < sm.config.RefreshInterval {
+		return sm.entries
+	}
+
+	entries := make([]sitemapEntry, 0)
+	mux.Root.RLockGraph()
+	hyr.Traverse(mux.Root, func(node hyr.HyperRenderer) bool {
+		webpage, ok := node.(*hyr.Webpage)
+		if !ok || sm.config.omitted(webpage) {
+			return false
+		}
+
+		lastMod := webpage.LastModified()
+		if sm.config.IgnoreModifications {
+			lastMod = webpage.CreatedAt
+		}
+		entries = append(entries, sitemapEntry{
+			Path:       webpage.GetPath(),
+			LastMod:    lastMod,
+			ChangeFreq: ChangeFreq(webpage.ChangeRate),
+		})
+		return false
+	})
+	mux.Root.RUnlockGraph()
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Path < entries[j].Path
+	})
+
+	sm.entries = entries
+	sm.generatedAt = now
+	return entries
+}
+
+type sitemapURL struct {
+	Loc        string `xml:"loc"`
+	LastMod    string `xml:"lastmod,omitempty"`
+	ChangeFreq string `xml:"changefreq,omitempty"`
+}
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapRef struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name     `xml:"sitemapindex"`
+	Xmlns    string       `xml:"xmlns,attr"`
+	Sitemaps []sitemapRef `xml:"sitemap"`
+}
+
+func (mux *GraphMux) sitemapBaseURL(r *http.Request) string {
+	if mux.sitemap.config.BaseURL != "" {
+		return strings.TrimSuffix(mux.sitemap.config.BaseURL, "/")
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s", scheme, r.Host)
+}
+
+func (mux *GraphMux) HandleSitemap(w http.ResponseWriter, r *http.Request) {
+	entries := mux.getSitemapEntries()
+	baseURL := mux.sitemapBaseURL(r)
+	maxURLs := mux.sitemap.config.MaxURLs
+
+	if len(entries) <= maxURLs {
+		writeSitemapURLSet(w, baseURL, entries)
+		return
+	}
+
+	index := sitemapIndex{Xmlns: sitemapXmlns}
+	for part := 0; part*maxURLs < len(entries); part++ {
+		partEntries := entries[part*maxURLs : min((part+1)*maxURLs, len(entries))]
+		index.Sitemaps = append(index.Sitemaps, sitemapRef{
+			Loc:     fmt.Sprintf("%s/sitemaps/%d.xml", baseURL, part+1),
+			LastMod: latestLastMod(partEntries).Format(time.RFC3339),
+		})
+	}
+	writeXML(w, index)
+}
+
+func (mux *GraphMux) HandleSitemapPart(w http.ResponseWriter, r *http.Request) {
+	part, err := strconv.Atoi(strings.TrimSuffix(r.PathValue("name"), ".xml"))
+	if err != nil || part < 1 {
+		http.NotFound(w, r)
+		return
+	}
+
+	entries := mux.getSitemapEntries()
+	maxURLs := mux.sitemap.config.MaxURLs
+	start := (part - 1) * maxURLs
+	if start >= len(entries) {
+		http.NotFound(w, r)
+		return
+	}
+
+	writeSitemapURLSet(w, mux.sitemapBaseURL(r), entries[start:min(start+maxURLs, len(entries))])
+}
+
+func writeSitemapURLSet(w http.ResponseWriter, baseURL string, entries []sitemapEntry) {
+	urlSet := sitemapURLSet{
+		Xmlns: sitemapXmlns,
+		URLs:  make([]sitemapURL, 0, len(entries)),
+	}
+	for _, entry := range entries {
+		urlSet.URLs = append(urlSet.URLs, sitemapURL{
+			Loc:        baseURL + entry.Path,
+			LastMod:    entry.LastMod.Format(time.RFC3339),
+			ChangeFreq: entry.ChangeFreq,
+		})
+	}
+	writeXML(w, urlSet)
+}
+
+func writeXML(w http.ResponseWriter, v any) {
+	output, err := xml.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.Write([]byte(xml.Header))
+	w.Write(output)
+}
+
+func latestLastMod(entries []sitemapEntry) time.Time {
+	var latest time.Time
+	for _, entry := range entries {
+		if entry.LastMod.After(latest) {
+			latest = entry.LastMod
+		}
+	}
+	return latest
+}
@@ -0,0 +1,113 @@
+package graphmultiplexer_test
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	gmx "github.com/sdqri/sequined/internal/graphmultiplexer"
+	hyr "github.com/sdqri/sequined/internal/hyperrenderer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testURLSet struct {
+	URLs []struct {
+		Loc        string `xml:"loc"`
+		LastMod    string `xml:"lastmod"`
+		ChangeFreq string `xml:"changefreq"`
+	} `xml:"url"`
+}
+
+type testSitemapIndex struct {
+	Sitemaps []struct {
+		Loc string `xml:"loc"`
+	} `xml:"sitemap"`
+}
+
+func getXML(t *testing.T, mx *gmx.GraphMux, path string, v any) int {
+	r := httptest.NewRecorder()
+	mx.ServeHTTP(r, httptest.NewRequest(http.MethodGet, "http://example.com"+path, strings.NewReader("")))
+	if r.Code == http.StatusOK {
+		require.NoError(t, xml.Unmarshal(r.Body.Bytes(), v))
+	}
+	return r.Code
+}
+
+func TestSitemap(t *testing.T) {
+	root := hyr.NewWebpage(hyr.WebpageTypeHub)
+	child := root.AddChild(hyr.WebpageTypeAuthority)
+	child.ChangeRate = 2
+	modifiedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	child.Modify(modifiedAt)
+
+	mx, err := gmx.New(root, gmx.WithSitemap(gmx.SitemapConfig{}))
+	require.NoError(t, err)
+
+	var urlSet testURLSet
+	assert.Equal(t, http.StatusOK, getXML(t, mx, "/sitemap.xml", &urlSet))
+	require.Len(t, urlSet.URLs, 2)
+	for _, u := range urlSet.URLs {
+		if u.Loc == "http://example.com"+child.GetPath() {
+			assert.Equal(t, modifiedAt.Format(time.RFC3339), u.LastMod)
+			assert.Equal(t, "hourly", u.ChangeFreq)
+		} else {
+			assert.Equal(t, "http://example.com/", u.Loc)
+			assert.Equal(t, "never", u.ChangeFreq)
+		}
+	}
+}
+
+func TestSitemapIndex(t *testing.T) {
+	root := hyr.NewWebpage(hyr.WebpageTypeHub)
+	for i := 0; i < 4; i++ {
+		root.AddChild(hyr.WebpageTypeAuthority)
+	}
+
+	mx, err := gmx.New(root, gmx.WithSitemap(gmx.SitemapConfig{MaxURLs: 2, BaseURL: "https://site.test/"}))
+	require.NoError(t, err)
+
+	var index testSitemapIndex
+	assert.Equal(t, http.StatusOK, getXML(t, mx, "/sitemap.xml", &index))
+	require.Len(t, index.Sitemaps, 3)
+	assert.Equal(t, "https://site.test/sitemaps/3.xml", index.Sitemaps[2].Loc)
+
+	total := 0
+	for part := 0; part < len(index.Sitemaps); part++ {
+		var urlSet testURLSet
+		assert.Equal(t, http.StatusOK, getXML(t, mx, strings.TrimPrefix(index.Sitemaps[part].Loc, "https://site.test"), &urlSet))
+		total += len(urlSet.URLs)
+	}
+	assert.Equal(t, 5, total)
+	assert.Equal(t, http.StatusNotFound, getXML(t, mx, "/sitemaps/4.xml", &testURLSet{}))
+}
+
+func TestSitemapStaleAndIncomplete(t *testing.T) {
+	root := hyr.NewWebpage(hyr.WebpageTypeHub)
+	for i := 0; i < 100; i++ {
+		root.AddChild(hyr.WebpageTypeAuthority)
+	}
+
+	mx, err := gmx.New(root, gmx.WithSitemap(gmx.SitemapConfig{RefreshInterval: time.Hour, OmitFraction: 0.5}))
+	require.NoError(t, err)
+
+	var urlSet testURLSet
+	getXML(t, mx, "/sitemap.xml", &urlSet)
+	assert.Less(t, len(urlSet.URLs), 101, "some pages should be omitted")
+	assert.Greater(t, len(urlSet.URLs), 0)
+
+	root.AddChild(hyr.WebpageTypeAuthority)
+	var staleURLSet testURLSet
+	getXML(t, mx, "/sitemap.xml", &staleURLSet)
+	assert.Equal(t, urlSet, staleURLSet, "sitemap should not be regenerated before the refresh interval")
+}
+
+func TestChangeFreq(t *testing.T) {
+	assert.Equal(t, "never", gmx.ChangeFreq(0))
+	assert.Equal(t, "always", gmx.ChangeFreq(120))
+	assert.Equal(t, "daily", gmx.ChangeFreq(0.1))
+	assert.Equal(t, "yearly", gmx.ChangeFreq(1.0/(24*365)))
+}
@@ -0,0 +1,106 @@
+package graphmultiplexer
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	ggr "github.com/sdqri/sequined/internal/graphgenerator"
+	hyr "github.com/sdqri/sequined/internal/hyperrenderer"
+)
+
+// WithSnapshot restores the redirects and deleted paths of a snapshot the
+// graph of the multiplexer was built from, see hyr.Snapshot.Build.
+func WithSnapshot(snapshot *hyr.Snapshot) GraphMuxOption {
+	return func(mux *GraphMux) {
+		mux.snapshot = snapshot
+	}
+}
+
+// restoreSnapshot restores the state besides the graph of the snapshot given
+// by WithSnapshot.
+func (mux *GraphMux) restoreSnapshot() error {
+	mux.routeMu.Lock()
+	defer mux.routeMu.Unlock()
+
+	for _, redirect := range mux.snapshot.Redirects {
+		webpage, ok := mux.snapshot.Page(redirect.Page)
+		if !ok {
+			return fmt.Errorf("%w: redirect from %s to unknown page %s", hyr.ErrInvalidSnapshot, redirect.From, redirect.Page)
+		}
+		mux.redirects[redirect.From] = ggr.Redirect{
+			From:    redirect.From,
+			To:      redirect.To,
+			Status:  redirect.Status,
+			Webpage: webpage,
+		}
+	}
+	now := mux.Clock.Now()
+	for _, deletedPath := range mux.snapshot.DeletedPaths {
+		webpage, ok := mux.snapshot.Page(deletedPath.Page)
+		if !ok {
+			return fmt.Errorf("%w: deleted path %s of unknown page %s", hyr.ErrInvalidSnapshot, deletedPath.Path, deletedPath.Page)
+		}
+		// snapshots written before deletion times were kept retain their
+		// deleted paths from now on
+		deletedAt := deletedPath.DeletedAt
+		if deletedAt.IsZero() {
+			deletedAt = now
+		}
+		mux.deletedPages[deletedPath.Path] = deletedPage{webpage: webpage, deletedAt: deletedAt}
+	}
+	mux.queueDeletedPages()
+	mux.expireDeletedPages(now)
+	return nil
+}
+
+// Snapshot returns the snapshot of the graph along with the redirects and
+// deleted paths the multiplexer serves.
+func (mux *GraphMux) Snapshot() (*hyr.Snapshot, error) {
+	mux.Root.RLockGraph()
+	defer mux.Root.RUnlockGraph()
+	snapshot, err := mux.Root.Snapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	mux.routeMu.RLock()
+	defer mux.routeMu.RUnlock()
+	for _, redirect := range mux.redirects {
+		snapshot.Redirects = append(snapshot.Redirects, hyr.RedirectSnapshot{
+			From:   redirect.From,
+			To:     redirect.To,
+			Status: redirect.Status,
+			Page:   redirect.Webpage.GetID(),
+		})
+	}
+	now := mux.Clock.Now()
+	for path, deleted := range mux.deletedPages {
+		if mux.deletedPageExpired(deleted.deletedAt, now) {
+			continue
+		}
+		snapshot.DeletedPaths = append(snapshot.DeletedPaths, hyr.DeletedPathSnapshot{
+			Path:      path,
+			Page:      deleted.webpage.GetID(),
+			DeletedAt: deleted.deletedAt.UTC(),
+		})
+	}
+
+	// maps are unordered, the same state must result in the same snapshot
+	sort.Slice(snapshot.Redirects, func(i, j int) bool {
+		return snapshot.Redirects[i].From < snapshot.Redirects[j].From
+	})
+	sort.Slice(snapshot.DeletedPaths, func(i, j int) bool {
+		return snapshot.DeletedPaths[i].Path < snapshot.DeletedPaths[j].Path
+	})
+	return snapshot, nil
+}
+
+// WriteSnapshot writes the snapshot of the multiplexer as JSON, see Snapshot.
+func (mux *GraphMux) WriteSnapshot(w io.Writer) error {
+	snapshot, err := mux.Snapshot()
+	if err != nil {
+		return err
+	}
+	return snapshot.Write(w)
+}
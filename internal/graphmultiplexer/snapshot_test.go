@@ -0,0 +1,74 @@
+package graphmultiplexer_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ggr "github.com/sdqri/sequined/internal/graphgenerator"
+	gmx "github.com/sdqri/sequined/internal/graphmultiplexer"
+	hyr "github.com/sdqri/sequined/internal/hyperrenderer"
+)
+
+func TestSnapshotRestoresRedirectsAndDeletedPaths(t *testing.T) {
+	root := hyr.NewWebpage(hyr.WebpageTypeHub, hyr.WithSeed(1))
+	hub := root.AddChild(hyr.WebpageTypeHub)
+	moved := root.AddChild(hyr.WebpageTypeAuthority)
+	deleted := root.AddChild(hyr.WebpageTypeAuthority)
+	mx, err := gmx.New(root, gmx.WithDeletedPageResponse(gmx.DeletedPageGone))
+	require.NoError(t, err)
+
+	oldPath := moved.GetPath()
+	root.RemoveLink(moved)
+	hub.Adopt(moved)
+	deletedPath := deleted.GetPath()
+	root.RemoveLink(deleted)
+	deleted.Deleted = true
+
+	updateChan := make(chan ggr.UpdateMessage, 2)
+	updateChan <- ggr.UpdateMessage{Type: ggr.UpdateTypeMove, Redirects: []ggr.Redirect{
+		{From: oldPath, To: moved.GetPath(), Status: http.StatusMovedPermanently, Webpage: moved},
+	}}
+	updateChan <- ggr.UpdateMessage{Type: ggr.UpdateTypeDelete, Webpage: deleted}
+	close(updateChan)
+	errChan := make(chan error)
+	close(errChan)
+	mx.SyncGraph(updateChan, errChan)
+
+	var buf bytes.Buffer
+	require.NoError(t, mx.WriteSnapshot(&buf))
+
+	snapshot, err := hyr.DecodeSnapshot(&buf)
+	require.NoError(t, err)
+	loaded, err := snapshot.Build()
+	require.NoError(t, err)
+	loadedMx, err := gmx.New(loaded, gmx.WithSnapshot(snapshot), gmx.WithDeletedPageResponse(gmx.DeletedPageGone))
+	require.NoError(t, err)
+
+	r := httptest.NewRecorder()
+	loadedMx.ServeHTTP(r, httptest.NewRequest(http.MethodGet, oldPath, nil))
+	assert.Equal(t, http.StatusMovedPermanently, r.Code)
+	assert.Equal(t, moved.GetPath(), r.Header().Get("Location"))
+	redirect, ok := loadedMx.Redirect(oldPath)
+	require.True(t, ok)
+	assert.Equal(t, moved.GetID(), redirect.Webpage.GetID())
+
+	r = httptest.NewRecorder()
+	loadedMx.ServeHTTP(r, httptest.NewRequest(http.MethodGet, deletedPath, nil))
+	assert.Equal(t, http.StatusGone, r.Code)
+	deletedPage, ok := loadedMx.DeletedPage(deletedPath)
+	require.True(t, ok)
+	assert.Equal(t, deleted.GetID(), deletedPage.GetID())
+	assert.True(t, deletedPage.Deleted)
+
+	expected, err := mx.Snapshot()
+	require.NoError(t, err)
+	actual, err := loadedMx.Snapshot()
+	require.NoError(t, err)
+	assert.Equal(t, expected.Redirects, actual.Redirects)
+	assert.Equal(t, expected.DeletedPaths, actual.DeletedPaths)
+}
@@ -0,0 +1,57 @@
+package graphmultiplexer
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+
+	hyr "github.com/sdqri/sequined/internal/hyperrenderer"
+)
+
+// collectTraps returns the traps linked from the graph. The graph must be read
+// locked.
+func collectTraps(root *hyr.Webpage) []*hyr.Trap {
+	traps := make([]*hyr.Trap, 0)
+	hyr.Traverse(root, func(node hyr.HyperRenderer) bool {
+		if webpage, ok := node.(*hyr.Webpage); ok {
+			traps = append(traps, webpage.Traps...)
+		}
+		return false
+	})
+	return traps
+}
+
+// TrapPage returns the page of a trap linked from the graph served on u, if
+// any. Trap pages are generated on every request, they are not routed, at the
+// time of the clock and drawing from the rng of the multiplexer.
+func (mux *GraphMux) TrapPage(u *url.URL) (*hyr.TrapPage, bool) {
+	mux.routeMu.RLock()
+	traps := mux.traps
+	mux.routeMu.RUnlock()
+
+	now := mux.Clock.Now()
+	for _, trap := range traps {
+		if trapPage, ok := trap.Resolve(u, now, mux.rng); ok {
+			return trapPage, true
+		}
+	}
+	return nil, false
+}
+
+// recordTrapPage marks the visit of the request, if logged, as a visit of a
+// trap page.
+func recordTrapPage(r *http.Request, trapPage *hyr.TrapPage) {
+	if visit, ok := r.Context().Value(visitContextKey{}).(*visitRecord); ok {
+		visit.trapPage = trapPage
+	}
+}
+
+func (mux *GraphMux) serveTrapPage(w http.ResponseWriter, r *http.Request, trapPage *hyr.TrapPage) {
+	var buf bytes.Buffer
+	if err := trapPage.Render(&buf); err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	recordTrapPage(r, trapPage)
+	w.Write(buf.Bytes())
+}
@@ -0,0 +1,76 @@
+package graphmultiplexer_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	gmx "github.com/sdqri/sequined/internal/graphmultiplexer"
+	hyr "github.com/sdqri/sequined/internal/hyperrenderer"
+	obs "github.com/sdqri/sequined/internal/observer"
+)
+
+func TestTraps(t *testing.T) {
+	calendar, err := hyr.NewTrap(hyr.TrapTypeCalendar, "")
+	require.NoError(t, err)
+	facets, err := hyr.NewTrap(hyr.TrapTypeFacets, "/search")
+	require.NoError(t, err)
+	root := hyr.NewWebpage(hyr.WebpageTypeHub, hyr.WithTraps(calendar, facets))
+	child := root.AddChild(hyr.WebpageTypeAuthority)
+
+	o := obs.New()
+	mx, err := gmx.New(root, gmx.WithObserver(o))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+		expectedTrap   string
+	}{
+		{"graph page", child.GetPath(), http.StatusOK, ""},
+		{"calendar month", "/calendar/2024/01", http.StatusOK, "calendar"},
+		{"calendar day", "/calendar/2024/01/31", http.StatusOK, "calendar"},
+		{"facets", "/search?color=red&sort=newest", http.StatusOK, "facets"},
+		{"out of the trap", "/calendar/someday", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			visits := len(o.VisitHistory)
+			r := httptest.NewRecorder()
+			mx.ServeHTTP(r, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			assert.Equal(t, tt.expectedStatus, r.Code)
+
+			if tt.expectedStatus != http.StatusOK {
+				assert.Len(t, o.VisitHistory, visits)
+				return
+			}
+			require.Len(t, o.VisitHistory, visits+1)
+			assert.Equal(t, tt.expectedTrap, o.VisitHistory[visits].Trap)
+		})
+	}
+
+	assert.Equal(t, map[string]int{"calendar": 2, "facets": 1}, o.GetTrapRequests("192.0.2.1", o.Now()))
+	assert.NotContains(t, mx.RouteMap, "/calendar", "Traps must not be routed")
+}
+
+func TestTrapLinks(t *testing.T) {
+	recursion, err := hyr.NewTrap(hyr.TrapTypeRecursion, "")
+	require.NoError(t, err)
+	root := hyr.NewWebpage(hyr.WebpageTypeHub, hyr.WithTraps(recursion))
+	mx, err := gmx.New(root)
+	require.NoError(t, err)
+
+	r := httptest.NewRecorder()
+	mx.ServeHTTP(r, httptest.NewRequest(http.MethodGet, root.GetPath(), nil))
+	assert.Contains(t, r.Body.String(), `href="/recursion"`)
+
+	r = httptest.NewRecorder()
+	mx.ServeHTTP(r, httptest.NewRequest(http.MethodGet, "/recursion/more/more", nil))
+	assert.Equal(t, http.StatusOK, r.Code)
+	assert.Contains(t, r.Body.String(), `href="/recursion/more/more/archive"`)
+}
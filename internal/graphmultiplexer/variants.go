@@ -0,0 +1,72 @@
+package graphmultiplexer
+
+import (
+	"net/url"
+
+	hyr "github.com/sdqri/sequined/internal/hyperrenderer"
+)
+
+// variantRoute is a page served on the path of one of its URL variants.
+type variantRoute struct {
+	webpage *hyr.Webpage
+	variant hyr.URLVariant
+}
+
+// collectVariants returns the URL variants of the pages of the graph keyed by
+// path, query variants aside as query strings are not routed. The graph must
+// be read locked.
+func collectVariants(root *hyr.Webpage) map[string]variantRoute {
+	variants := make(map[string]variantRoute)
+	hyr.Traverse(root, func(node hyr.HyperRenderer) bool {
+		webpage, ok := node.(*hyr.Webpage)
+		if !ok {
+			return false
+		}
+		for _, variant := range webpage.Variants {
+			if variant == hyr.URLVariantQuery {
+				continue
+			}
+			if path, ok := webpage.VariantPath(variant); ok {
+				variants[path] = variantRoute{webpage: webpage, variant: variant}
+			}
+		}
+		return false
+	})
+	return variants
+}
+
+// Variant returns the page served on path as one of its URL variants.
+func (mux *GraphMux) Variant(path string) (*hyr.Webpage, hyr.URLVariant, bool) {
+	mux.routeMu.RLock()
+	defer mux.routeMu.RUnlock()
+
+	route, ok := mux.variants[path]
+	return route.webpage, route.variant, ok
+}
+
+// resolve returns the page served on u, by path or URL variant as normalized
+// by the URL normalization rules, along with the variant it was requested
+// under. Any query string makes a query variant, as it serves the same
+// content under another URL, unless nothing is left of it once normalized.
+func (mux *GraphMux) resolve(u *url.URL) (hyr.HyperRenderer, hyr.URLVariant, bool) {
+	mux.routeMu.RLock()
+	r, ok := mux.routes[mux.normalization.routeKey(u)]
+	// a variant normalized to the path of its page is still that variant
+	exact, isVariant := mux.variants[u.Path]
+	mux.routeMu.RUnlock()
+	if !ok || !mux.normalization.acceptsQuery(r, u.RawQuery) {
+		return nil, "", false
+	}
+
+	variant := r.variant
+	if variant == "" && u.EscapedPath() != r.path {
+		variant = hyr.URLVariantNormalized
+		if isVariant && hyr.HyperRenderer(exact.webpage) == r.page {
+			variant = exact.variant
+		}
+	}
+	if variant == "" && mux.normalization.normalizeQuery(u.RawQuery) != "" {
+		variant = hyr.URLVariantQuery
+	}
+	return r.page, variant, true
+}
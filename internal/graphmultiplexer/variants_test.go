@@ -0,0 +1,86 @@
+package graphmultiplexer_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	gmx "github.com/sdqri/sequined/internal/graphmultiplexer"
+	hyr "github.com/sdqri/sequined/internal/hyperrenderer"
+	obs "github.com/sdqri/sequined/internal/observer"
+)
+
+func TestURLVariants(t *testing.T) {
+	root := hyr.NewWebpage(hyr.WebpageTypeHub, hyr.WithSeed(1))
+	child := root.AddChild(hyr.WebpageTypeAuthority, hyr.WithPathGenerator(func(*hyr.Webpage) string { return "/news/paris" }))
+	child.Variants = hyr.URLVariants
+	plain := root.AddChild(hyr.WebpageTypeAuthority)
+
+	o := obs.New()
+	mx, err := gmx.New(root, gmx.WithObserver(o))
+	require.NoError(t, err)
+
+	r := httptest.NewRecorder()
+	mx.ServeHTTP(r, httptest.NewRequest(http.MethodGet, child.GetPath(), nil))
+	require.Equal(t, http.StatusOK, r.Code)
+	body := r.Body.String()
+
+	tests := []struct {
+		name            string
+		path            string
+		expectedStatus  int
+		expectedVariant string
+	}{
+		{"path", "/news/paris", http.StatusOK, ""},
+		{"trailing slash", "/news/paris/", http.StatusOK, "trailing-slash"},
+		{"case", "/NEWS/PARIS", http.StatusOK, "case"},
+		{"query", "/news/paris?utm_source=sequined&utm_medium=referral", http.StatusOK, "query"},
+		{"any query", "/news/paris?ref=home", http.StatusOK, "query"},
+		{"variant of another page", plain.GetPath() + "/", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			visits := len(o.VisitHistory)
+			r := httptest.NewRecorder()
+			mx.ServeHTTP(r, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			assert.Equal(t, tt.expectedStatus, r.Code)
+			if tt.expectedStatus != http.StatusOK {
+				assert.Len(t, o.VisitHistory, visits)
+				return
+			}
+			assert.Equal(t, body, r.Body.String(), "Variants should serve identical content")
+			require.Len(t, o.VisitHistory, visits+1)
+			assert.Equal(t, obs.NodeID(child.GetID()), o.VisitHistory[visits].NodeID)
+			assert.Equal(t, tt.expectedVariant, o.VisitHistory[visits].Variant)
+		})
+	}
+
+	duplicateFetches, _ := o.GetDuplicateScore("192.0.2.1", o.Now())
+	assert.Equal(t, float64(3), duplicateFetches)
+}
+
+func TestDuplicateNodes(t *testing.T) {
+	root := hyr.NewWebpage(hyr.WebpageTypeHub)
+	original := root.AddChild(hyr.WebpageTypeAuthority)
+	duplicate := root.AddChild(hyr.WebpageTypeAuthority)
+	duplicate.DuplicateOf = original
+	duplicate.Similarity = 1
+
+	o := obs.New()
+	mx, err := gmx.New(root, gmx.WithObserver(o))
+	require.NoError(t, err)
+	assert.Equal(t, obs.NodeID(original.GetID()), o.NodeLogMap[obs.NodeID(duplicate.GetID())].DuplicateOf)
+
+	for _, webpage := range []*hyr.Webpage{original, duplicate} {
+		r := httptest.NewRecorder()
+		mx.ServeHTTP(r, httptest.NewRequest(http.MethodGet, webpage.GetPath(), nil))
+		require.Equal(t, http.StatusOK, r.Code)
+	}
+	duplicateFetches, score := o.GetDuplicateScore("192.0.2.1", o.Now())
+	assert.Equal(t, float64(1), duplicateFetches)
+	assert.Equal(t, 0.5, score)
+}
@@ -0,0 +1,89 @@
+package hyperrenderer
+
+import (
+	"math"
+	"math/rand"
+	"strings"
+
+	"github.com/brianvoe/gofakeit/v7"
+)
+
+const (
+	contentParagraphs  = 10
+	paragraphSentences = 5
+	sentenceWords      = 10
+)
+
+// Content is the text of a page as rendered by the default authority template.
+type Content struct {
+	Title     string
+	Summary   string
+	Sentences []string
+}
+
+// Paragraphs joins the sentences of the content into paragraphs.
+func (content Content) Paragraphs() []string {
+	paragraphs := make([]string, 0, contentParagraphs)
+	for i := 0; i < len(content.Sentences); i += paragraphSentences {
+		end := min(i+paragraphSentences, len(content.Sentences))
+		paragraphs = append(paragraphs, strings.Join(content.Sentences[i:end], " "))
+	}
+	return paragraphs
+}
+
+// contentSeed seeds the faker of the content version of a page.
+func contentSeed(id, version uint64) uint64 {
+	seed := id
+	if version != 0 {
+		seed ^= version * 0x9E3779B97F4A7C15
+	}
+	return seed
+}
+
+// Content returns the text of the current content version of the page. The
+// text of a duplicate keeps the share Similarity of the sentences of the page
+// it duplicates, as of DuplicateVersion, and its title and summary.
+func (wp *Webpage) Content() Content {
+	return wp.contentAt(wp.Version)
+}
+
+func (wp *Webpage) contentAt(version uint64) Content {
+	seed := contentSeed(wp.ID, version)
+	faker := gofakeit.New(seed)
+	content := Content{
+		Title:     faker.City(),
+		Summary:   faker.Sentence(sentenceWords),
+		Sentences: make([]string, contentParagraphs*paragraphSentences),
+	}
+	for i := range content.Sentences {
+		content.Sentences[i] = faker.Sentence(sentenceWords)
+	}
+	if wp.DuplicateOf == nil {
+		return content
+	}
+
+	duplicated := wp.DuplicateOf.contentAt(wp.DuplicateVersion)
+	content.Title, content.Summary = duplicated.Title, duplicated.Summary
+	kept := int(math.Round(min(max(wp.Similarity, 0), 1) * float64(len(content.Sentences))))
+	for _, i := range rand.New(rand.NewSource(int64(seed))).Perm(len(content.Sentences))[:kept] {
+		content.Sentences[i] = duplicated.Sentences[i]
+	}
+	return content
+}
+
+// CanonicalPath returns the path rel=canonical points to, see CanonicalTag:
+// the path of the page it duplicates unless deleted, its own path otherwise.
+func (wp *Webpage) CanonicalPath() string {
+	if wp.DuplicateOf != nil && !wp.DuplicateOf.Deleted {
+		return wp.DuplicateOf.GetPath()
+	}
+	return wp.GetPath()
+}
+
+// WithCanonicalTag renders a rel=canonical link in the head of the page and of
+// its clones, see CanonicalPath.
+func WithCanonicalTag() WebpageOption {
+	return func(w *Webpage) {
+		w.CanonicalTag = true
+	}
+}
@@ -0,0 +1,118 @@
+package hyperrenderer_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	hr "github.com/sdqri/sequined/internal/hyperrenderer"
+)
+
+func sharedSentences(a, b hr.Content) int {
+	shared := 0
+	for i, sentence := range a.Sentences {
+		if b.Sentences[i] == sentence {
+			shared++
+		}
+	}
+	return shared
+}
+
+func TestContentDuplicates(t *testing.T) {
+	tests := []struct {
+		name           string
+		similarity     float64
+		expectedShared int
+	}{
+		{"exact duplicate", 1, 50},
+		{"near duplicate", 0.8, 40},
+		{"loose duplicate", 0.3, 15},
+		{"unrelated", 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := hr.NewWebpage(hr.WebpageTypeHub, hr.WithSeed(1))
+			original := root.AddChild(hr.WebpageTypeAuthority)
+			duplicate := root.AddChild(hr.WebpageTypeAuthority)
+			duplicate.DuplicateOf = original
+			duplicate.Similarity = tt.similarity
+
+			originalContent, duplicateContent := original.Content(), duplicate.Content()
+			require.Len(t, duplicateContent.Paragraphs(), 10)
+			assert.Equal(t, originalContent.Title, duplicateContent.Title)
+			assert.Equal(t, tt.expectedShared, sharedSentences(originalContent, duplicateContent))
+			assert.Equal(t, duplicateContent, duplicate.Content(), "Content should be reproducible")
+		})
+	}
+}
+
+func TestContentDuplicatesFollowModifications(t *testing.T) {
+	root := hr.NewWebpage(hr.WebpageTypeHub, hr.WithSeed(1))
+	original := root.AddChild(hr.WebpageTypeAuthority)
+	duplicate := root.AddChild(hr.WebpageTypeAuthority)
+	duplicate.DuplicateOf = original
+	duplicate.Similarity = 1
+
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	original.Modify(at)
+	assert.NotEqual(t, original.Content(), duplicate.Content(), "Duplicates should keep the copied version")
+
+	duplicate.Modify(at)
+	assert.Equal(t, original.Content(), duplicate.Content(), "Modified duplicates should copy the current version")
+}
+
+func TestCanonicalTag(t *testing.T) {
+	root := hr.NewWebpage(hr.WebpageTypeHub, hr.WithCanonicalTag())
+	original := root.AddChild(hr.WebpageTypeAuthority)
+	duplicate := root.AddChild(hr.WebpageTypeAuthority)
+	duplicate.DuplicateOf = original
+	duplicate.Similarity = 0.9
+
+	var buf bytes.Buffer
+	require.NoError(t, duplicate.Render(&buf))
+	assert.Contains(t, buf.String(), `<link rel="canonical" href="`+original.GetPath()+`">`)
+	buf.Reset()
+	require.NoError(t, root.Render(&buf))
+	assert.Contains(t, buf.String(), `<link rel="canonical" href="/">`)
+
+	original.Deleted = true
+	assert.Equal(t, duplicate.GetPath(), duplicate.CanonicalPath())
+
+	withoutTag := hr.NewWebpage(hr.WebpageTypeHub)
+	buf.Reset()
+	require.NoError(t, withoutTag.Render(&buf))
+	assert.NotContains(t, buf.String(), `rel="canonical"`)
+}
+
+func TestSnapshotDuplicates(t *testing.T) {
+	root := hr.NewWebpage(hr.WebpageTypeHub, hr.WithSeed(1), hr.WithCanonicalTag())
+	original := root.AddChild(hr.WebpageTypeAuthority)
+	duplicate := root.AddChild(hr.WebpageTypeAuthority)
+	duplicate.DuplicateOf = original
+	duplicate.Similarity = 0.5
+	duplicate.Variants = []hr.URLVariant{hr.URLVariantQuery}
+	orphan := root.AddChild(hr.WebpageTypeAuthority)
+	orphan.DuplicateOf = original
+	orphan.Similarity = 1
+	expectedContent := orphan.Content()
+
+	// the original of orphan is deleted and unlinked
+	root.RemoveLink(original)
+	original.Deleted = true
+
+	var buf bytes.Buffer
+	require.NoError(t, root.WriteSnapshot(&buf))
+	restored, err := hr.ReadSnapshot(&buf)
+	require.NoError(t, err)
+
+	require.Len(t, restored.Links, 2)
+	assert.Equal(t, duplicate.Content(), restored.Links[0].Content())
+	assert.Equal(t, duplicate.VariantPaths(), restored.Links[0].VariantPaths())
+	assert.True(t, restored.Links[0].CanonicalTag)
+	assert.Equal(t, expectedContent, restored.Links[1].Content())
+	assert.Equal(t, restored.Links[1].GetPath(), restored.Links[1].CanonicalPath())
+}
@@ -45,6 +45,13 @@ func (m *MockHyperRenderer) GetLinks() []hr.HyperRenderer {
 }
 
 func TestTraverse(t *testing.T) {
+	cycleRoot := &MockHyperRenderer{ID: "root", Path: "/root"}
+	cycleChild := &MockHyperRenderer{ID: "child", Path: "/root/child"}
+	cycleGrandChild := &MockHyperRenderer{ID: "grandchild", Path: "/root/child/grandchild"}
+	cycleRoot.Links = []*MockHyperRenderer{cycleChild, cycleGrandChild}
+	cycleChild.Links = []*MockHyperRenderer{cycleGrandChild}
+	cycleGrandChild.Links = []*MockHyperRenderer{cycleRoot, cycleChild}
+
 	testCases := []struct {
 		Name           string
 		Root           hr.HyperRenderer
@@ -65,6 +72,11 @@ func TestTraverse(t *testing.T) {
 			},
 			ExpectedVisits: 2,
 		},
+		{
+			Name:           "Cycles",
+			Root:           cycleRoot,
+			ExpectedVisits: 3,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -0,0 +1,306 @@
+package hyperrenderer
+
+import (
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	ErrSitemapIndex = errors.New("sitemap index can not be imported, import its sitemaps")
+	ErrNoURLs       = errors.New("no URLs to import")
+)
+
+// FixedPathGenerator returns the Path of the page if it has one, as imported
+// pages do, deriving it from the parent like the default path generator
+// otherwise.
+func FixedPathGenerator(webpage *Webpage) string {
+	if webpage.Path != "" {
+		return webpage.Path
+	}
+	return defaultPathGenerator(webpage)
+}
+
+func init() {
+	RegisterPathGenerator("fixed", FixedPathGenerator)
+}
+
+// importedPage is a page to import along with what the source tells of it.
+type importedPage struct {
+	path       string
+	createdAt  time.Time
+	changeRate float64
+}
+
+type importedLink struct {
+	from, to string
+}
+
+// importer builds the graph of imported pages, keeping their paths.
+type importer struct {
+	pages []importedPage
+	links []importedLink
+	index map[string]int
+}
+
+func newImporter() *importer {
+	return &importer{index: make(map[string]int)}
+}
+
+// normalizePath returns the unescaped path of a URL or path, the form request
+// URLs and generated paths have, dropping the host, the query and trailing
+// slashes.
+func normalizePath(rawURL string) (string, error) {
+	parsed, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return "", err
+	}
+	cleaned := path.Clean("/" + parsed.Path)
+	return cleaned, nil
+}
+
+// add adds the page of the URL if new and returns its path.
+func (imp *importer) add(rawURL string) (string, error) {
+	pagePath, err := normalizePath(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("importing %q: %w", rawURL, err)
+	}
+	if _, ok := imp.index[pagePath]; !ok {
+		imp.index[pagePath] = len(imp.pages)
+		imp.pages = append(imp.pages, importedPage{path: pagePath})
+	}
+	return pagePath, nil
+}
+
+func (imp *importer) page(pagePath string) *importedPage {
+	return &imp.pages[imp.index[pagePath]]
+}
+
+// build creates the graph. Links are added in the order given, the first link
+// to a page making its parent as with AddLink. Pages left without parent
+// become children of the page at their nearest ancestor path, or of the root.
+// Pages linking others are hubs, the others authorities.
+func (imp *importer) build(opts ...WebpageOption) (*Webpage, error) {
+	if len(imp.pages) == 0 {
+		return nil, ErrNoURLs
+	}
+
+	root := NewWebpage(WebpageTypeHub, append(opts, WithPathGenerator(FixedPathGenerator))...)
+	root.Path = "/"
+	if _, ok := imp.index["/"]; !ok {
+		imp.index["/"] = len(imp.pages)
+		imp.pages = append(imp.pages, importedPage{path: "/"})
+	}
+
+	pages := make(map[string]*Webpage, len(imp.pages))
+	created := make([]*Webpage, 0, len(imp.pages))
+	for _, imported := range imp.pages {
+		webpage := root
+		if imported.path != "/" {
+			webpage = root.Clone(WebpageTypeAuthority)
+			webpage.Path = imported.path
+		}
+		if !imported.createdAt.IsZero() {
+			webpage.CreatedAt = imported.createdAt
+		}
+		webpage.ChangeRate = imported.changeRate
+		pages[imported.path] = webpage
+		created = append(created, webpage)
+	}
+
+	for _, link := range imp.links {
+		if link.from != link.to {
+			pages[link.from].AddLink(pages[link.to])
+		}
+	}
+
+	for _, webpage := range created {
+		if webpage == root || webpage.Parent != nil {
+			continue
+		}
+		parent := root
+		for ancestorPath := path.Dir(webpage.Path); ancestorPath != "/"; ancestorPath = path.Dir(ancestorPath) {
+			if ancestor, ok := pages[ancestorPath]; ok && !webpage.IsAncestorOf(ancestor) {
+				parent = ancestor
+				break
+			}
+		}
+		parent.Adopt(webpage)
+	}
+
+	for _, webpage := range created {
+		if webpage != root && len(webpage.Links) > 0 {
+			webpage.Type = WebpageTypeHub
+		}
+	}
+	return root, nil
+}
+
+type sitemapURLSet struct {
+	XMLName xml.Name `xml:"urlset"`
+	URLs    []struct {
+		Loc        string `xml:"loc"`
+		LastMod    string `xml:"lastmod"`
+		ChangeFreq string `xml:"changefreq"`
+	} `xml:"url"`
+}
+
+// changeRates maps sitemap changefreq values to change rates in changes per
+// hour.
+var changeRates = map[string]float64{
+	"always":  60,
+	"hourly":  1,
+	"daily":   1.0 / 24,
+	"weekly":  1.0 / (24 * 7),
+	"monthly": 1.0 / (24 * 30),
+	"yearly":  1.0 / (24 * 365),
+	"never":   0,
+}
+
+// ReadSitemap builds a graph from the URLs of a sitemap, keeping their paths
+// and the tree they form. lastmod sets the creation time of pages and
+// changefreq their change rate.
+func ReadSitemap(r io.Reader, opts ...WebpageOption) (*Webpage, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var probe struct{ XMLName xml.Name }
+	if err := xml.Unmarshal(content, &probe); err != nil {
+		return nil, fmt.Errorf("decoding sitemap: %w", err)
+	}
+	if probe.XMLName.Local == "sitemapindex" {
+		return nil, ErrSitemapIndex
+	}
+
+	var urlSet sitemapURLSet
+	if err := xml.Unmarshal(content, &urlSet); err != nil {
+		return nil, fmt.Errorf("decoding sitemap: %w", err)
+	}
+
+	imp := newImporter()
+	for _, entry := range urlSet.URLs {
+		pagePath, err := imp.add(entry.Loc)
+		if err != nil {
+			return nil, err
+		}
+		page := imp.page(pagePath)
+		if entry.LastMod != "" {
+			page.createdAt, err = parseLastMod(entry.LastMod)
+			if err != nil {
+				return nil, fmt.Errorf("lastmod of %s: %w", entry.Loc, err)
+			}
+		}
+		page.changeRate = changeRates[strings.ToLower(strings.TrimSpace(entry.ChangeFreq))]
+	}
+	return imp.build(opts...)
+}
+
+// parseLastMod parses the W3C datetime formats sitemaps use.
+func parseLastMod(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04Z07:00", "2006-01-02", "2006-01", "2006"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unknown datetime format %q", value)
+}
+
+// ReadURLList builds a graph from a list of URLs or paths, one per line,
+// keeping their paths and the tree they form. Empty lines and lines starting
+// with # are skipped.
+func ReadURLList(r io.Reader, opts ...WebpageOption) (*Webpage, error) {
+	imp := newImporter()
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if _, err := imp.add(line); err != nil {
+			return nil, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return imp.build(opts...)
+}
+
+// ReadEdgeList builds a graph from the links of a crawl, either as an edge
+// list of whitespace separated URL or path pairs, one link per line, or as the
+// edge statements of a DOT digraph such as "/a" -> "/b". Lines with a single
+// URL add a page without links. The first link to a page makes its parent,
+// pages not linked from their parent path keep their paths nevertheless.
+func ReadEdgeList(r io.Reader, opts ...WebpageOption) (*Webpage, error) {
+	imp := newImporter()
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		nodes := parseEdgeLine(scanner.Text())
+
+		paths := make([]string, 0, len(nodes))
+		for _, node := range nodes {
+			pagePath, err := imp.add(node)
+			if err != nil {
+				return nil, err
+			}
+			paths = append(paths, pagePath)
+		}
+		for i := 1; i < len(paths); i++ {
+			imp.links = append(imp.links, importedLink{from: paths[i-1], to: paths[i]})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return imp.build(opts...)
+}
+
+// parseEdgeLine returns the nodes of an edge list or DOT line, a chain of
+// links for DOT edge statements.
+func parseEdgeLine(line string) []string {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "//") {
+		return nil
+	}
+
+	line = strings.TrimSpace(strings.TrimSuffix(stripAttributes(line), ";"))
+	if dotStatement.MatchString(line) {
+		return nil
+	}
+
+	if !strings.Contains(line, "->") {
+		fields := strings.Fields(line)
+		for i, field := range fields {
+			fields[i] = strings.Trim(field, `"`)
+		}
+		return fields
+	}
+
+	nodes := strings.Split(line, "->")
+	for i, node := range nodes {
+		nodes[i] = strings.Trim(strings.TrimSpace(node), `"`)
+	}
+	return nodes
+}
+
+// dotStatement matches DOT statements other than node and edge statements:
+// braces, graph, node and edge attributes and assignments.
+var dotStatement = regexp.MustCompile(`^$|^[{}]|[{]$|^(strict|digraph|graph|subgraph|node|edge)\b|^\w+\s*=`)
+
+// stripAttributes removes the DOT attribute list of a statement.
+func stripAttributes(statement string) string {
+	if i := strings.Index(statement, " ["); i >= 0 {
+		return strings.TrimSpace(statement[:i])
+	}
+	return statement
+}
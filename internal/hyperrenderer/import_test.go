@@ -0,0 +1,161 @@
+package hyperrenderer_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	hr "github.com/sdqri/sequined/internal/hyperrenderer"
+)
+
+func pageByPath(t *testing.T, root hr.HyperRenderer, path string) *hr.Webpage {
+	t.Helper()
+
+	page, ok := hr.CreatePathMap(root)[path]
+	require.True(t, ok, "page %s is missing", path)
+	return page.(*hr.Webpage)
+}
+
+func TestReadSitemap(t *testing.T) {
+	sitemap := `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>https://example.com/</loc></url>
+  <url><loc>https://example.com/blog/</loc><changefreq>daily</changefreq></url>
+  <url><loc>https://example.com/blog/first-post</loc><lastmod>2024-01-02</lastmod></url>
+  <url><loc>https://example.com/blog/second-post?utm=1</loc><lastmod>2024-01-03T10:00:00+02:00</lastmod></url>
+  <url><loc>https://example.com/about</loc></url>
+</urlset>`
+
+	root, err := hr.ReadSitemap(strings.NewReader(sitemap))
+	require.NoError(t, err)
+
+	paths := pathIDs(root)
+	assert.Len(t, paths, 5)
+	for _, path := range []string{"/", "/blog", "/blog/first-post", "/blog/second-post", "/about"} {
+		assert.Contains(t, paths, path)
+	}
+
+	blog := pageByPath(t, root, "/blog")
+	assert.Equal(t, root, blog.Parent)
+	assert.EqualValues(t, hr.WebpageTypeHub, blog.Type)
+	assert.InDelta(t, 1.0/24, blog.ChangeRate, 1e-9)
+
+	post := pageByPath(t, root, "/blog/first-post")
+	assert.Equal(t, blog, post.Parent)
+	assert.EqualValues(t, hr.WebpageTypeAuthority, post.Type)
+	assert.Equal(t, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), post.CreatedAt)
+	assert.Equal(t, time.Date(2024, 1, 3, 8, 0, 0, 0, time.UTC), pageByPath(t, root, "/blog/second-post").CreatedAt)
+}
+
+func TestReadSitemapErrors(t *testing.T) {
+	testCases := []struct {
+		description string
+		sitemap     string
+		expectedErr error
+	}{
+		{
+			description: "sitemap index",
+			sitemap:     `<sitemapindex><sitemap><loc>https://example.com/sitemap1.xml</loc></sitemap></sitemapindex>`,
+			expectedErr: hr.ErrSitemapIndex,
+		},
+		{
+			description: "no URLs",
+			sitemap:     `<urlset></urlset>`,
+			expectedErr: hr.ErrNoURLs,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			_, err := hr.ReadSitemap(strings.NewReader(tc.sitemap))
+			assert.ErrorIs(t, err, tc.expectedErr)
+		})
+	}
+
+	_, err := hr.ReadSitemap(strings.NewReader(`<urlset><url><loc>/a</loc><lastmod>yesterday</lastmod></url></urlset>`))
+	assert.Error(t, err)
+}
+
+func TestReadURLList(t *testing.T) {
+	list := `# crawl of example.com
+https://example.com/shop/shoes/sneakers
+
+/shop
+/shop/hats/
+`
+	root, err := hr.ReadURLList(strings.NewReader(list))
+	require.NoError(t, err)
+	assert.Len(t, pathIDs(root), 4)
+
+	// missing intermediate paths attach pages to their nearest ancestor
+	shop := pageByPath(t, root, "/shop")
+	assert.Equal(t, shop, pageByPath(t, root, "/shop/shoes/sneakers").Parent)
+	assert.Equal(t, shop, pageByPath(t, root, "/shop/hats").Parent)
+	assert.Equal(t, root, shop.Parent)
+
+	_, err = hr.ReadURLList(strings.NewReader("# nothing\n\n"))
+	assert.ErrorIs(t, err, hr.ErrNoURLs)
+}
+
+func TestReadEdgeList(t *testing.T) {
+	testCases := []struct {
+		description string
+		edges       string
+	}{
+		{
+			description: "edge list",
+			edges: `/ /docs
+/docs /docs/install
+/docs/install /docs
+/ https://example.com/contact
+/docs/install /contact
+/orphan/page`,
+		},
+		{
+			description: "DOT",
+			edges: `digraph site {
+  rankdir=LR;
+  node [shape=box];
+  "/" -> "/docs" -> "/docs/install" -> "/docs";
+  "/" -> "/contact" [color=red];
+  "/docs/install" -> "/contact";
+  "/orphan/page" [label="orphan"];
+}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			root, err := hr.ReadEdgeList(strings.NewReader(tc.edges))
+			require.NoError(t, err)
+			assert.Len(t, pathIDs(root), 5)
+
+			docs := pageByPath(t, root, "/docs")
+			install := pageByPath(t, root, "/docs/install")
+			contact := pageByPath(t, root, "/contact")
+			assert.Equal(t, root, docs.Parent)
+			assert.Equal(t, docs, install.Parent)
+			assert.Equal(t, root, contact.Parent)
+			// links beyond the tree are kept
+			assert.True(t, install.HasLink(docs))
+			assert.True(t, install.HasLink(contact))
+			assert.EqualValues(t, hr.WebpageTypeHub, install.Type)
+			assert.EqualValues(t, hr.WebpageTypeAuthority, contact.Type)
+			assert.Equal(t, root, pageByPath(t, root, "/orphan/page").Parent)
+		})
+	}
+}
+
+func TestImportedGraphEvolution(t *testing.T) {
+	root, err := hr.ReadURLList(strings.NewReader("/blog\n/blog/post\n"))
+	require.NoError(t, err)
+
+	// pages added to an imported graph get paths derived from their parents
+	blog := pageByPath(t, root, "/blog")
+	child := blog.AddChild(hr.WebpageTypeAuthority)
+	assert.Equal(t, "/blog/"+child.GetID(), child.GetPath())
+	assert.Len(t, pathIDs(root), 4)
+}
@@ -0,0 +1,134 @@
+package hyperrenderer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var ErrUnknownPaginationStyle = errors.New("unknown pagination style")
+
+// PaginationStyle is how the URLs of the pages of a hub are formed.
+type PaginationStyle string
+
+const (
+	// PaginationQuery appends ?page=N to the path of the hub.
+	PaginationQuery PaginationStyle = "query"
+	// PaginationPath appends /page/N to the path of the hub.
+	PaginationPath PaginationStyle = "path"
+)
+
+// PageParam is the query parameter of PaginationQuery.
+const PageParam = "page"
+
+// ParsePaginationStyle returns the pagination style named name.
+func ParsePaginationStyle(name string) (PaginationStyle, error) {
+	switch style := PaginationStyle(name); style {
+	case PaginationQuery, PaginationPath:
+		return style, nil
+	}
+	return "", fmt.Errorf("%w: %q", ErrUnknownPaginationStyle, name)
+}
+
+// WithPagination renders the links of hubs pageSize per page, newest first,
+// pages other than the first being served on URLs of style. Clones inherit
+// it.
+func WithPagination(pageSize int, style PaginationStyle) WebpageOption {
+	return func(w *Webpage) {
+		w.PageSize = pageSize
+		w.PaginationStyle = style
+	}
+}
+
+// Pagination is the position of a rendered page among the pages of a hub.
+type Pagination struct {
+	Page  int
+	Pages int
+	// Path is the URL of the page, Prev and Next the ones of the previous and
+	// next pages, empty for the first and last pages.
+	Path, Prev, Next string
+}
+
+// IsPaginated reports whether the links of the page are spread over pages.
+func (wp *Webpage) IsPaginated() bool {
+	return wp.Type == WebpageTypeHub && wp.PageSize > 0
+}
+
+// PageCount returns the number of pages of the page, 1 unless it is paginated.
+func (wp *Webpage) PageCount() int {
+	if !wp.IsPaginated() || len(wp.Links) == 0 {
+		return 1
+	}
+	return (len(wp.Links) + wp.PageSize - 1) / wp.PageSize
+}
+
+// PagePath returns the URL of page n of the page, its path for the first.
+func (wp *Webpage) PagePath(n int) string {
+	path := wp.GetPath()
+	if n <= 1 {
+		return path
+	}
+	if wp.PaginationStyle == PaginationPath {
+		pagePath, err := url.JoinPath(path, "page", strconv.Itoa(n))
+		if err != nil {
+			panic(err)
+		}
+		return pagePath
+	}
+	return fmt.Sprintf("%s?%s=%d", path, PageParam, n)
+}
+
+// PageLinks returns the links rendered on page n of the page, newest first
+// so that new links push older ones to later pages. Pages that are not
+// paginated render every link in order.
+func (wp *Webpage) PageLinks(n int) []*Webpage {
+	if !wp.IsPaginated() {
+		return wp.Links
+	}
+	links := slices.Clone(wp.Links)
+	slices.SortStableFunc(links, func(a, b *Webpage) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+	start := min((n-1)*wp.PageSize, len(links))
+	end := min(start+wp.PageSize, len(links))
+	return links[start:end]
+}
+
+// RenderPage renders page n of the page, with rel=prev and rel=next links to
+// its neighbours if it is a paginated hub.
+func (wp *Webpage) RenderPage(writer io.Writer, n int) error {
+	if !wp.IsPaginated() {
+		return wp.render(writer, wp.Links, nil)
+	}
+
+	pagination := &Pagination{Page: n, Pages: wp.PageCount(), Path: wp.PagePath(n)}
+	if n > 1 {
+		pagination.Prev = wp.PagePath(n - 1)
+	}
+	if n < pagination.Pages {
+		pagination.Next = wp.PagePath(n + 1)
+	}
+	return wp.render(writer, wp.PageLinks(n), pagination)
+}
+
+// SplitPagePath splits a URL of PaginationPath into the path of the hub and
+// the page number.
+func SplitPagePath(path string) (string, int, bool) {
+	i := strings.LastIndex(path, "/page/")
+	if i < 0 {
+		return "", 0, false
+	}
+	base, number := path[:i], path[i+len("/page/"):]
+	n, err := strconv.Atoi(number)
+	if err != nil || strconv.Itoa(n) != number {
+		return "", 0, false
+	}
+	if base == "" {
+		base = "/"
+	}
+	return base, n, true
+}
@@ -0,0 +1,128 @@
+package hyperrenderer_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	hr "github.com/sdqri/sequined/internal/hyperrenderer"
+)
+
+// newPaginatedHub returns a hub with seven children created a minute apart,
+// oldest first.
+func newPaginatedHub(style hr.PaginationStyle) (*hr.Webpage, []*hr.Webpage) {
+	root := hr.NewWebpage(hr.WebpageTypeHub, hr.WithPagination(3, style))
+	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	children := make([]*hr.Webpage, 0)
+	for i := 0; i < 7; i++ {
+		child := root.AddChild(hr.WebpageTypeAuthority)
+		child.CreatedAt = createdAt.Add(time.Duration(i) * time.Minute)
+		children = append(children, child)
+	}
+	return root, children
+}
+
+func TestPagination(t *testing.T) {
+	root, children := newPaginatedHub(hr.PaginationQuery)
+	require.True(t, root.IsPaginated())
+	assert.Equal(t, 3, root.PageCount())
+
+	tests := []struct {
+		page          int
+		expectedLinks []*hr.Webpage
+	}{
+		{1, []*hr.Webpage{children[6], children[5], children[4]}},
+		{2, []*hr.Webpage{children[3], children[2], children[1]}},
+		{3, []*hr.Webpage{children[0]}},
+		{4, []*hr.Webpage{}},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expectedLinks, root.PageLinks(tt.page), "page %d", tt.page)
+	}
+
+	// a new child pushes the oldest one to a new page
+	newest := root.AddChild(hr.WebpageTypeAuthority)
+	newest.CreatedAt = children[6].CreatedAt.Add(time.Minute)
+	assert.Equal(t, []*hr.Webpage{newest, children[6], children[5]}, root.PageLinks(1))
+	assert.Equal(t, []*hr.Webpage{children[1], children[0]}, root.PageLinks(3))
+
+	assert.False(t, children[0].IsPaginated(), "Authority pages are not paginated")
+	assert.Equal(t, 1, hr.NewWebpage(hr.WebpageTypeHub).PageCount())
+}
+
+func TestPagePath(t *testing.T) {
+	tests := []struct {
+		name         string
+		style        hr.PaginationStyle
+		page         int
+		expectedPath string
+	}{
+		{"first page", hr.PaginationQuery, 1, "/"},
+		{"query", hr.PaginationQuery, 2, "/?page=2"},
+		{"path", hr.PaginationPath, 3, "/page/3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, _ := newPaginatedHub(tt.style)
+			assert.Equal(t, tt.expectedPath, root.PagePath(tt.page))
+		})
+	}
+
+	_, children := newPaginatedHub(hr.PaginationPath)
+	hub := children[0]
+	hub.Type = hr.WebpageTypeHub
+	assert.Equal(t, hub.GetPath()+"/page/2", hub.PagePath(2))
+}
+
+func TestRenderPage(t *testing.T) {
+	root, children := newPaginatedHub(hr.PaginationPath)
+
+	var buf bytes.Buffer
+	require.NoError(t, root.RenderPage(&buf, 2))
+	body := buf.String()
+	assert.Contains(t, body, `<link rel="prev" href="/">`)
+	assert.Contains(t, body, `<link rel="next" href="/page/3">`)
+	assert.Contains(t, body, `href="`+children[3].GetPath()+`"`)
+	assert.NotContains(t, body, `href="`+children[6].GetPath()+`"`)
+
+	buf.Reset()
+	require.NoError(t, root.Render(&buf))
+	body = buf.String()
+	assert.NotContains(t, body, `rel="prev"`)
+	assert.Contains(t, body, `<link rel="next" href="/page/2">`)
+	assert.Contains(t, body, `href="`+children[6].GetPath()+`"`)
+	assert.NotContains(t, body, `href="`+children[3].GetPath()+`"`)
+
+	buf.Reset()
+	require.NoError(t, root.RenderPage(&buf, 3))
+	assert.NotContains(t, buf.String(), `rel="next"`)
+}
+
+func TestSplitPagePath(t *testing.T) {
+	tests := []struct {
+		path         string
+		expectedBase string
+		expectedPage int
+		expectedOK   bool
+	}{
+		{"/page/2", "/", 2, true},
+		{"/news/page/10", "/news", 10, true},
+		{"/news/page/", "", 0, false},
+		{"/news/page/02", "", 0, false},
+		{"/news/page/2/more", "", 0, false},
+		{"/news", "", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			base, page, ok := hr.SplitPagePath(tt.path)
+			assert.Equal(t, tt.expectedOK, ok)
+			assert.Equal(t, tt.expectedBase, base)
+			assert.Equal(t, tt.expectedPage, page)
+		})
+	}
+}
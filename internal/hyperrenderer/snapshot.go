@@ -0,0 +1,407 @@
+package hyperrenderer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// SnapshotVersion is the version of the snapshot format written by
+// WriteSnapshot.
+const SnapshotVersion = 1
+
+var (
+	ErrUnsupportedSnapshotVersion = errors.New("unsupported snapshot version")
+	ErrUnregisteredPathGenerator  = errors.New("path generator is not registered")
+	ErrInvalidSnapshot            = errors.New("invalid snapshot")
+)
+
+// Snapshot is the serialized form of a graph of webpages.
+type Snapshot struct {
+	Version int `json:"version"`
+	// Root is the ID of the root page.
+	Root  string         `json:"root"`
+	Pages []PageSnapshot `json:"pages"`
+	// IDsDrawn is how many IDs the seeded ID source of the graph drew, pages
+	// deleted since included, so that a graph built with the same seed does
+	// not draw them again. It is zero for unseeded graphs.
+	IDsDrawn uint64 `json:"ids_drawn,omitempty"`
+	// Redirects and DeletedPaths are the state a server of the graph keeps
+	// besides it, Snapshot of a webpage leaves them empty.
+	Redirects    []RedirectSnapshot    `json:"redirects,omitempty"`
+	DeletedPaths []DeletedPathSnapshot `json:"deleted_paths,omitempty"`
+
+	// pages are the pages built from the snapshot by ID, see Page.
+	pages map[string]*Webpage
+}
+
+// PageSnapshot is the serialized form of a webpage. IDs are decimal strings as
+// returned by GetID, so that JSON readers without 64-bit integers keep them.
+type PageSnapshot struct {
+	ID         string      `json:"id"`
+	Type       WebpageType `json:"type"`
+	Path       string      `json:"path,omitempty"`
+	PathPrefix string      `json:"path_prefix,omitempty"`
+	// PathGenerator is the name the path generator is registered under, empty
+	// if the page has none.
+	PathGenerator string     `json:"path_generator,omitempty"`
+	Parent        string     `json:"parent,omitempty"`
+	Links         []string   `json:"links,omitempty"`
+	Deleted       bool       `json:"deleted,omitempty"`
+	Version       uint64     `json:"version,omitempty"`
+	ChangeRate    float64    `json:"change_rate,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	ModifiedAt    *time.Time `json:"modified_at,omitempty"`
+	Traps         []*Trap    `json:"traps,omitempty"`
+	// DuplicateOf is the ID of the page the page duplicates, which may be
+	// missing from the snapshot once deleted.
+	DuplicateOf      string          `json:"duplicate_of,omitempty"`
+	DuplicateVersion uint64          `json:"duplicate_version,omitempty"`
+	Similarity       float64         `json:"similarity,omitempty"`
+	Variants         []URLVariant    `json:"variants,omitempty"`
+	CanonicalTag     bool            `json:"canonical_tag,omitempty"`
+	PageSize         int             `json:"page_size,omitempty"`
+	PaginationStyle  PaginationStyle `json:"pagination_style,omitempty"`
+}
+
+// RedirectSnapshot is a redirect left behind on the former path of a moved
+// page.
+type RedirectSnapshot struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Status int    `json:"status"`
+	// Page is the ID of the moved page.
+	Page string `json:"page"`
+}
+
+// DeletedPathSnapshot is the path a deleted page was served on.
+type DeletedPathSnapshot struct {
+	Path string `json:"path"`
+	// Page is the ID of the deleted page.
+	Page      string    `json:"page"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+var (
+	pathGeneratorsMu sync.RWMutex
+	pathGenerators   = map[string]PathGeneratorfunc{
+		"default": defaultPathGenerator,
+		"city":    CityPathGenerator,
+	}
+)
+
+// RegisterPathGenerator makes a custom path generator known to snapshots by
+// name. The default and city path generators are registered as "default" and
+// "city".
+func RegisterPathGenerator(name string, f PathGeneratorfunc) {
+	pathGeneratorsMu.Lock()
+	defer pathGeneratorsMu.Unlock()
+
+	pathGenerators[name] = f
+}
+
+func pathGeneratorName(f PathGeneratorfunc) (string, error) {
+	if f == nil {
+		return "", nil
+	}
+
+	pathGeneratorsMu.RLock()
+	defer pathGeneratorsMu.RUnlock()
+
+	// functions are not comparable, their code pointers are
+	pointer := reflect.ValueOf(f).Pointer()
+	for name, generator := range pathGenerators {
+		if reflect.ValueOf(generator).Pointer() == pointer {
+			return name, nil
+		}
+	}
+	return "", ErrUnregisteredPathGenerator
+}
+
+// Snapshot returns the snapshot of the graph reachable from wp, deleted pages
+// that are still linked included. The graph must be read locked.
+func (wp *Webpage) Snapshot() (*Snapshot, error) {
+	snapshot := &Snapshot{
+		Version: SnapshotVersion,
+		Root:    wp.GetID(),
+		Pages:   make([]PageSnapshot, 0),
+	}
+	if wp.ids != nil {
+		snapshot.IDsDrawn = wp.ids.drawn
+	}
+
+	// pages are listed in depth-first order so the same graph always results
+	// in the same snapshot
+	visited := make(map[*Webpage]struct{})
+	stack := []*Webpage{wp}
+	for len(stack) > 0 {
+		page := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if _, ok := visited[page]; ok {
+			continue
+		}
+		visited[page] = struct{}{}
+
+		pageSnapshot, err := page.snapshot()
+		if err != nil {
+			return nil, err
+		}
+		snapshot.Pages = append(snapshot.Pages, pageSnapshot)
+
+		for i := len(page.Links) - 1; i >= 0; i-- {
+			stack = append(stack, page.Links[i])
+		}
+	}
+	return snapshot, nil
+}
+
+func (wp *Webpage) snapshot() (PageSnapshot, error) {
+	pathGenerator, err := pathGeneratorName(wp.PathGenerator)
+	if err != nil {
+		return PageSnapshot{}, fmt.Errorf("page %s: %w", wp.GetID(), err)
+	}
+
+	pageSnapshot := PageSnapshot{
+		ID:            wp.GetID(),
+		Type:          wp.Type,
+		Path:          wp.Path,
+		PathPrefix:    wp.PathPrefix,
+		PathGenerator: pathGenerator,
+		Deleted:       wp.Deleted,
+		Version:       wp.Version,
+		ChangeRate:    wp.ChangeRate,
+		CreatedAt:     wp.CreatedAt,
+		Traps:         wp.Traps,
+
+		DuplicateVersion: wp.DuplicateVersion,
+		Similarity:       wp.Similarity,
+		Variants:         wp.Variants,
+		CanonicalTag:     wp.CanonicalTag,
+		PageSize:         wp.PageSize,
+		PaginationStyle:  wp.PaginationStyle,
+	}
+	if wp.DuplicateOf != nil {
+		pageSnapshot.DuplicateOf = wp.DuplicateOf.GetID()
+	}
+	if wp.Parent != nil {
+		pageSnapshot.Parent = wp.Parent.GetID()
+	}
+	for _, link := range wp.Links {
+		pageSnapshot.Links = append(pageSnapshot.Links, link.GetID())
+	}
+	if !wp.ModifiedAt.IsZero() {
+		modifiedAt := wp.ModifiedAt
+		pageSnapshot.ModifiedAt = &modifiedAt
+	}
+	return pageSnapshot, nil
+}
+
+// WriteSnapshot writes the snapshot of the graph reachable from wp as JSON.
+// The graph must be read locked.
+func (wp *Webpage) WriteSnapshot(w io.Writer) error {
+	snapshot, err := wp.Snapshot()
+	if err != nil {
+		return err
+	}
+	return snapshot.Write(w)
+}
+
+// Write writes the snapshot as JSON.
+func (snapshot *Snapshot) Write(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(snapshot)
+}
+
+// ReadSnapshot reads a graph written by WriteSnapshot and returns its root.
+// Templates are not part of snapshots, opts are applied to the root and
+// inherited by every other page like for pages created by AddChild.
+func ReadSnapshot(r io.Reader, opts ...WebpageOption) (*Webpage, error) {
+	snapshot, err := DecodeSnapshot(r)
+	if err != nil {
+		return nil, err
+	}
+	return snapshot.Build(opts...)
+}
+
+// DecodeSnapshot reads a snapshot written by WriteSnapshot without building
+// its graph, for the state besides the graph to be restored too.
+func DecodeSnapshot(r io.Reader) (*Snapshot, error) {
+	var snapshot Snapshot
+	if err := json.NewDecoder(r).Decode(&snapshot); err != nil {
+		return nil, fmt.Errorf("decoding snapshot: %w", err)
+	}
+	return &snapshot, nil
+}
+
+// Page returns the page of the given ID built by Build. Pages redirects and
+// deleted paths refer to but missing from the graph are built as deleted
+// pages.
+func (snapshot *Snapshot) Page(id string) (*Webpage, bool) {
+	page, ok := snapshot.pages[id]
+	return page, ok
+}
+
+// Build reconstructs the graph of the snapshot and returns its root, see
+// ReadSnapshot.
+func (snapshot *Snapshot) Build(opts ...WebpageOption) (*Webpage, error) {
+	if snapshot.Version != SnapshotVersion {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedSnapshotVersion, snapshot.Version)
+	}
+
+	var root *Webpage
+	pages := make(map[string]*Webpage, len(snapshot.Pages))
+	for _, pageSnapshot := range snapshot.Pages {
+		if pageSnapshot.ID != snapshot.Root {
+			continue
+		}
+		root = NewWebpage(pageSnapshot.Type, opts...)
+		if err := root.restore(pageSnapshot); err != nil {
+			return nil, err
+		}
+		pages[pageSnapshot.ID] = root
+	}
+	if root == nil {
+		return nil, fmt.Errorf("%w: root page %q missing", ErrInvalidSnapshot, snapshot.Root)
+	}
+
+	for _, pageSnapshot := range snapshot.Pages {
+		if pageSnapshot.ID == snapshot.Root {
+			continue
+		}
+		if _, ok := pages[pageSnapshot.ID]; ok {
+			return nil, fmt.Errorf("%w: duplicate page %s", ErrInvalidSnapshot, pageSnapshot.ID)
+		}
+		// clones share the templates, lock and ID source of the root
+		page := root.Clone(pageSnapshot.Type)
+		if err := page.restore(pageSnapshot); err != nil {
+			return nil, err
+		}
+		pages[pageSnapshot.ID] = page
+	}
+
+	for _, pageSnapshot := range snapshot.Pages {
+		page := pages[pageSnapshot.ID]
+		if pageSnapshot.Parent != "" {
+			parent, ok := pages[pageSnapshot.Parent]
+			if !ok {
+				return nil, fmt.Errorf("%w: page %s has unknown parent %s", ErrInvalidSnapshot, pageSnapshot.ID, pageSnapshot.Parent)
+			}
+			page.Parent = parent
+		}
+		for _, linkID := range pageSnapshot.Links {
+			link, ok := pages[linkID]
+			if !ok {
+				return nil, fmt.Errorf("%w: page %s links unknown page %s", ErrInvalidSnapshot, pageSnapshot.ID, linkID)
+			}
+			page.Links = append(page.Links, link)
+		}
+		if pageSnapshot.DuplicateOf != "" {
+			// the duplicated page may be deleted, its ID is all the content
+			// of the duplicate derives from
+			original, err := deletedPage(root, pages, pageSnapshot.DuplicateOf)
+			if err != nil {
+				return nil, err
+			}
+			page.DuplicateOf = original
+		}
+	}
+
+	for _, redirect := range snapshot.Redirects {
+		if _, err := deletedPage(root, pages, redirect.Page); err != nil {
+			return nil, err
+		}
+	}
+	for _, deletedPath := range snapshot.DeletedPaths {
+		if _, err := deletedPage(root, pages, deletedPath.Page); err != nil {
+			return nil, err
+		}
+	}
+	snapshot.pages = pages
+
+	// building drew IDs of its own, the pages of the snapshot having theirs
+	if root.ids != nil && snapshot.IDsDrawn > 0 {
+		root.ids.seek(snapshot.IDsDrawn)
+	}
+
+	// paths are derived from parents, their chains must end
+	for _, page := range pages {
+		steps := 0
+		for current := page; current != nil; current = current.Parent {
+			if steps > len(pages) {
+				return nil, fmt.Errorf("%w: page %s has cyclic parents", ErrInvalidSnapshot, page.GetID())
+			}
+			steps++
+		}
+	}
+	return root, nil
+}
+
+// deletedPage returns the page of the given ID, building it as a deleted page
+// cloned from root if it is missing from the snapshot.
+func deletedPage(root *Webpage, pages map[string]*Webpage, id string) (*Webpage, error) {
+	if page, ok := pages[id]; ok {
+		return page, nil
+	}
+	page := root.Clone(WebpageTypeAuthority)
+	if err := page.restore(PageSnapshot{ID: id, Type: WebpageTypeAuthority, Deleted: true}); err != nil {
+		return nil, err
+	}
+	pages[id] = page
+	return page, nil
+}
+
+func (wp *Webpage) restore(pageSnapshot PageSnapshot) error {
+	id, err := strconv.ParseUint(pageSnapshot.ID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: page ID %q: %w", ErrInvalidSnapshot, pageSnapshot.ID, err)
+	}
+	switch pageSnapshot.Type {
+	case WebpageTypeHub, WebpageTypeAuthority:
+	default:
+		return fmt.Errorf("%w: page %s has unknown type %q", ErrInvalidSnapshot, pageSnapshot.ID, pageSnapshot.Type)
+	}
+
+	var pathGenerator PathGeneratorfunc
+	if pageSnapshot.PathGenerator != "" {
+		pathGeneratorsMu.RLock()
+		generator, ok := pathGenerators[pageSnapshot.PathGenerator]
+		pathGeneratorsMu.RUnlock()
+		if !ok {
+			return fmt.Errorf("page %s: %w: %q", pageSnapshot.ID, ErrUnregisteredPathGenerator, pageSnapshot.PathGenerator)
+		}
+		pathGenerator = generator
+	}
+
+	wp.ID = id
+	wp.Path = pageSnapshot.Path
+	wp.PathPrefix = pageSnapshot.PathPrefix
+	wp.PathGenerator = pathGenerator
+	wp.Deleted = pageSnapshot.Deleted
+	wp.Version = pageSnapshot.Version
+	wp.ChangeRate = pageSnapshot.ChangeRate
+	wp.CreatedAt = pageSnapshot.CreatedAt
+	wp.ModifiedAt = time.Time{}
+	if pageSnapshot.ModifiedAt != nil {
+		wp.ModifiedAt = *pageSnapshot.ModifiedAt
+	}
+	if pageSnapshot.Traps != nil {
+		wp.Traps = pageSnapshot.Traps
+	}
+	wp.DuplicateVersion = pageSnapshot.DuplicateVersion
+	wp.Similarity = pageSnapshot.Similarity
+	wp.Variants = pageSnapshot.Variants
+	if pageSnapshot.CanonicalTag {
+		wp.CanonicalTag = true
+	}
+	if pageSnapshot.PageSize > 0 {
+		wp.PageSize = pageSnapshot.PageSize
+		wp.PaginationStyle = pageSnapshot.PaginationStyle
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package hyperrenderer_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	hr "github.com/sdqri/sequined/internal/hyperrenderer"
+)
+
+func newSnapshotGraph() *hr.Webpage {
+	root := hr.NewWebpage(hr.WebpageTypeHub, hr.WithSeed(1), hr.WithPathPrefix("/site"))
+	hub := root.AddChild(hr.WebpageTypeHub)
+	authority := hub.AddChild(hr.WebpageTypeAuthority)
+	authority.ChangeRate = 1.5
+	authority.Modify(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	city := root.AddChild(hr.WebpageTypeHub, hr.WithPathGenerator(hr.CityPathGenerator))
+	city.AddChild(hr.WebpageTypeAuthority)
+	// a dangling link
+	deleted := root.AddChild(hr.WebpageTypeAuthority)
+	deleted.Deleted = true
+	return root
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	root := newSnapshotGraph()
+
+	var buf bytes.Buffer
+	require.NoError(t, root.WriteSnapshot(&buf))
+
+	loaded, err := hr.ReadSnapshot(bytes.NewReader(buf.Bytes()), hr.WithSeed(1))
+	require.NoError(t, err)
+
+	expected, err := root.Snapshot()
+	require.NoError(t, err)
+	actual, err := loaded.Snapshot()
+	require.NoError(t, err)
+	assert.Equal(t, expected, actual)
+	assert.Len(t, actual.Pages, 6)
+
+	assert.Equal(t, pathIDs(root), pathIDs(loaded))
+
+	expectedPage := root.Links[0].Links[0]
+	actualPage := loaded.Links[0].Links[0]
+	var expectedHTML, actualHTML bytes.Buffer
+	require.NoError(t, expectedPage.Render(&expectedHTML))
+	require.NoError(t, actualPage.Render(&actualHTML))
+	assert.Equal(t, expectedHTML.String(), actualHTML.String())
+	assert.Equal(t, expectedPage.ETag(), actualPage.ETag())
+	assert.Equal(t, expectedPage.LastModified(), actualPage.LastModified())
+}
+
+func pathIDs(root hr.HyperRenderer) map[string]string {
+	ids := make(map[string]string)
+	for path, page := range hr.CreatePathMap(root) {
+		ids[path] = page.GetID()
+	}
+	return ids
+}
+
+func TestReadSnapshotErrors(t *testing.T) {
+	testCases := []struct {
+		description string
+		snapshot    string
+		expectedErr error
+	}{
+		{
+			description: "unsupported version",
+			snapshot:    `{"version": 2, "root": "1", "pages": [{"id": "1", "type": "hub"}]}`,
+			expectedErr: hr.ErrUnsupportedSnapshotVersion,
+		},
+		{
+			description: "missing root",
+			snapshot:    `{"version": 1, "root": "1", "pages": [{"id": "2", "type": "hub"}]}`,
+			expectedErr: hr.ErrInvalidSnapshot,
+		},
+		{
+			description: "unknown link",
+			snapshot:    `{"version": 1, "root": "1", "pages": [{"id": "1", "type": "hub", "links": ["2"]}]}`,
+			expectedErr: hr.ErrInvalidSnapshot,
+		},
+		{
+			description: "duplicate page",
+			snapshot:    `{"version": 1, "root": "1", "pages": [{"id": "1", "type": "hub"}, {"id": "2", "type": "hub"}, {"id": "2", "type": "hub"}]}`,
+			expectedErr: hr.ErrInvalidSnapshot,
+		},
+		{
+			description: "unknown type",
+			snapshot:    `{"version": 1, "root": "1", "pages": [{"id": "1", "type": "spam"}]}`,
+			expectedErr: hr.ErrInvalidSnapshot,
+		},
+		{
+			description: "unregistered path generator",
+			snapshot:    `{"version": 1, "root": "1", "pages": [{"id": "1", "type": "hub", "path_generator": "unknown"}]}`,
+			expectedErr: hr.ErrUnregisteredPathGenerator,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			_, err := hr.ReadSnapshot(strings.NewReader(tc.snapshot))
+			assert.ErrorIs(t, err, tc.expectedErr)
+		})
+	}
+}
+
+func TestSnapshotPathGenerators(t *testing.T) {
+	root := hr.NewWebpage(hr.WebpageTypeHub)
+	root.AddChild(hr.WebpageTypeAuthority, hr.WithPathGenerator(func(*hr.Webpage) string { return "/custom" }))
+
+	_, err := root.Snapshot()
+	require.ErrorIs(t, err, hr.ErrUnregisteredPathGenerator)
+
+	custom := func(*hr.Webpage) string { return "/registered" }
+	hr.RegisterPathGenerator("registered", custom)
+	root.Links[0].PathGenerator = custom
+
+	var buf bytes.Buffer
+	require.NoError(t, root.WriteSnapshot(&buf))
+	loaded, err := hr.ReadSnapshot(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, "/registered", loaded.Links[0].GetPath())
+}
+
+func TestSnapshotIDsAfterDeletion(t *testing.T) {
+	root := newSnapshotGraph()
+	drawn := make(map[string]struct{})
+	hr.Traverse(root, func(currentRenderer hr.HyperRenderer) bool {
+		drawn[currentRenderer.GetID()] = struct{}{}
+		return false
+	})
+	// pages deleted and unlinked are missing from the snapshot
+	for i := 0; i < 3; i++ {
+		child := root.AddChild(hr.WebpageTypeAuthority)
+		drawn[child.GetID()] = struct{}{}
+		root.RemoveLink(child)
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, root.WriteSnapshot(&buf))
+	loaded, err := hr.ReadSnapshot(&buf, hr.WithSeed(1))
+	require.NoError(t, err)
+
+	for i := 0; i < 5; i++ {
+		child := loaded.AddChild(hr.WebpageTypeAuthority)
+		assert.NotContains(t, drawn, child.GetID())
+		drawn[child.GetID()] = struct{}{}
+	}
+}
@@ -0,0 +1,329 @@
+package hyperrenderer
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"html/template"
+	"io"
+	"math/rand"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var _ HyperRenderer = &TrapPage{}
+
+var ErrUnknownTrapType = errors.New("unknown trap type")
+
+type TrapType string
+
+const (
+	// TrapTypeCalendar is a calendar of months and days linking the previous
+	// and next ones without end.
+	TrapTypeCalendar TrapType = "calendar"
+	// TrapTypeSessionID is a handful of pages whose links all carry a new
+	// session ID, making every URL unique.
+	TrapTypeSessionID TrapType = "session-id"
+	// TrapTypeRecursion is a path that deepens with every link.
+	TrapTypeRecursion TrapType = "recursion"
+	// TrapTypeFacets is a faceted search linking every combination of filters
+	// and sort orders in its query string.
+	TrapTypeFacets TrapType = "facets"
+)
+
+// Trap is a subgraph of pages generated on demand under Prefix to test how
+// crawlers avoid spider traps. Trap pages are not part of the graph of
+// webpages, they are resolved from the requested URL by Resolve.
+type Trap struct {
+	Type   TrapType `json:"type"`
+	Prefix string   `json:"prefix"`
+}
+
+// NewTrap returns a trap of the given type under prefix, "/" followed by the
+// type if empty.
+func NewTrap(trapType TrapType, prefix string) (*Trap, error) {
+	switch trapType {
+	case TrapTypeCalendar, TrapTypeSessionID, TrapTypeRecursion, TrapTypeFacets:
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownTrapType, trapType)
+	}
+	if prefix == "" {
+		prefix = "/" + string(trapType)
+	}
+	return &Trap{Type: trapType, Prefix: strings.TrimSuffix(prefix, "/")}, nil
+}
+
+// Title is the text of the links to the entry page of the trap.
+func (trap *Trap) Title() string {
+	switch trap.Type {
+	case TrapTypeCalendar:
+		return "Events calendar"
+	case TrapTypeSessionID:
+		return "Shop"
+	case TrapTypeRecursion:
+		return "Archive"
+	default:
+		return "Search"
+	}
+}
+
+// trapLink is a link of a trap page.
+type trapLink struct {
+	Path  string
+	Title string
+}
+
+// TrapPage is a page of a trap. Its links are resolved lazily and never end,
+// traversing them does not terminate.
+type TrapPage struct {
+	Trap  *Trap
+	URL   string
+	Title string
+	// key identifies the content of the page, URL without session ID.
+	key   string
+	links []trapLink
+	// now and rng are what the page was resolved with, its links are
+	// resolved with them too.
+	now time.Time
+	rng *rand.Rand
+}
+
+// Resolve returns the trap page served on u at the time now, if any. Session
+// IDs are drawn from rng, the global source if nil, so that a seeded rng and
+// the clock of the simulation make trap pages reproducible.
+func (trap *Trap) Resolve(u *url.URL, now time.Time, rng *rand.Rand) (*TrapPage, bool) {
+	page, ok := trap.resolve(u, now, rng)
+	if ok {
+		page.now, page.rng = now, rng
+	}
+	return page, ok
+}
+
+func (trap *Trap) resolve(u *url.URL, now time.Time, rng *rand.Rand) (*TrapPage, bool) {
+	if u.Path != trap.Prefix && !strings.HasPrefix(u.Path, trap.Prefix+"/") {
+		return nil, false
+	}
+	rest := strings.Trim(strings.TrimPrefix(u.Path, trap.Prefix), "/")
+
+	switch trap.Type {
+	case TrapTypeCalendar:
+		return trap.resolveCalendar(rest, now)
+	case TrapTypeSessionID:
+		return trap.resolveSessionID(rest, u.Query().Get(sessionIDParam), rng)
+	case TrapTypeRecursion:
+		return trap.resolveRecursion(rest)
+	case TrapTypeFacets:
+		return trap.resolveFacets(rest, u.Query())
+	}
+	return nil, false
+}
+
+func (trap *Trap) resolveCalendar(rest string, now time.Time) (*TrapPage, bool) {
+	monthPath := func(month time.Time) string {
+		return fmt.Sprintf("%s/%s", trap.Prefix, month.Format("2006/01"))
+	}
+	dayPath := func(day time.Time) string {
+		return fmt.Sprintf("%s/%s", trap.Prefix, day.Format("2006/01/02"))
+	}
+	monthLink := func(month time.Time) trapLink {
+		return trapLink{Path: monthPath(month), Title: month.Format("January 2006")}
+	}
+
+	if rest == "" {
+		today := now.UTC()
+		month := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return &TrapPage{Trap: trap, URL: trap.Prefix, Title: trap.Title(), links: []trapLink{monthLink(month)}}, true
+	}
+
+	if day, err := time.Parse("2006/01/02", rest); err == nil {
+		previous, next := day.AddDate(0, 0, -1), day.AddDate(0, 0, 1)
+		return &TrapPage{Trap: trap, URL: dayPath(day), Title: day.Format("Monday, January 2, 2006"), links: []trapLink{
+			{Path: dayPath(previous), Title: previous.Format("January 2, 2006")},
+			{Path: dayPath(next), Title: next.Format("January 2, 2006")},
+			monthLink(day),
+		}}, true
+	}
+
+	month, err := time.Parse("2006/01", rest)
+	if err != nil {
+		return nil, false
+	}
+	links := []trapLink{monthLink(month.AddDate(0, -1, 0)), monthLink(month.AddDate(0, 1, 0))}
+	for day := month; day.Month() == month.Month(); day = day.AddDate(0, 0, 1) {
+		links = append(links, trapLink{Path: dayPath(day), Title: day.Format("January 2")})
+	}
+	return &TrapPage{Trap: trap, URL: monthPath(month), Title: month.Format("January 2006"), links: links}, true
+}
+
+// sessionIDItems is the number of distinct pages of a session ID trap.
+const sessionIDItems = 5
+
+// sessionIDParam is the query parameter carrying session IDs, it is ignored
+// when resolving pages.
+const sessionIDParam = "sid"
+
+func (trap *Trap) resolveSessionID(rest, sessionID string, rng *rand.Rand) (*TrapPage, bool) {
+	title := trap.Title()
+	if rest != "" {
+		item, ok := strings.CutPrefix(rest, "item/")
+		if !ok {
+			return nil, false
+		}
+		n, err := strconv.Atoi(item)
+		if err != nil || n < 1 || n > sessionIDItems || strconv.Itoa(n) != item {
+			return nil, false
+		}
+		title = fmt.Sprintf("Item %d", n)
+	}
+
+	// links carry a session ID drawn anew on every resolution, like sessions
+	// of a server that does not keep them
+	var id uint64
+	if rng != nil {
+		id = rng.Uint64()
+	} else {
+		id = rand.Uint64()
+	}
+	newSessionID := strconv.FormatUint(id, 36)
+	links := make([]trapLink, 0, sessionIDItems+1)
+	links = append(links, trapLink{Path: fmt.Sprintf("%s?%s=%s", trap.Prefix, sessionIDParam, newSessionID), Title: trap.Title()})
+	for n := 1; n <= sessionIDItems; n++ {
+		links = append(links, trapLink{
+			Path:  fmt.Sprintf("%s/item/%d?%s=%s", trap.Prefix, n, sessionIDParam, newSessionID),
+			Title: fmt.Sprintf("Item %d", n),
+		})
+	}
+
+	path := trap.Prefix
+	if rest != "" {
+		path += "/" + rest
+	}
+	pageURL := path
+	if sessionID != "" {
+		pageURL += "?" + url.Values{sessionIDParam: {sessionID}}.Encode()
+	}
+	return &TrapPage{Trap: trap, URL: pageURL, Title: title, key: path, links: links}, true
+}
+
+// recursionSegments are the path segments every page of a recursion trap
+// links to below its own path.
+var recursionSegments = []string{"archive", "related", "more"}
+
+func (trap *Trap) resolveRecursion(rest string) (*TrapPage, bool) {
+	path := trap.Prefix
+	if rest != "" {
+		path += "/" + rest
+	}
+
+	links := make([]trapLink, 0, len(recursionSegments))
+	for _, segment := range recursionSegments {
+		links = append(links, trapLink{Path: path + "/" + segment, Title: strings.ToUpper(segment[:1]) + segment[1:]})
+	}
+	return &TrapPage{Trap: trap, URL: path, Title: trap.Title(), links: links}, true
+}
+
+// facets are the filters of a facets trap and their values.
+var facets = map[string][]string{
+	"brand": {"acme", "globex", "initech", "umbrella"},
+	"color": {"black", "blue", "green", "red", "white"},
+	"price": {"0-10", "10-50", "50-100", "100-500"},
+	"size":  {"xs", "s", "m", "l", "xl"},
+	"sort":  {"newest", "price-asc", "price-desc", "rating"},
+}
+
+func (trap *Trap) resolveFacets(rest string, query url.Values) (*TrapPage, bool) {
+	if rest != "" {
+		return nil, false
+	}
+
+	// unknown parameters are kept, as search pages usually do
+	current := make(url.Values, len(query))
+	for name, values := range query {
+		current[name] = values[:1]
+	}
+	pageURL := func(values url.Values) string {
+		if len(values) == 0 {
+			return trap.Prefix
+		}
+		return trap.Prefix + "?" + values.Encode()
+	}
+
+	names := make([]string, 0, len(facets))
+	for name := range facets {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	links := make([]trapLink, 0)
+	for _, name := range names {
+		for _, value := range facets[name] {
+			if current.Get(name) == value {
+				continue
+			}
+			values := cloneValues(current)
+			values.Set(name, value)
+			links = append(links, trapLink{Path: pageURL(values), Title: fmt.Sprintf("%s: %s", name, value)})
+		}
+		if current.Has(name) {
+			values := cloneValues(current)
+			values.Del(name)
+			links = append(links, trapLink{Path: pageURL(values), Title: fmt.Sprintf("Any %s", name)})
+		}
+	}
+	return &TrapPage{Trap: trap, URL: pageURL(current), Title: trap.Title(), links: links}, true
+}
+
+func cloneValues(values url.Values) url.Values {
+	clone := make(url.Values, len(values))
+	for name, value := range values {
+		clone[name] = slices.Clone(value)
+	}
+	return clone
+}
+
+// GetID returns a hash of the URL of the page, session ID aside, so that the
+// same URL always resolves to the same ID.
+func (page *TrapPage) GetID() string {
+	key := page.key
+	if key == "" {
+		key = page.URL
+	}
+	hash := fnv.New64a()
+	io.WriteString(hash, key)
+	return strconv.FormatUint(hash.Sum64(), 10)
+}
+
+// GetPath returns the URL of the page, query string included.
+func (page *TrapPage) GetPath() string {
+	return page.URL
+}
+
+func (page *TrapPage) GetLinks() []HyperRenderer {
+	links := make([]HyperRenderer, 0, len(page.links))
+	for _, link := range page.links {
+		u, err := url.Parse(link.Path)
+		if err != nil {
+			continue
+		}
+		if linked, ok := page.Trap.Resolve(u, page.now, page.rng); ok {
+			links = append(links, linked)
+		}
+	}
+	return links
+}
+
+var trapTmpl = template.Must(template.ParseFS(templateFS, "templates/trap.html.tmpl"))
+
+func (page *TrapPage) Render(writer io.Writer) error {
+	data := struct {
+		Title string
+		Links []trapLink
+	}{
+		Title: page.Title,
+		Links: page.links,
+	}
+	return trapTmpl.Execute(writer, data)
+}
@@ -0,0 +1,178 @@
+package hyperrenderer_test
+
+import (
+	"bytes"
+	"math/rand"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	hr "github.com/sdqri/sequined/internal/hyperrenderer"
+)
+
+func resolveTrap(t *testing.T, trap *hr.Trap, rawURL string) (*hr.TrapPage, bool) {
+	t.Helper()
+	u, err := url.Parse(rawURL)
+	require.NoError(t, err)
+	return trap.Resolve(u, time.Now(), nil)
+}
+
+func TestNewTrap(t *testing.T) {
+	trap, err := hr.NewTrap(hr.TrapTypeCalendar, "")
+	require.NoError(t, err)
+	assert.Equal(t, "/calendar", trap.Prefix)
+
+	trap, err = hr.NewTrap(hr.TrapTypeFacets, "/search/")
+	require.NoError(t, err)
+	assert.Equal(t, "/search", trap.Prefix)
+
+	_, err = hr.NewTrap("maze", "")
+	assert.ErrorIs(t, err, hr.ErrUnknownTrapType)
+}
+
+func TestTrapResolve(t *testing.T) {
+	tests := []struct {
+		name          string
+		trapType      hr.TrapType
+		url           string
+		expectedOK    bool
+		expectedLinks int
+	}{
+		{"calendar entry", hr.TrapTypeCalendar, "/trap", true, 1},
+		{"calendar month", hr.TrapTypeCalendar, "/trap/2024/02", true, 2 + 29},
+		{"calendar day", hr.TrapTypeCalendar, "/trap/2024/02/29", true, 3},
+		{"calendar far future", hr.TrapTypeCalendar, "/trap/2999/12", true, 2 + 31},
+		{"calendar invalid day", hr.TrapTypeCalendar, "/trap/2023/02/29", false, 0},
+		{"calendar invalid path", hr.TrapTypeCalendar, "/trap/events", false, 0},
+		{"session id entry", hr.TrapTypeSessionID, "/trap?sid=abc", true, 6},
+		{"session id item", hr.TrapTypeSessionID, "/trap/item/3?sid=abc", true, 6},
+		{"session id unknown item", hr.TrapTypeSessionID, "/trap/item/6", false, 0},
+		{"recursion entry", hr.TrapTypeRecursion, "/trap", true, 3},
+		{"recursion deep", hr.TrapTypeRecursion, "/trap/more/archive/more/related", true, 3},
+		{"facets entry", hr.TrapTypeFacets, "/trap", true, 22},
+		{"facets filtered", hr.TrapTypeFacets, "/trap?color=red&size=m", true, 22},
+		{"facets subpath", hr.TrapTypeFacets, "/trap/shoes", false, 0},
+		{"outside prefix", hr.TrapTypeRecursion, "/trapdoor", false, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trap, err := hr.NewTrap(tt.trapType, "/trap")
+			require.NoError(t, err)
+
+			page, ok := resolveTrap(t, trap, tt.url)
+			require.Equal(t, tt.expectedOK, ok)
+			if !ok {
+				return
+			}
+			links := page.GetLinks()
+			assert.Len(t, links, tt.expectedLinks)
+			for _, link := range links {
+				assert.True(t, strings.HasPrefix(link.GetPath(), "/trap"), "Trap links out of the trap")
+			}
+
+			var buf bytes.Buffer
+			require.NoError(t, page.Render(&buf))
+			for _, link := range links {
+				assert.Contains(t, buf.String(), `href="`+strings.ReplaceAll(link.GetPath(), "&", "&amp;")+`"`)
+			}
+		})
+	}
+}
+
+func TestTrapsNeverEnd(t *testing.T) {
+	for _, trapType := range []hr.TrapType{hr.TrapTypeCalendar, hr.TrapTypeSessionID, hr.TrapTypeRecursion, hr.TrapTypeFacets} {
+		t.Run(string(trapType), func(t *testing.T) {
+			trap, err := hr.NewTrap(trapType, "")
+			require.NoError(t, err)
+
+			page, ok := resolveTrap(t, trap, trap.Prefix)
+			require.True(t, ok)
+
+			// a breadth-first crawl keeps finding URLs it has not seen
+			seen := map[string]struct{}{page.GetPath(): {}}
+			queue := []hr.HyperRenderer{page}
+			for len(seen) < 1000 && len(queue) > 0 {
+				current := queue[0]
+				queue = queue[1:]
+				for _, link := range current.GetLinks() {
+					if _, ok := seen[link.GetPath()]; !ok {
+						seen[link.GetPath()] = struct{}{}
+						queue = append(queue, link)
+					}
+				}
+			}
+			assert.GreaterOrEqual(t, len(seen), 1000)
+		})
+	}
+}
+
+func TestTrapPageIDs(t *testing.T) {
+	trap, err := hr.NewTrap(hr.TrapTypeFacets, "")
+	require.NoError(t, err)
+
+	page, ok := resolveTrap(t, trap, "/facets?size=m&color=red")
+	require.True(t, ok)
+	same, ok := resolveTrap(t, trap, "/facets?color=red&size=m")
+	require.True(t, ok)
+	other, ok := resolveTrap(t, trap, "/facets?color=blue&size=m")
+	require.True(t, ok)
+
+	assert.Equal(t, "/facets?color=red&size=m", page.GetPath())
+	assert.Equal(t, page.GetID(), same.GetID())
+	assert.NotEqual(t, page.GetID(), other.GetID())
+}
+
+func TestSessionIDTrapLinksAreUnique(t *testing.T) {
+	trap, err := hr.NewTrap(hr.TrapTypeSessionID, "")
+	require.NoError(t, err)
+
+	first, ok := resolveTrap(t, trap, "/session-id/item/1")
+	require.True(t, ok)
+	second, ok := resolveTrap(t, trap, "/session-id/item/1")
+	require.True(t, ok)
+	withSessionID, ok := resolveTrap(t, trap, "/session-id/item/1?sid=abc")
+	require.True(t, ok)
+	assert.Equal(t, "/session-id/item/1?sid=abc", withSessionID.GetPath())
+	assert.Equal(t, first.GetID(), withSessionID.GetID(), "Session IDs must not change the page")
+	assert.NotEqual(t, hr.GetPaths(first), hr.GetPaths(second), "Session IDs should be drawn anew")
+}
+
+func TestTrapsAreReproducible(t *testing.T) {
+	calendar, err := hr.NewTrap(hr.TrapTypeCalendar, "")
+	require.NoError(t, err)
+	page, ok := calendar.Resolve(&url.URL{Path: "/calendar"}, time.Date(2024, 2, 10, 0, 0, 0, 0, time.UTC), nil)
+	require.True(t, ok)
+	assert.Equal(t, []string{"/calendar/2024/02"}, hr.GetPaths(page))
+
+	sessionID, err := hr.NewTrap(hr.TrapTypeSessionID, "")
+	require.NoError(t, err)
+	resolve := func() *hr.TrapPage {
+		page, ok := sessionID.Resolve(&url.URL{Path: "/session-id"}, time.Now(), rand.New(rand.NewSource(1)))
+		require.True(t, ok)
+		return page
+	}
+	assert.Equal(t, hr.GetPaths(resolve()), hr.GetPaths(resolve()))
+}
+
+func TestWebpageLinksTraps(t *testing.T) {
+	trap, err := hr.NewTrap(hr.TrapTypeCalendar, "")
+	require.NoError(t, err)
+	root := hr.NewWebpage(hr.WebpageTypeHub, hr.WithTraps(trap))
+	child := root.AddChild(hr.WebpageTypeAuthority)
+
+	var buf bytes.Buffer
+	require.NoError(t, root.Render(&buf))
+	assert.Contains(t, buf.String(), `href="/calendar"`)
+	assert.Empty(t, child.Traps, "Clones should not inherit traps")
+
+	buf.Reset()
+	require.NoError(t, root.WriteSnapshot(&buf))
+	restored, err := hr.ReadSnapshot(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, []*hr.Trap{trap}, restored.Traps)
+}
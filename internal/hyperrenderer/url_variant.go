@@ -0,0 +1,71 @@
+package hyperrenderer
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrUnknownURLVariant = errors.New("unknown URL variant")
+
+// URLVariant is a URL serving the same content as the path of a page.
+type URLVariant string
+
+const (
+	// URLVariantTrailingSlash is the path followed by a slash.
+	URLVariantTrailingSlash URLVariant = "trailing-slash"
+	// URLVariantQuery is the path followed by tracking query parameters.
+	URLVariantQuery URLVariant = "query"
+	// URLVariantCase is the path in upper case.
+	URLVariantCase URLVariant = "case"
+	// URLVariantNormalized is any other URL a server normalizes to the path,
+	// e.g. with percent-encoded characters. It is never generated.
+	URLVariantNormalized URLVariant = "normalized"
+)
+
+// URLVariants are the URL variants pages can be given.
+var URLVariants = []URLVariant{URLVariantTrailingSlash, URLVariantQuery, URLVariantCase}
+
+// variantQuery is the query string of URLVariantQuery.
+const variantQuery = "utm_source=sequined&utm_medium=referral"
+
+// ParseURLVariant returns the URL variant named name.
+func ParseURLVariant(name string) (URLVariant, error) {
+	for _, variant := range URLVariants {
+		if string(variant) == name {
+			return variant, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %q", ErrUnknownURLVariant, name)
+}
+
+// VariantPath returns the URL of the variant of the page path. It reports
+// false if the variant would not differ from the path, e.g. the upper case
+// variant of a path without letters.
+func (wp *Webpage) VariantPath(variant URLVariant) (string, bool) {
+	path := wp.GetPath()
+	switch variant {
+	case URLVariantTrailingSlash:
+		if strings.HasSuffix(path, "/") {
+			return "", false
+		}
+		return path + "/", true
+	case URLVariantQuery:
+		return path + "?" + variantQuery, true
+	case URLVariantCase:
+		upper := strings.ToUpper(path)
+		return upper, upper != path
+	}
+	return "", false
+}
+
+// VariantPaths returns the URLs of the variants of the page, see Variants.
+func (wp *Webpage) VariantPaths() []string {
+	paths := make([]string, 0, len(wp.Variants))
+	for _, variant := range wp.Variants {
+		if path, ok := wp.VariantPath(variant); ok {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
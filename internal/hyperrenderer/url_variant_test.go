@@ -0,0 +1,63 @@
+package hyperrenderer_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	hr "github.com/sdqri/sequined/internal/hyperrenderer"
+)
+
+func TestVariantPath(t *testing.T) {
+	root := hr.NewWebpage(hr.WebpageTypeHub)
+	child := root.AddChild(hr.WebpageTypeAuthority, hr.WithPathGenerator(func(*hr.Webpage) string { return "/news/Paris" }))
+
+	tests := []struct {
+		name         string
+		webpage      *hr.Webpage
+		variant      hr.URLVariant
+		expectedPath string
+		expectedOK   bool
+	}{
+		{"trailing slash", child, hr.URLVariantTrailingSlash, "/news/Paris/", true},
+		{"trailing slash of root", root, hr.URLVariantTrailingSlash, "", false},
+		{"query", child, hr.URLVariantQuery, "/news/Paris?utm_source=sequined&utm_medium=referral", true},
+		{"case", child, hr.URLVariantCase, "/NEWS/PARIS", true},
+		{"case without letters", root, hr.URLVariantCase, "/", false},
+		{"unknown", child, "fragment", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, ok := tt.webpage.VariantPath(tt.variant)
+			assert.Equal(t, tt.expectedOK, ok)
+			if ok {
+				assert.Equal(t, tt.expectedPath, path)
+			}
+		})
+	}
+}
+
+func TestParseURLVariant(t *testing.T) {
+	variant, err := hr.ParseURLVariant("trailing-slash")
+	require.NoError(t, err)
+	assert.Equal(t, hr.URLVariantTrailingSlash, variant)
+
+	_, err = hr.ParseURLVariant("fragment")
+	assert.ErrorIs(t, err, hr.ErrUnknownURLVariant)
+}
+
+func TestVariantsAreLinked(t *testing.T) {
+	root := hr.NewWebpage(hr.WebpageTypeHub)
+	child := root.AddChild(hr.WebpageTypeAuthority)
+	child.Variants = []hr.URLVariant{hr.URLVariantTrailingSlash, hr.URLVariantCase}
+	grandChild := child.AddChild(hr.WebpageTypeAuthority)
+	assert.Empty(t, grandChild.Variants, "Clones should not inherit variants")
+
+	var buf bytes.Buffer
+	require.NoError(t, root.Render(&buf))
+	assert.Contains(t, buf.String(), `href="`+child.GetPath()+`/"`)
+	assert.Equal(t, []string{child.GetPath() + "/"}, child.VariantPaths(), "Paths without letters have no case variant")
+}
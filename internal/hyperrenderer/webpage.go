@@ -3,11 +3,14 @@ package hyperrenderer
 import (
 	"embed"
 	"fmt"
+	"hash/fnv"
 	"html/template"
 	"io"
 	"math/rand"
-	"net/url"
+	"path"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/brianvoe/gofakeit/v7"
 	"github.com/goccy/go-graphviz"
@@ -29,14 +32,81 @@ type Webpage struct {
 	ID         uint64
 	Path       string
 	PathPrefix string
-	Parent     *Webpage
-	Links      []*Webpage
-	Type       WebpageType
+	// Parent is the page the path of the page derives from, see AddLink.
+	// Parents form a tree spanning the graph while Links may form any
+	// directed graph.
+	Parent *Webpage
+	Links  []*Webpage
+	Type   WebpageType
+	// Deleted marks a page removed from the graph. Links pointing to a deleted
+	// page dangle: they are still rendered but skipped by GetLinks.
+	Deleted bool
+	// Version is the content version of the page, it seeds the faker so every
+	// modification changes the rendered content.
+	Version uint64
+	// ChangeRate is the rate of content modifications in changes per hour.
+	ChangeRate float64
+	CreatedAt  time.Time
+	// ModifiedAt is the time of the last content modification, zero if the
+	// page was never modified.
+	ModifiedAt time.Time
+
+	// Traps are linked from the page, see Trap. Clones do not inherit them.
+	Traps []*Trap
+
+	// DuplicateOf is the page whose content the page duplicates as of
+	// DuplicateVersion, keeping the share Similarity of its sentences, see
+	// Content. Clones do not inherit it.
+	DuplicateOf      *Webpage
+	DuplicateVersion uint64
+	Similarity       float64
+	// Variants are URL variants serving the page besides its path, pages
+	// linking to it link them too. Clones do not inherit them.
+	Variants []URLVariant
+	// CanonicalTag renders a rel=canonical link to CanonicalPath.
+	CanonicalTag bool
+	// PageSize is the number of links per page of hubs, newest first, zero
+	// rendering every link on one page. Pages other than the first are
+	// served on URLs of PaginationStyle, see PagePath.
+	PageSize        int
+	PaginationStyle PaginationStyle
 
 	PathGenerator PathGeneratorfunc
 	AuthorityTmpl *template.Template
 	HubTmpl       *template.Template
 	CustomTmpl    *template.Template
+
+	// mu is shared by every page of a graph, see LockGraph and RLockGraph.
+	mu *sync.RWMutex
+	// ids draws the IDs of the page and its clones, nil uses the global
+	// source. It is shared by clones and guarded by the graph lock.
+	ids *idSource
+}
+
+// idSource is a seeded source of IDs that counts the IDs it drew, for a graph
+// built from a snapshot to go on where the saved graph left off.
+type idSource struct {
+	seed  int64
+	rng   *rand.Rand
+	drawn uint64
+}
+
+func newIDSource(seed int64) *idSource {
+	return &idSource{seed: seed, rng: rand.New(rand.NewSource(seed))}
+}
+
+func (ids *idSource) next() uint64 {
+	ids.drawn++
+	return ids.rng.Uint64()
+}
+
+// seek positions the source after the first drawn IDs of its seed.
+func (ids *idSource) seek(drawn uint64) {
+	ids.rng = rand.New(rand.NewSource(ids.seed))
+	ids.drawn = 0
+	for ids.drawn < drawn {
+		ids.next()
+	}
 }
 
 type WebpageOption func(*Webpage)
@@ -48,8 +118,6 @@ func NewWebpage(
 	webpageType WebpageType,
 	opts ...WebpageOption,
 ) *Webpage {
-	id := rand.Uint64()
-
 	fnMaps := template.FuncMap{"Split": strings.Split}
 
 	defaultAuthorityTmpl, err := template.New("default_authority.html.tmpl").
@@ -66,31 +134,52 @@ func NewWebpage(
 	}
 
 	webpage := Webpage{
-		ID:    id,
-		Links: make([]*Webpage, 0),
-		Type:  webpageType,
+		Links:     make([]*Webpage, 0),
+		Type:      webpageType,
+		CreatedAt: time.Now().UTC(),
 
 		PathGenerator: defaultPathGenerator,
 		AuthorityTmpl: defaultAuthorityTmpl,
 		HubTmpl:       defaultHubTmpl,
+
+		mu: &sync.RWMutex{},
 	}
 
 	for _, opt := range opts {
 		opt(&webpage)
 	}
+	webpage.ID = webpage.newID()
 	return &webpage
 }
 
+func (wp *Webpage) newID() uint64 {
+	if wp.ids != nil {
+		return wp.ids.next()
+	}
+	return rand.Uint64()
+}
+
 // Clone creates a new Webpage instance based on the current one with a specified type, a unique ID, and no links.
 func (wp *Webpage) Clone(webpageType WebpageType) *Webpage {
 	webpage := *wp
 	// assign a unique id
-	id := rand.Uint64()
-	webpage.ID = id
+	webpage.ID = wp.newID()
 
-	// initializing links & type
+	// initializing path, parent, links & type
+	webpage.Path = ""
+	webpage.Parent = nil
 	webpage.Links = make([]*Webpage, 0)
+	webpage.Traps = nil
+	webpage.DuplicateOf = nil
+	webpage.DuplicateVersion = 0
+	webpage.Similarity = 0
+	webpage.Variants = nil
 	webpage.Type = webpageType
+	webpage.Deleted = false
+	webpage.Version = 0
+	webpage.ChangeRate = 0
+	webpage.CreatedAt = time.Now().UTC()
+	webpage.ModifiedAt = time.Time{}
 	return &webpage
 }
 
@@ -120,11 +209,24 @@ func (wp *Webpage) GetPath() string {
 	return wp.Path
 }
 
+// Render renders the page, the first page of paginated hubs, see RenderPage.
 func (wp *Webpage) Render(writer io.Writer) error {
+	return wp.RenderPage(writer, 1)
+}
+
+// render renders the page with the given links, pagination is nil unless the
+// page is a paginated hub.
+func (wp *Webpage) render(writer io.Writer, links []*Webpage, pagination *Pagination) error {
 	data := struct {
 		Node *Webpage
+		// Links are the links rendered on the page, Node.Links on pages that
+		// are not paginated.
+		Links      []*Webpage
+		Pagination *Pagination
 	}{
-		Node: wp,
+		Node:       wp,
+		Links:      links,
+		Pagination: pagination,
 	}
 	if wp.CustomTmpl != nil {
 		return wp.CustomTmpl.Execute(writer, data)
@@ -135,27 +237,172 @@ func (wp *Webpage) Render(writer io.Writer) error {
 	return wp.HubTmpl.Execute(writer, data)
 }
 
+// GetLinks returns the pages linked from the webpage, skipping deleted ones.
 func (wp *Webpage) GetLinks() []HyperRenderer {
-	links := make([]HyperRenderer, len(wp.Links))
-	for i, link := range wp.Links {
-		links[i] = link
+	links := make([]HyperRenderer, 0, len(wp.Links))
+	for _, link := range wp.Links {
+		if link.Deleted {
+			continue
+		}
+		links = append(links, link)
 	}
 	return links
 }
 
+// AddLink links page from wp, linking a page twice is a no-op. A page without
+// parent becomes a child of wp unless it is wp or one of its ancestors, so the
+// first link to a page places it in the tree of parents. Further links are
+// cross links, or back links when they point to an ancestor, and may form
+// cycles.
 func (wp *Webpage) AddLink(page *Webpage) {
-	page.Parent = wp
+	if wp.HasLink(page) {
+		return
+	}
+	if page.Parent == nil && !page.IsAncestorOf(wp) {
+		page.Parent = wp
+	}
 	wp.Links = append(wp.Links, page)
+	if wp.mu != nil {
+		shareLock(page, wp.mu)
+	}
+}
+
+// Adopt links page from wp, if not linked yet, and makes wp its parent. page
+// must not be wp or one of its ancestors.
+func (wp *Webpage) Adopt(page *Webpage) {
+	wp.AddLink(page)
+	page.Parent = wp
+}
+
+func (wp *Webpage) HasLink(page *Webpage) bool {
+	for _, link := range wp.Links {
+		if link == page {
+			return true
+		}
+	}
+	return false
+}
+
+// Children returns the linked pages whose parent wp is, deleted ones included.
+func (wp *Webpage) Children() []*Webpage {
+	children := make([]*Webpage, 0, len(wp.Links))
+	for _, link := range wp.Links {
+		if link.Parent == wp {
+			children = append(children, link)
+		}
+	}
+	return children
+}
+
+// IsAncestorOf reports whether wp is page or one of the parents of its parents.
+func (wp *Webpage) IsAncestorOf(page *Webpage) bool {
+	for current := page; current != nil; current = current.Parent {
+		if current == wp {
+			return true
+		}
+	}
+	return false
+}
+
+// shareLock makes root and the pages reachable from it use mu as graph lock.
+func shareLock(root *Webpage, mu *sync.RWMutex) {
+	stack := []*Webpage{root}
+	for len(stack) > 0 {
+		page := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if page.mu == mu {
+			continue
+		}
+		page.mu = mu
+		stack = append(stack, page.Links...)
+	}
 }
 
+// LockGraph locks the graph the page belongs to for writing. Methods of Webpage do
+// not lock by themselves: goroutines mutating a graph that is being served
+// must hold LockGraph, goroutines reading it must hold RLockGraph. Pages not created by
+// NewWebpage, Clone or AddChild have no lock and locking them is a no-op.
+func (wp *Webpage) LockGraph() {
+	if wp.mu != nil {
+		wp.mu.Lock()
+	}
+}
+
+func (wp *Webpage) UnlockGraph() {
+	if wp.mu != nil {
+		wp.mu.Unlock()
+	}
+}
+
+// RLockGraph locks the graph the page belongs to for reading, see LockGraph.
+func (wp *Webpage) RLockGraph() {
+	if wp.mu != nil {
+		wp.mu.RLock()
+	}
+}
+
+func (wp *Webpage) RUnlockGraph() {
+	if wp.mu != nil {
+		wp.mu.RUnlock()
+	}
+}
+
+// RemoveLink removes the link to page and reports whether it was present.
+// The parent pointer of page is left untouched.
+func (wp *Webpage) RemoveLink(page *Webpage) bool {
+	for i, link := range wp.Links {
+		if link == page {
+			wp.Links = append(wp.Links[:i], wp.Links[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Faker returns a faker seeded by the ID and the content version of the page.
 func (wp *Webpage) Faker() *gofakeit.Faker {
-	return gofakeit.New(wp.ID)
+	return gofakeit.New(contentSeed(wp.ID, wp.Version))
+}
+
+// Modify bumps the content version of the page. A duplicate copies the
+// current content version of the page it duplicates again.
+func (wp *Webpage) Modify(at time.Time) {
+	wp.Version++
+	wp.ModifiedAt = at
+	if wp.DuplicateOf != nil {
+		wp.DuplicateVersion = wp.DuplicateOf.Version
+	}
+}
+
+// LastModified returns the time the current content version came into being.
+func (wp *Webpage) LastModified() time.Time {
+	if wp.ModifiedAt.IsZero() {
+		return wp.CreatedAt
+	}
+	return wp.ModifiedAt
+}
+
+// ETag returns a strong entity tag of the rendered page. Besides the content
+// version it covers what else is rendered: the canonical path, the content a
+// duplicate copies and the paths, variant paths and versions of the links.
+func (wp *Webpage) ETag() string {
+	hash := fnv.New64a()
+	if wp.CanonicalTag {
+		fmt.Fprintf(hash, "%s\x00", wp.CanonicalPath())
+	}
+	if wp.DuplicateOf != nil {
+		fmt.Fprintf(hash, "%d\x00%d\x00%g\x00", wp.DuplicateOf.ID, wp.DuplicateVersion, wp.Similarity)
+	}
+	for _, link := range wp.Links {
+		fmt.Fprintf(hash, "%s\x00%d\x00%s\x00", link.GetPath(), link.Version, strings.Join(link.VariantPaths(), " "))
+	}
+	return fmt.Sprintf(`"%s-%d-%x"`, wp.GetID(), wp.Version, hash.Sum64())
 }
 
 func (wp *Webpage) CountLinksByType(t WebpageType) int {
 	i := 0
 	for _, link := range wp.Links {
-		if link.Type == t {
+		if link.Type == t && !link.Deleted {
 			i++
 		}
 	}
@@ -213,10 +460,14 @@ func (wp *Webpage) Draw(format graphviz.Format, w io.Writer) error {
 			if !ok {
 				panic("unable to get child id")
 			}
-			_, err := graph.CreateEdge("", parentNode, child)
+			edge, err := graph.CreateEdge("", parentNode, child)
 			if err != nil {
 				return err
 			}
+			// links other than to children are cross or back links
+			if linkedPage, ok := link.(*Webpage); ok && linkedPage.Parent != node {
+				edge.SetStyle(cgraph.DashedEdgeStyle)
+			}
 		}
 	}
 
@@ -235,11 +486,8 @@ func defaultPathGenerator(webpage *Webpage) string {
 		}
 		return "/"
 	}
-	result, err := url.JoinPath(webpage.Parent.GetPath(), fmt.Sprintf("%s", webpage.GetID()))
-	if err != nil {
-		panic(err)
-	}
-	return result
+	// paths are kept unescaped, as in request URLs, so they are joined as is
+	return path.Join(webpage.Parent.GetPath(), webpage.GetID())
 }
 
 func CityPathGenerator(webpage *Webpage) string {
@@ -247,14 +495,11 @@ func CityPathGenerator(webpage *Webpage) string {
 		return "/"
 	}
 
-	result, err := url.JoinPath(
+	// paths must not change with content, so the faker is seeded by ID only
+	return path.Join(
 		webpage.Parent.GetPath(),
-		strings.ReplaceAll(strings.ToLower(webpage.Faker().City()), " ", "-"),
+		strings.ReplaceAll(strings.ToLower(gofakeit.New(webpage.ID).City()), " ", "-"),
 	)
-	if err != nil {
-		panic(err)
-	}
-	return result
 }
 
 func WithAuthorityTemplate(tmpl *template.Template) WebpageOption {
@@ -281,6 +526,21 @@ func WithPathGenerator(f func(*Webpage) string) WebpageOption {
 	}
 }
 
+// WithSeed makes the IDs of the page and of its clones, and therefore their
+// paths and content, reproducible.
+func WithSeed(seed int64) WebpageOption {
+	return func(w *Webpage) {
+		w.ids = newIDSource(seed)
+	}
+}
+
+// WithTraps links the traps from the page.
+func WithTraps(traps ...*Trap) WebpageOption {
+	return func(w *Webpage) {
+		w.Traps = traps
+	}
+}
+
 func WithPathPrefix(prefix string) WebpageOption {
 	return func(w *Webpage) {
 		w.PathPrefix = strings.TrimSuffix(prefix, "/")
@@ -7,7 +7,9 @@ import (
 	"html/template"
 	"os"
 	"path"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/goccy/go-graphviz"
 	"github.com/stretchr/testify/assert"
@@ -285,6 +287,48 @@ func TestAddLink(t *testing.T) {
 	}
 }
 
+func TestAddLinkBeyondTree(t *testing.T) {
+	root := hr.NewWebpage(hr.WebpageTypeHub)
+	hub := root.AddChild(hr.WebpageTypeHub)
+	authority := hub.AddChild(hr.WebpageTypeAuthority)
+	other := root.AddChild(hr.WebpageTypeAuthority)
+	path := authority.GetPath()
+
+	testCases := []struct {
+		description string
+		from        *hr.Webpage
+		to          *hr.Webpage
+	}{
+		{description: "cross link", from: other, to: authority},
+		{description: "back link to parent", from: authority, to: hub},
+		{description: "back link to root closes a cycle", from: authority, to: root},
+		{description: "duplicate link", from: hub, to: authority},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			parent := tc.to.Parent
+			tc.from.AddLink(tc.to)
+
+			assert.Equal(t, parent, tc.to.Parent, "Parent changed by a link")
+			assert.Contains(t, tc.from.Links, tc.to)
+		})
+	}
+
+	assert.Len(t, hub.Links, 1, "Duplicate link added")
+	assert.Equal(t, path, authority.GetPath())
+	assert.Equal(t, []*hr.Webpage{authority}, hub.Children())
+	assert.Empty(t, authority.Children())
+	assert.True(t, root.IsAncestorOf(authority))
+	assert.False(t, authority.IsAncestorOf(root))
+	assert.Len(t, hr.Traverse(root, hr.NoOpVisit), 4)
+
+	root.Adopt(authority)
+	assert.Equal(t, root, authority.Parent)
+	assert.Empty(t, hub.Children())
+	assert.Equal(t, "/"+authority.GetID(), authority.GetPath())
+}
+
 var update = flag.Bool("update", false, "update golden files")
 
 func TestDrawGolden(t *testing.T) {
@@ -306,3 +350,96 @@ func TestDrawGolden(t *testing.T) {
 
 	assert.Equal(t, string(expected), string(actual.Bytes()), "Generated output does not match golden output")
 }
+
+func TestRemoveLink(t *testing.T) {
+	root := hr.NewWebpage(hr.WebpageTypeHub)
+	child1 := root.AddChild(hr.WebpageTypeAuthority)
+	child2 := root.AddChild(hr.WebpageTypeAuthority)
+
+	assert.True(t, root.RemoveLink(child1), "RemoveLink should report removed link")
+	assert.False(t, root.RemoveLink(child1), "RemoveLink should not remove a link twice")
+	assert.Equal(t, []*hr.Webpage{child2}, root.Links)
+}
+
+func TestGetLinksSkipsDeleted(t *testing.T) {
+	root := hr.NewWebpage(hr.WebpageTypeHub)
+	child1 := root.AddChild(hr.WebpageTypeAuthority)
+	child2 := root.AddChild(hr.WebpageTypeAuthority)
+	child1.Deleted = true
+
+	assert.Len(t, root.Links, 2, "Deleted page should still be linked")
+	assert.Equal(t, []hr.HyperRenderer{child2}, root.GetLinks())
+	assert.Equal(t, 1, root.CountLinksByType(hr.WebpageTypeAuthority))
+}
+
+func TestModify(t *testing.T) {
+	root := hr.NewWebpage(hr.WebpageTypeHub, hr.WithPathGenerator(hr.CityPathGenerator))
+	child := root.AddChild(hr.WebpageTypeAuthority)
+
+	var before bytes.Buffer
+	require.NoError(t, child.Render(&before))
+	pathBefore := child.GetPath()
+
+	child.Modify(time.Now())
+
+	var after bytes.Buffer
+	require.NoError(t, child.Render(&after))
+	assert.Equal(t, uint64(1), child.Version)
+	assert.NotEqual(t, before.String(), after.String(), "Content should change with version")
+	assert.Equal(t, pathBefore, child.GetPath(), "Path should not change with version")
+
+	cloned := child.Clone(hr.WebpageTypeAuthority)
+	assert.Equal(t, uint64(0), cloned.Version, "Clone should reset content version")
+}
+
+func TestETag(t *testing.T) {
+	root := hr.NewWebpage(hr.WebpageTypeHub)
+	child := root.AddChild(hr.WebpageTypeHub)
+	etag := root.ETag()
+
+	assert.True(t, strings.HasPrefix(etag, `"`) && strings.HasSuffix(etag, `"`), "ETag should be quoted")
+	assert.Equal(t, etag, root.ETag(), "ETag should be stable")
+	assert.NotEqual(t, etag, child.ETag())
+
+	tests := []struct {
+		name   string
+		change func()
+	}{
+		{"modification", func() { root.Modify(time.Now()) }},
+		{"modification of link", func() { child.Modify(time.Now()) }},
+		{"new link", func() { root.AddChild(hr.WebpageTypeAuthority) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := root.ETag()
+			tt.change()
+			assert.NotEqual(t, before, root.ETag())
+		})
+	}
+}
+
+func TestWithSeed(t *testing.T) {
+	build := func(seed int64) (*hr.Webpage, string) {
+		root := hr.NewWebpage(hr.WebpageTypeHub, hr.WithSeed(seed), hr.WithPathGenerator(hr.CityPathGenerator))
+		root.AddChild(hr.WebpageTypeHub).AddChild(hr.WebpageTypeAuthority)
+		root.AddChild(hr.WebpageTypeAuthority)
+
+		var buf bytes.Buffer
+		hr.Traverse(root, func(node hr.HyperRenderer) bool {
+			fmt.Fprintf(&buf, "%s %s\n", node.GetID(), node.GetPath())
+			require.NoError(t, node.Render(&buf))
+			return false
+		})
+		return root, buf.String()
+	}
+
+	root1, site1 := build(42)
+	root2, site2 := build(42)
+	root3, site3 := build(43)
+
+	assert.Equal(t, root1.ID, root2.ID)
+	assert.Equal(t, site1, site2, "Same seed should produce the same site")
+	assert.NotEqual(t, root1.ID, root3.ID)
+	assert.NotEqual(t, site1, site3, "Different seeds should produce different sites")
+}
@@ -0,0 +1,46 @@
+// Package lockedrand provides random sources safe for concurrent use, so that
+// seeded generators can be shared between goroutines.
+package lockedrand
+
+import (
+	"math/rand"
+	"sync"
+)
+
+var _ rand.Source64 = &Source{}
+
+// Source makes a rand.Rand built on it safe for concurrent use.
+type Source struct {
+	mu     sync.Mutex
+	source rand.Source64
+}
+
+func NewSource(seed int64) *Source {
+	return &Source{source: rand.NewSource(seed).(rand.Source64)}
+}
+
+// New returns a rand.Rand drawing from a Source seeded with seed.
+func New(seed int64) *rand.Rand {
+	return rand.New(NewSource(seed))
+}
+
+func (source *Source) Int63() int64 {
+	source.mu.Lock()
+	defer source.mu.Unlock()
+
+	return source.source.Int63()
+}
+
+func (source *Source) Uint64() uint64 {
+	source.mu.Lock()
+	defer source.mu.Unlock()
+
+	return source.source.Uint64()
+}
+
+func (source *Source) Seed(seed int64) {
+	source.mu.Lock()
+	defer source.mu.Unlock()
+
+	source.source.Seed(seed)
+}
@@ -0,0 +1,51 @@
+package lockedrand_test
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sdqri/sequined/internal/lockedrand"
+)
+
+func TestNewIsSeeded(t *testing.T) {
+	expected := rand.New(rand.NewSource(42))
+	rng := lockedrand.New(42)
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, expected.Uint64(), rng.Uint64())
+		assert.Equal(t, expected.Float64(), rng.Float64())
+	}
+}
+
+func TestConcurrentDraws(t *testing.T) {
+	rng := lockedrand.New(42)
+	draws := make(chan uint64, 4*1000)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				draws <- rng.Uint64()
+			}
+		}()
+	}
+	wg.Wait()
+	close(draws)
+
+	// draws are not lost to races, every value of the sequence is drawn once
+	expected := rand.New(rand.NewSource(42))
+	sequence := make(map[uint64]int)
+	for i := 0; i < 4*1000; i++ {
+		sequence[expected.Uint64()]++
+	}
+	for draw := range draws {
+		sequence[draw]--
+	}
+	for _, count := range sequence {
+		assert.Zero(t, count)
+	}
+}
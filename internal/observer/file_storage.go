@@ -0,0 +1,262 @@
+package observer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+var _ Storage = &FileStorage{}
+
+var ErrUnknownRecordKind = errors.New("unknown record kind in storage file")
+
+type recordKind string
+
+const (
+	recordKindNode      recordKind = "node"
+	recordKindNodeEvent recordKind = "node_event"
+	recordKindVisit     recordKind = "visit"
+	recordKindViolation recordKind = "violation"
+	recordKindFault     recordKind = "fault"
+)
+
+type record struct {
+	Kind      recordKind    `json:"kind"`
+	Node      *NodeLog      `json:"node,omitempty"`
+	NodeEvent *NodeEvent    `json:"node_event,omitempty"`
+	Visit     *VisitLog     `json:"visit,omitempty"`
+	Violation *ViolationLog `json:"violation,omitempty"`
+	Fault     *FaultLog     `json:"fault,omitempty"`
+}
+
+// FileStorage appends logs to a JSON lines file. The file can be reopened to
+// resume or analyze an observation, and compacted to fold node events into
+// node records. A trailing partial record, e.g. left by a crash, is removed
+// when the file is opened.
+type FileStorage struct {
+	path string
+	file *os.File
+	mu   sync.Mutex
+}
+
+func OpenFileStorage(path string) (*FileStorage, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := truncatePartialRecord(file); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &FileStorage{
+		path: path,
+		file: file,
+	}, nil
+}
+
+// truncatePartialRecord truncates the file after its last newline, so that the
+// next record appended does not extend a partially written one.
+func truncatePartialRecord(file *os.File) error {
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	size := info.Size()
+	end := size
+	buf := make([]byte, 4096)
+	for end > 0 {
+		start := max(0, end-int64(len(buf)))
+		chunk := buf[:end-start]
+		if _, err := file.ReadAt(chunk, start); err != nil {
+			return err
+		}
+		if i := bytes.LastIndexByte(chunk, '\n'); i >= 0 {
+			end = start + int64(i) + 1
+			break
+		}
+		end = start
+	}
+	if end == size {
+		return nil
+	}
+	return file.Truncate(end)
+}
+
+func (storage *FileStorage) SaveNode(nodeLog NodeLog) error {
+	return storage.append(record{Kind: recordKindNode, Node: &nodeLog})
+}
+
+func (storage *FileStorage) SaveNodeEvent(event NodeEvent) error {
+	return storage.append(record{Kind: recordKindNodeEvent, NodeEvent: &event})
+}
+
+func (storage *FileStorage) SaveVisit(visitLog VisitLog) error {
+	return storage.append(record{Kind: recordKindVisit, Visit: &visitLog})
+}
+
+func (storage *FileStorage) SaveViolation(violationLog ViolationLog) error {
+	return storage.append(record{Kind: recordKindViolation, Violation: &violationLog})
+}
+
+func (storage *FileStorage) SaveFault(faultLog FaultLog) error {
+	return storage.append(record{Kind: recordKindFault, Fault: &faultLog})
+}
+
+func (storage *FileStorage) append(r record) error {
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+
+	// a single write per record keeps records whole between concurrent writers
+	_, err = storage.file.Write(line)
+	return err
+}
+
+func (storage *FileStorage) Load() (NodeLogMapType, VisitHistoryType, ViolationHistoryType, FaultHistoryType, error) {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+
+	return readRecords(storage.path)
+}
+
+func readRecords(path string) (NodeLogMapType, VisitHistoryType, ViolationHistoryType, FaultHistoryType, error) {
+	nodeLogMap := make(NodeLogMapType)
+	visitHistory := make(VisitHistoryType, 0)
+	violationHistory := make(ViolationHistoryType, 0)
+	faultHistory := make(FaultHistoryType, 0)
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	for lineNumber := 1; ; lineNumber++ {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// a line without newline is a partially written record
+			break
+		}
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		var r record
+		if err := json.Unmarshal(line, &r); err != nil {
+			return nil, nil, nil, nil, fmt.Errorf("%s:%d: %w", path, lineNumber, err)
+		}
+
+		switch {
+		case r.Kind == recordKindNode && r.Node != nil:
+			nodeLogMap[r.Node.ID] = *r.Node
+		case r.Kind == recordKindNodeEvent && r.NodeEvent != nil:
+			// events follow the record of their node
+			if nodeLog, ok := nodeLogMap[r.NodeEvent.NodeID]; ok {
+				nodeLogMap[r.NodeEvent.NodeID] = nodeLog.apply(*r.NodeEvent)
+			}
+		case r.Kind == recordKindVisit && r.Visit != nil:
+			visitHistory = append(visitHistory, *r.Visit)
+		case r.Kind == recordKindViolation && r.Violation != nil:
+			violationHistory = append(violationHistory, *r.Violation)
+		case r.Kind == recordKindFault && r.Fault != nil:
+			faultHistory = append(faultHistory, *r.Fault)
+		default:
+			return nil, nil, nil, nil, fmt.Errorf("%s:%d: %w", path, lineNumber, ErrUnknownRecordKind)
+		}
+	}
+
+	return nodeLogMap, visitHistory, violationHistory, faultHistory, nil
+}
+
+// Compact rewrites the file keeping a single record of every node, its events
+// folded in, and all visits, violations and faults. The file is replaced atomically.
+func (storage *FileStorage) Compact() error {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+
+	nodeLogMap, visitHistory, violationHistory, faultHistory, err := readRecords(storage.path)
+	if err != nil {
+		return err
+	}
+
+	tmpPath := storage.path + ".compact"
+	tmpFile, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpPath)
+
+	writer := bufio.NewWriter(tmpFile)
+	encoder := json.NewEncoder(writer)
+	for _, nodeLog := range nodeLogMap {
+		if err := encoder.Encode(record{Kind: recordKindNode, Node: &nodeLog}); err != nil {
+			tmpFile.Close()
+			return err
+		}
+	}
+	for _, visitLog := range visitHistory {
+		if err := encoder.Encode(record{Kind: recordKindVisit, Visit: &visitLog}); err != nil {
+			tmpFile.Close()
+			return err
+		}
+	}
+	for _, violationLog := range violationHistory {
+		if err := encoder.Encode(record{Kind: recordKindViolation, Violation: &violationLog}); err != nil {
+			tmpFile.Close()
+			return err
+		}
+	}
+	for _, faultLog := range faultHistory {
+		if err := encoder.Encode(record{Kind: recordKindFault, Fault: &faultLog}); err != nil {
+			tmpFile.Close()
+			return err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Sync(); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+
+	if err := storage.file.Close(); err != nil {
+		return err
+	}
+	renameErr := os.Rename(tmpPath, storage.path)
+
+	// reopen whether or not the rename succeeded, so that saving keeps working
+	storage.file, err = os.OpenFile(storage.path, os.O_APPEND|os.O_WRONLY, 0644)
+	if renameErr != nil {
+		return renameErr
+	}
+	return err
+}
+
+func (storage *FileStorage) Close() error {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+
+	return storage.file.Close()
+}
@@ -0,0 +1,164 @@
+package observer_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sdqri/sequined/internal/observer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileStorageReopen(t *testing.T) {
+	now := time.Now().UTC()
+	path := filepath.Join(t.TempDir(), "observer.jsonl")
+
+	storage, err := observer.OpenFileStorage(path)
+	require.NoError(t, err)
+	o, err := observer.Open(storage)
+	require.NoError(t, err)
+
+	o.LogNode(observer.NodeLog{ID: "node1", CreatedAt: now})
+	o.LogNode(observer.NodeLog{ID: "node2", CreatedAt: now})
+	o.LogNodeModification("node1", now.Add(time.Minute))
+	o.LogNodeDeletion("node2", now.Add(2*time.Minute))
+	o.LogVisit(observer.VisitLog{CrawlerID: "1.1.1.1", NodeID: "node1", VisitedAt: now.Add(30 * time.Second)})
+	o.LogViolation(observer.ViolationLog{CrawlerID: "1.1.1.1", Type: observer.ViolationTypeCrawlDelay, Path: "/", At: now.Add(30 * time.Second)})
+	o.LogFault(observer.FaultLog{CrawlerID: "1.1.1.1", Type: observer.FaultTypeReset, Path: "/", At: now.Add(40 * time.Second)})
+	require.NoError(t, o.Close())
+
+	storage, err = observer.OpenFileStorage(path)
+	require.NoError(t, err)
+	reopened, err := observer.Open(storage)
+	require.NoError(t, err)
+	defer reopened.Close()
+
+	assert.Len(t, reopened.NodeLogMap, 2)
+	assert.Len(t, reopened.NodeLogMap["node1"].ModifiedAt, 1)
+	assert.True(t, reopened.NodeLogMap["node1"].ModifiedAt[0].Equal(now.Add(time.Minute)))
+	assert.NotNil(t, reopened.NodeLogMap["node2"].DeletedAt)
+	assert.Len(t, reopened.VisitHistory, 1)
+	assert.Equal(t, observer.CrawlerID("1.1.1.1"), reopened.VisitHistory[0].CrawlerID)
+	assert.Equal(t, o.ViolationHistory, reopened.ViolationHistory)
+	assert.Equal(t, o.FaultHistory, reopened.FaultHistory)
+	assert.Equal(t, o.GetAverageAge("1.1.1.1", now.Add(time.Hour)), reopened.GetAverageAge("1.1.1.1", now.Add(time.Hour)))
+}
+
+func TestFileStorageAppendsNodeEvents(t *testing.T) {
+	now := time.Now().UTC()
+	path := filepath.Join(t.TempDir(), "observer.jsonl")
+
+	storage, err := observer.OpenFileStorage(path)
+	require.NoError(t, err)
+	defer storage.Close()
+	o, err := observer.Open(storage)
+	require.NoError(t, err)
+
+	o.LogNode(observer.NodeLog{ID: "node1", CreatedAt: now})
+	for i := 0; i < 10; i++ {
+		o.LogNodeModification("node1", now.Add(time.Duration(i)*time.Minute))
+		o.LogNodeMove("node1", now.Add(time.Duration(i)*time.Minute))
+	}
+	o.LogNodeDeletion("node1", now.Add(time.Hour))
+	// events of nodes never logged are dropped
+	o.LogNodeModification("node2", now)
+	require.NoError(t, o.Err())
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 1+20+1, strings.Count(string(content), "\n"), "every event should append a single record")
+	assert.Equal(t, 1, strings.Count(string(content), `"ModifiedAt"`), "node history should not be rewritten")
+
+	nodeLogMap, _, _, _, err := storage.Load()
+	require.NoError(t, err)
+	assert.Equal(t, o.NodeLogMap, nodeLogMap)
+	assert.Len(t, nodeLogMap["node1"].ModifiedAt, 10)
+	assert.Len(t, nodeLogMap["node1"].MovedAt, 10)
+	assert.True(t, nodeLogMap["node1"].DeletedAt.Equal(now.Add(time.Hour)))
+}
+
+func TestFileStorageIgnoresPartialRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "observer.jsonl")
+
+	storage, err := observer.OpenFileStorage(path)
+	require.NoError(t, err)
+	require.NoError(t, storage.SaveVisit(observer.VisitLog{CrawlerID: "1.1.1.1", NodeID: "node1"}))
+	require.NoError(t, storage.Close())
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = file.WriteString(`{"kind":"visit","vis`)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	storage, err = observer.OpenFileStorage(path)
+	require.NoError(t, err)
+	defer storage.Close()
+	_, visitHistory, _, _, err := storage.Load()
+	require.NoError(t, err)
+	assert.Len(t, visitHistory, 1)
+}
+
+func TestFileStorageAppendsAfterPartialRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "observer.jsonl")
+
+	storage, err := observer.OpenFileStorage(path)
+	require.NoError(t, err)
+	require.NoError(t, storage.SaveVisit(observer.VisitLog{CrawlerID: "1.1.1.1", NodeID: "node1"}))
+	require.NoError(t, storage.Close())
+
+	// a crash mid-write leaves a partial record behind
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = file.WriteString(`{"kind":"visit","vis`)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	storage, err = observer.OpenFileStorage(path)
+	require.NoError(t, err)
+	require.NoError(t, storage.SaveVisit(observer.VisitLog{CrawlerID: "1.1.1.1", NodeID: "node2"}))
+	require.NoError(t, storage.Close())
+
+	storage, err = observer.OpenFileStorage(path)
+	require.NoError(t, err)
+	defer storage.Close()
+	_, visitHistory, _, _, err := storage.Load()
+	require.NoError(t, err)
+	require.Len(t, visitHistory, 2)
+	assert.Equal(t, observer.NodeID("node2"), visitHistory[1].NodeID)
+}
+
+func TestFileStorageCompact(t *testing.T) {
+	now := time.Now().UTC()
+	path := filepath.Join(t.TempDir(), "observer.jsonl")
+
+	storage, err := observer.OpenFileStorage(path)
+	require.NoError(t, err)
+	defer storage.Close()
+	o, err := observer.Open(storage)
+	require.NoError(t, err)
+
+	o.LogNode(observer.NodeLog{ID: "node1", CreatedAt: now})
+	for i := 0; i < 10; i++ {
+		o.LogNodeModification("node1", now.Add(time.Duration(i)*time.Minute))
+	}
+	o.LogVisit(observer.VisitLog{CrawlerID: "1.1.1.1", NodeID: "node1", VisitedAt: now})
+
+	require.NoError(t, storage.Compact())
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(string(content), "\n"), "compacted file should hold one record per node and visit")
+
+	// saving keeps working after compaction
+	o.LogVisit(observer.VisitLog{CrawlerID: "1.1.1.1", NodeID: "node1", VisitedAt: now})
+	require.NoError(t, o.Err())
+
+	nodeLogMap, visitHistory, _, _, err := storage.Load()
+	require.NoError(t, err)
+	assert.Len(t, nodeLogMap["node1"].ModifiedAt, 10)
+	assert.Len(t, visitHistory, 2)
+}
@@ -1,48 +1,597 @@
 package observer
 
 import (
+	"sync"
 	"time"
+
+	clk "github.com/sdqri/sequined/internal/clock"
 )
 
-type IPAddr string
+type CrawlerID string
 type NodeID string
 
 type VisitLog struct {
-	RemoteAddr IPAddr
-	NodeID     NodeID
-	VisitedAt  time.Time
+	CrawlerID CrawlerID
+	NodeID    NodeID
+	VisitedAt time.Time
+	// Revalidated visits were answered with 304 Not Modified instead of the
+	// full page. They still bring the copy of the crawler up to date.
+	Revalidated bool
+	// Delay is the latency injected into the response by the server.
+	Delay time.Duration
+	// Redirected visits reached the node through a redirect from one of its
+	// former paths.
+	Redirected bool
+	// Deleted visits requested the node after its deletion and were answered
+	// as the server answers deleted pages, e.g. 410 Gone or a soft 404.
+	Deleted bool
+	// Trap is the type of the spider trap the visited page belongs to, empty
+	// for pages of the graph. NodeID is then the ID of the trap page.
+	Trap string
+	// Variant is the URL variant the node was requested under, empty for its
+	// path, see hyperrenderer.URLVariant.
+	Variant string
+	// Page is the page of a paginated hub visited beyond the first, zero for
+	// the first page and other nodes.
+	Page int
 }
 
 type NodeLog struct {
 	ID        NodeID
 	CreatedAt time.Time
 	DeletedAt *time.Time
+	// ModifiedAt holds the content modification history in chronological order.
+	ModifiedAt []time.Time
+	// MovedAt holds the times the node was moved to another path in
+	// chronological order.
+	MovedAt []time.Time
+	// DuplicateOf is the node whose content the node duplicates, keeping the
+	// share Similarity of it. It is empty for nodes that duplicate none.
+	DuplicateOf NodeID
+	Similarity  float64
+}
+
+type ViolationType string
+
+const (
+	// ViolationTypeDisallowed is a visit to a path disallowed by robots.txt.
+	ViolationTypeDisallowed ViolationType = "disallowed"
+	// ViolationTypeCrawlDelay is a request sooner than the crawl delay after
+	// the previous request of the same crawler.
+	ViolationTypeCrawlDelay ViolationType = "crawl-delay"
+	// ViolationTypeRetryAfter is a request sooner than the Retry-After of a
+	// 429 Too Many Requests answered to the same crawler.
+	ViolationTypeRetryAfter ViolationType = "retry-after"
+)
+
+type ViolationLog struct {
+	CrawlerID CrawlerID
+	Type      ViolationType
+	Path      string
+	At        time.Time
+}
+
+type FaultType string
+
+const (
+	FaultTypeInternalServerError FaultType = "500"
+	FaultTypeBadGateway          FaultType = "502"
+	FaultTypeServiceUnavailable  FaultType = "503"
+	// FaultTypeTimeout is a response withheld until the crawler gives up.
+	FaultTypeTimeout FaultType = "timeout"
+	// FaultTypeReset is a connection reset instead of a response.
+	FaultTypeReset FaultType = "reset"
+	// FaultTypeTruncate is a response body cut short of its Content-Length.
+	FaultTypeTruncate FaultType = "truncate"
+	// FaultTypeRateLimited is a 429 Too Many Requests to a crawler exceeding
+	// its rate limit.
+	FaultTypeRateLimited FaultType = "429"
+	// FaultTypeBanned is a 403 Forbidden to a crawler banned for ignoring
+	// Retry-After.
+	FaultTypeBanned FaultType = "403"
+)
+
+// FaultLog is an error the server answered a crawler with instead of the
+// page, injected or due to throttling. Faulted requests are not logged as
+// visits.
+type FaultLog struct {
+	CrawlerID CrawlerID
+	Type      FaultType
+	Path      string
+	At        time.Time
 }
 
 type NodeLogMapType map[NodeID]NodeLog
 type VisitHistoryType []VisitLog
+type ViolationHistoryType []ViolationLog
+type FaultHistoryType []FaultLog
 
+// Observer is safe for concurrent use through its methods. NodeLogMap and
+// VisitHistory must not be accessed directly while the observer is in use.
 type Observer struct {
-	NodeLogMap   NodeLogMapType
-	VisitHistory VisitHistoryType
+	NodeLogMap       NodeLogMapType
+	VisitHistory     VisitHistoryType
+	ViolationHistory ViolationHistoryType
+	FaultHistory     FaultHistoryType
+
+	// storage, if any, receives every change of the logs, see Open.
+	storage Storage
+	// storageErr is the first error returned by storage.
+	storageErr error
+	// clock tells the time logged events are stamped with.
+	clock clk.Clock
+
+	mu sync.RWMutex
+}
+
+type ObserverOption func(*Observer)
+
+// New returns an observer keeping its logs in memory only.
+func New(opts ...ObserverOption) *Observer {
+	observer := &Observer{
+		NodeLogMap:       make(NodeLogMapType),
+		VisitHistory:     make(VisitHistoryType, 0),
+		ViolationHistory: make(ViolationHistoryType, 0),
+		FaultHistory:     make(FaultHistoryType, 0),
+		clock:            clk.Real(),
+	}
+
+	for _, opt := range opts {
+		opt(observer)
+	}
+	return observer
+}
+
+// Open returns an observer initialized with the logs loaded from storage,
+// which then receives every change of the logs.
+func Open(storage Storage, opts ...ObserverOption) (*Observer, error) {
+	nodeLogMap, visitHistory, violationHistory, faultHistory, err := storage.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	observer := &Observer{
+		NodeLogMap:       nodeLogMap,
+		VisitHistory:     visitHistory,
+		ViolationHistory: violationHistory,
+		FaultHistory:     faultHistory,
+		storage:          storage,
+		clock:            clk.Real(),
+	}
+
+	for _, opt := range opts {
+		opt(observer)
+	}
+	return observer, nil
+}
+
+// WithClock sets the clock of the simulation the observer watches, so that
+// metrics are reported at its current time.
+func WithClock(clock clk.Clock) ObserverOption {
+	return func(observer *Observer) {
+		observer.clock = clock
+	}
+}
+
+// Now returns the current time of the observed simulation.
+func (observer *Observer) Now() time.Time {
+	return observer.clock.Now()
+}
+
+// Err returns the first error encountered while saving to storage.
+func (observer *Observer) Err() error {
+	observer.mu.RLock()
+	defer observer.mu.RUnlock()
+
+	return observer.storageErr
+}
+
+// Close closes the storage of the observer, if any.
+func (observer *Observer) Close() error {
+	observer.mu.Lock()
+	defer observer.mu.Unlock()
+
+	if observer.storage == nil {
+		return observer.storageErr
+	}
+	if err := observer.storage.Close(); err != nil {
+		return err
+	}
+	return observer.storageErr
+}
+
+func (observer *Observer) saveNode(nodeLog NodeLog) {
+	if observer.storage == nil {
+		return
+	}
+	if err := observer.storage.SaveNode(nodeLog); err != nil && observer.storageErr == nil {
+		observer.storageErr = err
+	}
+}
+
+func (observer *Observer) saveNodeEvent(event NodeEvent) {
+	if observer.storage == nil {
+		return
+	}
+	if err := observer.storage.SaveNodeEvent(event); err != nil && observer.storageErr == nil {
+		observer.storageErr = err
+	}
+}
+
+// logNodeEvent applies the event to the log of its node, if logged, and saves
+// it. The observer must be locked.
+func (observer *Observer) logNodeEvent(event NodeEvent) {
+	if nodeLog, ok := observer.NodeLogMap[event.NodeID]; ok {
+		observer.NodeLogMap[event.NodeID] = nodeLog.apply(event)
+		observer.saveNodeEvent(event)
+	}
+}
+
+func (observer *Observer) saveVisit(visitLog VisitLog) {
+	if observer.storage == nil {
+		return
+	}
+	if err := observer.storage.SaveVisit(visitLog); err != nil && observer.storageErr == nil {
+		observer.storageErr = err
+	}
+}
+
+func (observer *Observer) saveViolation(violationLog ViolationLog) {
+	if observer.storage == nil {
+		return
+	}
+	if err := observer.storage.SaveViolation(violationLog); err != nil && observer.storageErr == nil {
+		observer.storageErr = err
+	}
 }
 
-func New() *Observer {
-	return &Observer{
-		NodeLogMap:   make(NodeLogMapType),
-		VisitHistory: make(VisitHistoryType, 0),
+func (observer *Observer) saveFault(faultLog FaultLog) {
+	if observer.storage == nil {
+		return
+	}
+	if err := observer.storage.SaveFault(faultLog); err != nil && observer.storageErr == nil {
+		observer.storageErr = err
 	}
 }
 
 func (observer *Observer) LogNode(nodeLog NodeLog) {
+	observer.mu.Lock()
+	defer observer.mu.Unlock()
+
 	observer.NodeLogMap[nodeLog.ID] = nodeLog
+	observer.saveNode(nodeLog)
 }
 
 func (observer *Observer) LogVisit(visitLog VisitLog) {
+	observer.mu.Lock()
+	defer observer.mu.Unlock()
+
 	observer.VisitHistory = append(observer.VisitHistory, visitLog)
+	observer.saveVisit(visitLog)
+}
+
+func (observer *Observer) LogViolation(violationLog ViolationLog) {
+	observer.mu.Lock()
+	defer observer.mu.Unlock()
+
+	observer.ViolationHistory = append(observer.ViolationHistory, violationLog)
+	observer.saveViolation(violationLog)
+}
+
+func (observer *Observer) LogFault(faultLog FaultLog) {
+	observer.mu.Lock()
+	defer observer.mu.Unlock()
+
+	observer.FaultHistory = append(observer.FaultHistory, faultLog)
+	observer.saveFault(faultLog)
+}
+
+// GetFaultCounts returns the number of faults injected into responses to the
+// crawler up to at by type.
+func (observer *Observer) GetFaultCounts(crawlerID string, at time.Time) map[FaultType]int {
+	observer.mu.RLock()
+	defer observer.mu.RUnlock()
+
+	counts := make(map[FaultType]int)
+	for _, faultLog := range observer.FaultHistory {
+		if faultLog.CrawlerID == CrawlerID(crawlerID) && !faultLog.At.After(at) {
+			counts[faultLog.Type]++
+		}
+	}
+	return counts
+}
+
+// GetViolationCounts returns the number of violations of the crawler up to at
+// by type.
+func (observer *Observer) GetViolationCounts(crawlerID string, at time.Time) map[ViolationType]int {
+	observer.mu.RLock()
+	defer observer.mu.RUnlock()
+
+	counts := make(map[ViolationType]int)
+	for _, violationLog := range observer.ViolationHistory {
+		if violationLog.CrawlerID == CrawlerID(crawlerID) && !violationLog.At.After(at) {
+			counts[violationLog.Type]++
+		}
+	}
+	return counts
+}
+
+// LogNodeDeletion marks the node as deleted at the given time.
+func (observer *Observer) LogNodeDeletion(nodeID NodeID, deletedAt time.Time) {
+	observer.mu.Lock()
+	defer observer.mu.Unlock()
+
+	observer.logNodeEvent(NodeEvent{NodeID: nodeID, Type: NodeEventTypeDeletion, At: deletedAt})
+}
+
+// GetFetchCounts returns the number of visits of the crawler up to at that
+// fetched the full page and that were answered by a 304 revalidation, visits
+// of deleted nodes and traps aside.
+func (observer *Observer) GetFetchCounts(crawlerID string, at time.Time) (fullFetches int, revalidations int) {
+	observer.mu.RLock()
+	defer observer.mu.RUnlock()
+
+	for _, visitLog := range observer.VisitHistory {
+		if visitLog.CrawlerID != CrawlerID(crawlerID) || visitLog.VisitedAt.After(at) || visitLog.Deleted || visitLog.Trap != "" {
+			continue
+		}
+		if visitLog.Revalidated {
+			revalidations++
+		} else {
+			fullFetches++
+		}
+	}
+	return fullFetches, revalidations
+}
+
+// GetInjectedDelay returns the total delay injected into the responses to
+// the crawler up to at and the number of delayed visits.
+func (observer *Observer) GetInjectedDelay(crawlerID string, at time.Time) (total time.Duration, delayedVisits int) {
+	observer.mu.RLock()
+	defer observer.mu.RUnlock()
+
+	for _, visitLog := range observer.VisitHistory {
+		if visitLog.CrawlerID != CrawlerID(crawlerID) || visitLog.VisitedAt.After(at) || visitLog.Delay == 0 {
+			continue
+		}
+		total += visitLog.Delay
+		delayedVisits++
+	}
+	return total, delayedVisits
+}
+
+// GetCrawlerIDs returns the distinct crawlers in the visit history in order of
+// first visit, followed by crawlers that were only answered faults.
+func (observer *Observer) GetCrawlerIDs() []CrawlerID {
+	observer.mu.RLock()
+	defer observer.mu.RUnlock()
+
+	seen := make(map[CrawlerID]bool)
+	crawlerIDs := make([]CrawlerID, 0)
+	for _, visitLog := range observer.VisitHistory {
+		if !seen[visitLog.CrawlerID] {
+			seen[visitLog.CrawlerID] = true
+			crawlerIDs = append(crawlerIDs, visitLog.CrawlerID)
+		}
+	}
+	for _, faultLog := range observer.FaultHistory {
+		if !seen[faultLog.CrawlerID] {
+			seen[faultLog.CrawlerID] = true
+			crawlerIDs = append(crawlerIDs, faultLog.CrawlerID)
+		}
+	}
+	return crawlerIDs
+}
+
+// LastActivity returns the time of the latest logged event.
+func (observer *Observer) LastActivity() time.Time {
+	observer.mu.RLock()
+	defer observer.mu.RUnlock()
+
+	var last time.Time
+	for _, nodeLog := range observer.NodeLogMap {
+		if nodeLog.CreatedAt.After(last) {
+			last = nodeLog.CreatedAt
+		}
+		if nodeLog.DeletedAt != nil && nodeLog.DeletedAt.After(last) {
+			last = *nodeLog.DeletedAt
+		}
+		for _, modifiedAt := range nodeLog.ModifiedAt {
+			if modifiedAt.After(last) {
+				last = modifiedAt
+			}
+		}
+	}
+	for _, visitLog := range observer.VisitHistory {
+		if visitLog.VisitedAt.After(last) {
+			last = visitLog.VisitedAt
+		}
+	}
+	for _, violationLog := range observer.ViolationHistory {
+		if violationLog.At.After(last) {
+			last = violationLog.At
+		}
+	}
+	for _, faultLog := range observer.FaultHistory {
+		if faultLog.At.After(last) {
+			last = faultLog.At
+		}
+	}
+	return last
 }
 
-func (observer *Observer) GetFreshness(ip string, at time.Time) float64 {
+// LogNodeModification appends a content modification to the history of the node.
+func (observer *Observer) LogNodeModification(nodeID NodeID, modifiedAt time.Time) {
+	observer.mu.Lock()
+	defer observer.mu.Unlock()
+
+	observer.logNodeEvent(NodeEvent{NodeID: nodeID, Type: NodeEventTypeModification, At: modifiedAt})
+}
+
+// LogNodeMove appends a move to another path to the history of the node.
+func (observer *Observer) LogNodeMove(nodeID NodeID, movedAt time.Time) {
+	observer.mu.Lock()
+	defer observer.mu.Unlock()
+
+	observer.logNodeEvent(NodeEvent{NodeID: nodeID, Type: NodeEventTypeMove, At: movedAt})
+}
+
+// GetURLUpdates counts the nodes moved up to at that the crawler visited
+// since their last move. A node is outdated when the latest of these visits
+// still went through a redirect from a former path, updated otherwise.
+func (observer *Observer) GetURLUpdates(crawlerID string, at time.Time) (updated, outdated int) {
+	observer.mu.RLock()
+	defer observer.mu.RUnlock()
+
+	lastMoves := make(map[NodeID]time.Time)
+	for nodeID, nodeLog := range observer.NodeLogMap {
+		for _, movedAt := range nodeLog.MovedAt {
+			if !movedAt.After(at) {
+				lastMoves[nodeID] = movedAt
+			}
+		}
+	}
+
+	latestVisits := make(map[NodeID]VisitLog)
+	for _, visitLog := range observer.VisitHistory {
+		if visitLog.CrawlerID != CrawlerID(crawlerID) || visitLog.VisitedAt.After(at) {
+			continue
+		}
+		lastMove, ok := lastMoves[visitLog.NodeID]
+		if !ok || visitLog.VisitedAt.Before(lastMove) {
+			continue
+		}
+		if latest, ok := latestVisits[visitLog.NodeID]; !ok || !visitLog.VisitedAt.Before(latest.VisitedAt) {
+			latestVisits[visitLog.NodeID] = visitLog
+		}
+	}
+
+	for _, visitLog := range latestVisits {
+		if visitLog.Redirected {
+			outdated++
+		} else {
+			updated++
+		}
+	}
+	return updated, outdated
+}
+
+// GetTrapRequests returns the number of requests the crawler wasted inside
+// spider traps up to at by trap type.
+func (observer *Observer) GetTrapRequests(crawlerID string, at time.Time) map[string]int {
+	observer.mu.RLock()
+	defer observer.mu.RUnlock()
+
+	counts := make(map[string]int)
+	for _, visitLog := range observer.VisitHistory {
+		if visitLog.CrawlerID == CrawlerID(crawlerID) && !visitLog.VisitedAt.After(at) && visitLog.Trap != "" {
+			counts[visitLog.Trap]++
+		}
+	}
+	return counts
+}
+
+// GetDuplicateScore scores the duplicate content among the full fetches of the
+// crawler up to at. A fetch is a duplicate when the crawler already fetched
+// the same node under another URL, counting 1, or another node duplicating
+// the same content, counting their similarity. The first fetch of a URL and
+// its refetches are not duplicates. It returns the sum of these counts and
+// its share of the full fetches.
+func (observer *Observer) GetDuplicateScore(crawlerID string, at time.Time) (duplicateFetches float64, score float64) {
+	observer.mu.RLock()
+	defer observer.mu.RUnlock()
+
+	fetchedURLs := make(map[NodeID]map[string]struct{})
+	fetchedNodes := make(map[NodeID][]NodeID)
+	fullFetches := 0
+	for _, visitLog := range observer.VisitHistory {
+		if visitLog.CrawlerID != CrawlerID(crawlerID) || visitLog.VisitedAt.After(at) ||
+			visitLog.Revalidated || visitLog.Deleted || visitLog.Trap != "" {
+			continue
+		}
+		fullFetches++
+
+		variants, ok := fetchedURLs[visitLog.NodeID]
+		if ok {
+			if _, ok := variants[visitLog.Variant]; !ok {
+				duplicateFetches++
+				variants[visitLog.Variant] = struct{}{}
+			}
+			continue
+		}
+		fetchedURLs[visitLog.NodeID] = map[string]struct{}{visitLog.Variant: {}}
+
+		// nodes duplicating the same content are grouped under the node
+		// they duplicate
+		nodeLog := observer.NodeLogMap[visitLog.NodeID]
+		group := visitLog.NodeID
+		if nodeLog.DuplicateOf != "" {
+			group = nodeLog.DuplicateOf
+		}
+		similarity := float64(0)
+		for _, nodeID := range fetchedNodes[group] {
+			similarity = max(similarity, nodeLog.similarity()*observer.NodeLogMap[nodeID].similarity())
+		}
+		duplicateFetches += similarity
+		fetchedNodes[group] = append(fetchedNodes[group], visitLog.NodeID)
+	}
+
+	if fullFetches == 0 {
+		return 0, 0
+	}
+	return duplicateFetches, duplicateFetches / float64(fullFetches)
+}
+
+// similarity is the share of the duplicated content the node keeps, 1 for
+// nodes that duplicate none.
+func (nodeLog NodeLog) similarity() float64 {
+	if nodeLog.DuplicateOf == "" {
+		return 1
+	}
+	return nodeLog.Similarity
+}
+
+// GetStaleIndex measures how long the crawler keeps deleted nodes in its
+// index. It returns the number of nodes deleted up to at that the crawler
+// fetched again after their deletion, and the mean time from the deletion to
+// the last of these fetches, the crawler keeping the node at least that long.
+// Nodes never fetched again after their deletion are not counted.
+func (observer *Observer) GetStaleIndex(crawlerID string, at time.Time) (staleNodes int, meanStaleTime time.Duration) {
+	observer.mu.RLock()
+	defer observer.mu.RUnlock()
+
+	lastFetches := make(map[NodeID]time.Time)
+	for _, visitLog := range observer.VisitHistory {
+		if visitLog.CrawlerID != CrawlerID(crawlerID) || visitLog.VisitedAt.After(at) || !visitLog.Deleted {
+			continue
+		}
+		if lastFetch, ok := lastFetches[visitLog.NodeID]; !ok || visitLog.VisitedAt.After(lastFetch) {
+			lastFetches[visitLog.NodeID] = visitLog.VisitedAt
+		}
+	}
+
+	var total time.Duration
+	for nodeID, lastFetch := range lastFetches {
+		nodeLog, ok := observer.NodeLogMap[nodeID]
+		if !ok || nodeLog.DeletedAt == nil || nodeLog.DeletedAt.After(lastFetch) {
+			continue
+		}
+		total += lastFetch.Sub(*nodeLog.DeletedAt)
+		staleNodes++
+	}
+
+	if staleNodes == 0 {
+		return 0, 0
+	}
+	return staleNodes, total / time.Duration(staleNodes)
+}
+
+// GetFreshness is a coverage metric: the fraction of nodes alive at the given
+// time that the crawler has visited at least once, regardless of modifications.
+func (observer *Observer) GetFreshness(crawlerID string, at time.Time) float64 {
+	observer.mu.RLock()
+	defer observer.mu.RUnlock()
+
 	archiveNodesMap := make(NodeLogMapType)
 	for ID, nodeLog := range observer.NodeLogMap {
 		if nodeLog.CreatedAt.Before(at) && (nodeLog.DeletedAt == nil ||
@@ -53,7 +602,7 @@ func (observer *Observer) GetFreshness(ip string, at time.Time) float64 {
 
 	visitedNodes := make(NodeLogMapType)
 	for _, visitLog := range observer.VisitHistory {
-		if visitLog.RemoteAddr == IPAddr(ip) && visitLog.VisitedAt.Before(at) {
+		if visitLog.CrawlerID == CrawlerID(crawlerID) && visitLog.VisitedAt.Before(at) {
 			if nodeLog, ok := archiveNodesMap[visitLog.NodeID]; ok {
 				visitedNodes[nodeLog.ID] = nodeLog
 			}
@@ -67,7 +616,12 @@ func (observer *Observer) GetFreshness(ip string, at time.Time) float64 {
 	return float64(len(visitedNodes)) / float64(len(archiveNodesMap))
 }
 
-func (observer *Observer) GetAge(ip string, at time.Time) time.Duration {
+// GetAge is a coverage metric: the mean delay between creation of nodes and
+// the last visit of the crawler, regardless of modifications.
+func (observer *Observer) GetAge(crawlerID string, at time.Time) time.Duration {
+	observer.mu.RLock()
+	defer observer.mu.RUnlock()
+
 	archiveNodesMap := make(NodeLogMapType)
 	for ID, nodeLog := range observer.NodeLogMap {
 		if nodeLog.CreatedAt.Before(at) && (nodeLog.DeletedAt == nil ||
@@ -76,9 +630,11 @@ func (observer *Observer) GetAge(ip string, at time.Time) time.Duration {
 		}
 	}
 
+	// visits of nodes deleted since count towards the mean with no age, trap
+	// pages are not nodes of the graph
 	visitByNodeIDMap := make(map[NodeID]VisitLog)
 	for _, visitLog := range observer.VisitHistory {
-		if visitLog.RemoteAddr == IPAddr(ip) && visitLog.VisitedAt.Before(at) {
+		if visitLog.CrawlerID == CrawlerID(crawlerID) && visitLog.VisitedAt.Before(at) && visitLog.Trap == "" {
 			visitByNodeIDMap[visitLog.NodeID] = visitLog
 		}
 	}
@@ -97,3 +653,145 @@ func (observer *Observer) GetAge(ip string, at time.Time) time.Duration {
 	age := time.Duration(float64(cumulativeTime) / float64(len(visitByNodeIDMap)))
 	return age
 }
+
+func (nodeLog NodeLog) isAlive(at time.Time) bool {
+	return nodeLog.CreatedAt.Before(at) && (nodeLog.DeletedAt == nil || nodeLog.DeletedAt.After(at))
+}
+
+// firstUnseenModification returns the first change of the node after lastVisit
+// and before at, creation being the first change when the node was never visited.
+func (nodeLog NodeLog) firstUnseenModification(lastVisit *time.Time, at time.Time) (time.Time, bool) {
+	if lastVisit == nil {
+		return nodeLog.CreatedAt, true
+	}
+
+	for _, modifiedAt := range nodeLog.ModifiedAt {
+		if modifiedAt.After(*lastVisit) && modifiedAt.Before(at) {
+			return modifiedAt, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func (nodeLog NodeLog) freshness(lastVisit *time.Time, at time.Time) float64 {
+	if _, ok := nodeLog.firstUnseenModification(lastVisit, at); ok {
+		return 0
+	}
+	return 1
+}
+
+func (nodeLog NodeLog) age(lastVisit *time.Time, at time.Time) time.Duration {
+	if modifiedAt, ok := nodeLog.firstUnseenModification(lastVisit, at); ok {
+		return at.Sub(modifiedAt)
+	}
+	return 0
+}
+
+func (observer *Observer) lastVisits(crawlerID string, at time.Time) map[NodeID]time.Time {
+	lastVisitMap := make(map[NodeID]time.Time)
+	for _, visitLog := range observer.VisitHistory {
+		if visitLog.CrawlerID != CrawlerID(crawlerID) || !visitLog.VisitedAt.Before(at) {
+			continue
+		}
+		if lastVisit, ok := lastVisitMap[visitLog.NodeID]; !ok || visitLog.VisitedAt.After(lastVisit) {
+			lastVisitMap[visitLog.NodeID] = visitLog.VisitedAt
+		}
+	}
+	return lastVisitMap
+}
+
+func (observer *Observer) lastVisit(crawlerID string, nodeID NodeID, at time.Time) *time.Time {
+	var lastVisit *time.Time
+	for _, visitLog := range observer.VisitHistory {
+		if visitLog.CrawlerID != CrawlerID(crawlerID) || visitLog.NodeID != nodeID || !visitLog.VisitedAt.Before(at) {
+			continue
+		}
+		if lastVisit == nil || visitLog.VisitedAt.After(*lastVisit) {
+			visitedAt := visitLog.VisitedAt
+			lastVisit = &visitedAt
+		}
+	}
+	return lastVisit
+}
+
+// GetPageFreshness returns 1 if the local copy of the crawler is up to date at the
+// given time, i.e. the node was fetched after its last modification, and 0
+// otherwise (Cho & Garcia-Molina). Nodes not alive at the given time are never fresh.
+func (observer *Observer) GetPageFreshness(crawlerID string, nodeID NodeID, at time.Time) float64 {
+	observer.mu.RLock()
+	defer observer.mu.RUnlock()
+
+	nodeLog, ok := observer.NodeLogMap[nodeID]
+	if !ok || !nodeLog.isAlive(at) {
+		return 0
+	}
+	return nodeLog.freshness(observer.lastVisit(crawlerID, nodeID, at), at)
+}
+
+// GetPageAge returns the time elapsed since the first modification of the node
+// unseen by the crawler, or since its creation if it was never fetched, and 0 if the
+// local copy is fresh (Cho & Garcia-Molina).
+func (observer *Observer) GetPageAge(crawlerID string, nodeID NodeID, at time.Time) time.Duration {
+	observer.mu.RLock()
+	defer observer.mu.RUnlock()
+
+	nodeLog, ok := observer.NodeLogMap[nodeID]
+	if !ok || !nodeLog.isAlive(at) {
+		return 0
+	}
+	return nodeLog.age(observer.lastVisit(crawlerID, nodeID, at), at)
+}
+
+// GetAverageFreshness returns the page freshness averaged over nodes alive at the given time.
+func (observer *Observer) GetAverageFreshness(crawlerID string, at time.Time) float64 {
+	observer.mu.RLock()
+	defer observer.mu.RUnlock()
+
+	lastVisitMap := observer.lastVisits(crawlerID, at)
+
+	count := 0
+	cumulativeFreshness := float64(0)
+	for nodeID, nodeLog := range observer.NodeLogMap {
+		if !nodeLog.isAlive(at) {
+			continue
+		}
+		var lastVisit *time.Time
+		if visitedAt, ok := lastVisitMap[nodeID]; ok {
+			lastVisit = &visitedAt
+		}
+		cumulativeFreshness += nodeLog.freshness(lastVisit, at)
+		count++
+	}
+
+	if count == 0 {
+		return 0
+	}
+	return cumulativeFreshness / float64(count)
+}
+
+// GetAverageAge returns the page age averaged over nodes alive at the given time.
+func (observer *Observer) GetAverageAge(crawlerID string, at time.Time) time.Duration {
+	observer.mu.RLock()
+	defer observer.mu.RUnlock()
+
+	lastVisitMap := observer.lastVisits(crawlerID, at)
+
+	count := 0
+	cumulativeAge := time.Duration(0)
+	for nodeID, nodeLog := range observer.NodeLogMap {
+		if !nodeLog.isAlive(at) {
+			continue
+		}
+		var lastVisit *time.Time
+		if visitedAt, ok := lastVisitMap[nodeID]; ok {
+			lastVisit = &visitedAt
+		}
+		cumulativeAge += nodeLog.age(lastVisit, at)
+		count++
+	}
+
+	if count == 0 {
+		return 0
+	}
+	return time.Duration(float64(cumulativeAge) / float64(count))
+}
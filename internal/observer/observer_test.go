@@ -1,6 +1,8 @@
 package observer_test
 
 import (
+	"fmt"
+	"sync"
 	"testing"
 	"time"
 
@@ -58,16 +60,16 @@ func TestLogVisit(t *testing.T) {
 			name: "Add one visit log",
 			visitHistory: observer.VisitHistoryType{
 				observer.VisitLog{
-					RemoteAddr: "1.1.1.1",
-					NodeID:     "node1",
-					VisitedAt:  now,
+					CrawlerID: "1.1.1.1",
+					NodeID:    "node1",
+					VisitedAt: now,
 				},
 			},
 			expectedVisitHistory: observer.VisitHistoryType{
 				observer.VisitLog{
-					RemoteAddr: "1.1.1.1",
-					NodeID:     "node1",
-					VisitedAt:  now,
+					CrawlerID: "1.1.1.1",
+					NodeID:    "node1",
+					VisitedAt: now,
 				},
 			},
 		},
@@ -93,7 +95,7 @@ func TestFreshness(t *testing.T) {
 		name              string
 		nodeLogMap        observer.NodeLogMapType
 		visitHistory      observer.VisitHistoryType
-		ip                observer.IPAddr
+		crawlerID         observer.CrawlerID
 		at                time.Time
 		expectedFreshness float64
 	}{
@@ -107,12 +109,12 @@ func TestFreshness(t *testing.T) {
 				},
 			},
 			visitHistory:      observer.VisitHistoryType{},
-			ip:                "1.1.1.1",
+			crawlerID:         "1.1.1.1",
 			at:                now.Add(1 * time.Hour),
 			expectedFreshness: 0,
 		},
 		{
-			name: "different ip visit",
+			name: "different crawler visit",
 			nodeLogMap: observer.NodeLogMapType{
 				"node1": observer.NodeLog{
 					ID:        "node1",
@@ -122,12 +124,12 @@ func TestFreshness(t *testing.T) {
 			},
 			visitHistory: observer.VisitHistoryType{
 				observer.VisitLog{
-					RemoteAddr: "1.1.1.2",
-					NodeID:     "node1",
-					VisitedAt:  now,
+					CrawlerID: "1.1.1.2",
+					NodeID:    "node1",
+					VisitedAt: now,
 				},
 			},
-			ip:                "1.1.1.1",
+			crawlerID:         "1.1.1.1",
 			at:                now.Add(1 * time.Hour),
 			expectedFreshness: 0,
 		},
@@ -142,12 +144,12 @@ func TestFreshness(t *testing.T) {
 			},
 			visitHistory: observer.VisitHistoryType{
 				observer.VisitLog{
-					RemoteAddr: "1.1.1.1",
-					NodeID:     "node1",
-					VisitedAt:  now,
+					CrawlerID: "1.1.1.1",
+					NodeID:    "node1",
+					VisitedAt: now,
 				},
 			},
-			ip:                "1.1.1.1",
+			crawlerID:         "1.1.1.1",
 			at:                now.Add(1 * time.Hour),
 			expectedFreshness: 0,
 		},
@@ -162,12 +164,12 @@ func TestFreshness(t *testing.T) {
 			},
 			visitHistory: observer.VisitHistoryType{
 				observer.VisitLog{
-					RemoteAddr: "1.1.1.1",
-					NodeID:     "node1",
-					VisitedAt:  now,
+					CrawlerID: "1.1.1.1",
+					NodeID:    "node1",
+					VisitedAt: now,
 				},
 			},
-			ip:                "1.1.1.1",
+			crawlerID:         "1.1.1.1",
 			at:                now.Add(1 * time.Hour),
 			expectedFreshness: 1,
 		},
@@ -182,12 +184,12 @@ func TestFreshness(t *testing.T) {
 			},
 			visitHistory: observer.VisitHistoryType{
 				observer.VisitLog{
-					RemoteAddr: "1.1.1.1",
-					NodeID:     "node1",
-					VisitedAt:  now.Add(10 * time.Minute),
+					CrawlerID: "1.1.1.1",
+					NodeID:    "node1",
+					VisitedAt: now.Add(10 * time.Minute),
 				},
 			},
-			ip:                "1.1.1.1",
+			crawlerID:         "1.1.1.1",
 			at:                now.Add(5 * time.Minute),
 			expectedFreshness: 0,
 		},
@@ -202,17 +204,17 @@ func TestFreshness(t *testing.T) {
 			},
 			visitHistory: observer.VisitHistoryType{
 				observer.VisitLog{
-					RemoteAddr: "1.1.1.1",
-					NodeID:     "node1",
-					VisitedAt:  now,
+					CrawlerID: "1.1.1.1",
+					NodeID:    "node1",
+					VisitedAt: now,
 				},
 				observer.VisitLog{
-					RemoteAddr: "1.1.1.1",
-					NodeID:     "node1",
-					VisitedAt:  now.Add(15 * time.Minute),
+					CrawlerID: "1.1.1.1",
+					NodeID:    "node1",
+					VisitedAt: now.Add(15 * time.Minute),
 				},
 			},
-			ip:                "1.1.1.1",
+			crawlerID:         "1.1.1.1",
 			at:                now.Add(1 * time.Hour),
 			expectedFreshness: 1,
 		},
@@ -242,22 +244,22 @@ func TestFreshness(t *testing.T) {
 			},
 			visitHistory: observer.VisitHistoryType{
 				observer.VisitLog{
-					RemoteAddr: "1.1.1.1",
-					NodeID:     "node1",
-					VisitedAt:  now,
+					CrawlerID: "1.1.1.1",
+					NodeID:    "node1",
+					VisitedAt: now,
 				},
 				observer.VisitLog{
-					RemoteAddr: "1.1.1.1",
-					NodeID:     "node4",
-					VisitedAt:  now,
+					CrawlerID: "1.1.1.1",
+					NodeID:    "node4",
+					VisitedAt: now,
 				},
 				observer.VisitLog{
-					RemoteAddr: "1.1.1.2",
-					NodeID:     "node3",
-					VisitedAt:  now,
+					CrawlerID: "1.1.1.2",
+					NodeID:    "node3",
+					VisitedAt: now,
 				},
 			},
-			ip:                "1.1.1.1",
+			crawlerID:         "1.1.1.1",
 			at:                now.Add(1 * time.Hour),
 			expectedFreshness: 0.5,
 		},
@@ -275,7 +277,7 @@ func TestFreshness(t *testing.T) {
 				o.LogVisit(visitLog)
 			}
 
-			assert.Equal(t, tc.expectedFreshness, o.GetFreshness(string(tc.ip), tc.at))
+			assert.Equal(t, tc.expectedFreshness, o.GetFreshness(string(tc.crawlerID), tc.at))
 		})
 	}
 }
@@ -287,7 +289,7 @@ func TestAge(t *testing.T) {
 		name         string
 		nodeLogMap   observer.NodeLogMapType
 		visitHistory observer.VisitHistoryType
-		ip           observer.IPAddr
+		crawlerID    observer.CrawlerID
 		at           time.Time
 		expectedAge  time.Duration
 	}{
@@ -301,12 +303,12 @@ func TestAge(t *testing.T) {
 				},
 			},
 			visitHistory: observer.VisitHistoryType{},
-			ip:           "1.1.1.1",
+			crawlerID:    "1.1.1.1",
 			at:           now.Add(1 * time.Hour),
 			expectedAge:  0,
 		},
 		{
-			name: "different ip visit",
+			name: "different crawler visit",
 			nodeLogMap: observer.NodeLogMapType{
 				"node1": observer.NodeLog{
 					ID:        "node1",
@@ -316,12 +318,12 @@ func TestAge(t *testing.T) {
 			},
 			visitHistory: observer.VisitHistoryType{
 				observer.VisitLog{
-					RemoteAddr: "1.1.1.2",
-					NodeID:     "node1",
-					VisitedAt:  now,
+					CrawlerID: "1.1.1.2",
+					NodeID:    "node1",
+					VisitedAt: now,
 				},
 			},
-			ip:          "1.1.1.1",
+			crawlerID:   "1.1.1.1",
 			at:          now.Add(1 * time.Hour),
 			expectedAge: 0,
 		},
@@ -336,12 +338,12 @@ func TestAge(t *testing.T) {
 			},
 			visitHistory: observer.VisitHistoryType{
 				observer.VisitLog{
-					RemoteAddr: "1.1.1.1",
-					NodeID:     "node1",
-					VisitedAt:  now,
+					CrawlerID: "1.1.1.1",
+					NodeID:    "node1",
+					VisitedAt: now,
 				},
 			},
-			ip:          "1.1.1.1",
+			crawlerID:   "1.1.1.1",
 			at:          now.Add(1 * time.Hour),
 			expectedAge: 0,
 		},
@@ -356,12 +358,12 @@ func TestAge(t *testing.T) {
 			},
 			visitHistory: observer.VisitHistoryType{
 				observer.VisitLog{
-					RemoteAddr: "1.1.1.1",
-					NodeID:     "node1",
-					VisitedAt:  now.Add(20 * time.Second),
+					CrawlerID: "1.1.1.1",
+					NodeID:    "node1",
+					VisitedAt: now.Add(20 * time.Second),
 				},
 			},
-			ip:          "1.1.1.1",
+			crawlerID:   "1.1.1.1",
 			at:          now.Add(1 * time.Hour),
 			expectedAge: time.Duration(20 * time.Second),
 		},
@@ -376,17 +378,17 @@ func TestAge(t *testing.T) {
 			},
 			visitHistory: observer.VisitHistoryType{
 				observer.VisitLog{
-					RemoteAddr: "1.1.1.1",
-					NodeID:     "node1",
-					VisitedAt:  now,
+					CrawlerID: "1.1.1.1",
+					NodeID:    "node1",
+					VisitedAt: now,
 				},
 				observer.VisitLog{
-					RemoteAddr: "1.1.1.1",
-					NodeID:     "node1",
-					VisitedAt:  now.Add(15 * time.Minute),
+					CrawlerID: "1.1.1.1",
+					NodeID:    "node1",
+					VisitedAt: now.Add(15 * time.Minute),
 				},
 			},
-			ip:          "1.1.1.1",
+			crawlerID:   "1.1.1.1",
 			at:          now.Add(1 * time.Hour),
 			expectedAge: time.Duration(15 * time.Minute),
 		},
@@ -416,25 +418,55 @@ func TestAge(t *testing.T) {
 			},
 			visitHistory: observer.VisitHistoryType{
 				observer.VisitLog{
-					RemoteAddr: "1.1.1.1",
-					NodeID:     "node1",
-					VisitedAt:  now.Add(15 * time.Minute),
+					CrawlerID: "1.1.1.1",
+					NodeID:    "node1",
+					VisitedAt: now.Add(15 * time.Minute),
 				},
 				observer.VisitLog{
-					RemoteAddr: "1.1.1.1",
-					NodeID:     "node4",
-					VisitedAt:  now.Add(45 * time.Minute),
+					CrawlerID: "1.1.1.1",
+					NodeID:    "node4",
+					VisitedAt: now.Add(45 * time.Minute),
 				},
 				observer.VisitLog{
-					RemoteAddr: "1.1.1.2",
-					NodeID:     "node3",
-					VisitedAt:  now,
+					CrawlerID: "1.1.1.2",
+					NodeID:    "node3",
+					VisitedAt: now,
 				},
 			},
-			ip:          "1.1.1.1",
+			crawlerID:   "1.1.1.1",
 			at:          now.Add(1 * time.Hour),
 			expectedAge: time.Duration(30 * time.Minute),
 		},
+		{
+			name: "deleted node visit",
+			nodeLogMap: observer.NodeLogMapType{
+				"node1": observer.NodeLog{
+					ID:        "node1",
+					CreatedAt: now,
+					DeletedAt: nil,
+				},
+				"node2": observer.NodeLog{
+					ID:        "node2",
+					CreatedAt: now,
+					DeletedAt: &deletedAt_deleted_node,
+				},
+			},
+			visitHistory: observer.VisitHistoryType{
+				observer.VisitLog{
+					CrawlerID: "1.1.1.1",
+					NodeID:    "node1",
+					VisitedAt: now.Add(30 * time.Minute),
+				},
+				observer.VisitLog{
+					CrawlerID: "1.1.1.1",
+					NodeID:    "node2",
+					VisitedAt: now.Add(10 * time.Minute),
+				},
+			},
+			crawlerID:   "1.1.1.1",
+			at:          now.Add(1 * time.Hour),
+			expectedAge: time.Duration(15 * time.Minute),
+		},
 	}
 
 	for _, tc := range testCases {
@@ -449,7 +481,446 @@ func TestAge(t *testing.T) {
 				o.LogVisit(visitLog)
 			}
 
-			assert.Equal(t, tc.expectedAge, o.GetAge(string(tc.ip), tc.at))
+			assert.Equal(t, tc.expectedAge, o.GetAge(string(tc.crawlerID), tc.at))
+		})
+	}
+}
+
+func TestLogNodeModification(t *testing.T) {
+	now := time.Now()
+	o := observer.New()
+	o.LogNode(observer.NodeLog{ID: "node1", CreatedAt: now})
+	o.LogNodeModification("node1", now.Add(time.Minute))
+	o.LogNodeModification("node2", now.Add(time.Minute))
+
+	assert.Equal(t, []time.Time{now.Add(time.Minute)}, o.NodeLogMap["node1"].ModifiedAt)
+	assert.Len(t, o.NodeLogMap, 1, "Modification of unknown node should be ignored")
+}
+
+func TestViolationCounts(t *testing.T) {
+	now := time.Now()
+	o := observer.New()
+	o.LogViolation(observer.ViolationLog{CrawlerID: "1.1.1.1", Type: observer.ViolationTypeDisallowed, Path: "/a", At: now})
+	o.LogViolation(observer.ViolationLog{CrawlerID: "1.1.1.1", Type: observer.ViolationTypeDisallowed, Path: "/a/b", At: now.Add(time.Minute)})
+	o.LogViolation(observer.ViolationLog{CrawlerID: "1.1.1.1", Type: observer.ViolationTypeCrawlDelay, Path: "/", At: now.Add(time.Minute)})
+	o.LogViolation(observer.ViolationLog{CrawlerID: "2.2.2.2", Type: observer.ViolationTypeCrawlDelay, Path: "/", At: now})
+
+	tests := []struct {
+		name      string
+		crawlerID string
+		at        time.Time
+		expected  map[observer.ViolationType]int
+	}{
+		{"before violations", "1.1.1.1", now.Add(-time.Minute), map[observer.ViolationType]int{}},
+		{"first violation", "1.1.1.1", now, map[observer.ViolationType]int{observer.ViolationTypeDisallowed: 1}},
+		{"all violations", "1.1.1.1", now.Add(time.Hour), map[observer.ViolationType]int{
+			observer.ViolationTypeDisallowed: 2,
+			observer.ViolationTypeCrawlDelay: 1,
+		}},
+		{"other crawler", "2.2.2.2", now.Add(time.Hour), map[observer.ViolationType]int{observer.ViolationTypeCrawlDelay: 1}},
+		{"unknown crawler", "3.3.3.3", now.Add(time.Hour), map[observer.ViolationType]int{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, o.GetViolationCounts(tt.crawlerID, tt.at))
+		})
+	}
+}
+
+func TestPageFreshnessAndAge(t *testing.T) {
+	now := time.Now()
+	deletedAt := now.Add(30 * time.Minute)
+	testCases := []struct {
+		name              string
+		nodeLog           observer.NodeLog
+		visitHistory      observer.VisitHistoryType
+		at                time.Time
+		expectedFreshness float64
+		expectedAge       time.Duration
+	}{
+		{
+			name:              "never visited",
+			nodeLog:           observer.NodeLog{ID: "node1", CreatedAt: now},
+			visitHistory:      observer.VisitHistoryType{},
+			at:                now.Add(1 * time.Hour),
+			expectedFreshness: 0,
+			expectedAge:       1 * time.Hour,
+		},
+		{
+			name:    "visited without modification",
+			nodeLog: observer.NodeLog{ID: "node1", CreatedAt: now},
+			visitHistory: observer.VisitHistoryType{
+				{CrawlerID: "1.1.1.1", NodeID: "node1", VisitedAt: now.Add(10 * time.Minute)},
+			},
+			at:                now.Add(1 * time.Hour),
+			expectedFreshness: 1,
+			expectedAge:       0,
+		},
+		{
+			name: "modified after visit",
+			nodeLog: observer.NodeLog{ID: "node1", CreatedAt: now, ModifiedAt: []time.Time{
+				now.Add(5 * time.Minute), now.Add(20 * time.Minute), now.Add(40 * time.Minute),
+			}},
+			visitHistory: observer.VisitHistoryType{
+				{CrawlerID: "1.1.1.1", NodeID: "node1", VisitedAt: now.Add(10 * time.Minute)},
+			},
+			at:                now.Add(1 * time.Hour),
+			expectedFreshness: 0,
+			expectedAge:       40 * time.Minute,
+		},
+		{
+			name: "revisited after modification",
+			nodeLog: observer.NodeLog{ID: "node1", CreatedAt: now, ModifiedAt: []time.Time{
+				now.Add(20 * time.Minute),
+			}},
+			visitHistory: observer.VisitHistoryType{
+				{CrawlerID: "1.1.1.1", NodeID: "node1", VisitedAt: now.Add(10 * time.Minute)},
+				{CrawlerID: "1.1.1.1", NodeID: "node1", VisitedAt: now.Add(30 * time.Minute)},
+			},
+			at:                now.Add(1 * time.Hour),
+			expectedFreshness: 1,
+			expectedAge:       0,
+		},
+		{
+			name: "modification after at",
+			nodeLog: observer.NodeLog{ID: "node1", CreatedAt: now, ModifiedAt: []time.Time{
+				now.Add(2 * time.Hour),
+			}},
+			visitHistory: observer.VisitHistoryType{
+				{CrawlerID: "1.1.1.1", NodeID: "node1", VisitedAt: now.Add(10 * time.Minute)},
+			},
+			at:                now.Add(1 * time.Hour),
+			expectedFreshness: 1,
+			expectedAge:       0,
+		},
+		{
+			name:    "different crawler visit",
+			nodeLog: observer.NodeLog{ID: "node1", CreatedAt: now},
+			visitHistory: observer.VisitHistoryType{
+				{CrawlerID: "1.1.1.2", NodeID: "node1", VisitedAt: now.Add(10 * time.Minute)},
+			},
+			at:                now.Add(1 * time.Hour),
+			expectedFreshness: 0,
+			expectedAge:       1 * time.Hour,
+		},
+		{
+			name:    "deleted node",
+			nodeLog: observer.NodeLog{ID: "node1", CreatedAt: now, DeletedAt: &deletedAt},
+			visitHistory: observer.VisitHistoryType{
+				{CrawlerID: "1.1.1.1", NodeID: "node1", VisitedAt: now.Add(10 * time.Minute)},
+			},
+			at:                now.Add(1 * time.Hour),
+			expectedFreshness: 0,
+			expectedAge:       0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			o := observer.New()
+			o.LogNode(tc.nodeLog)
+			for _, visitLog := range tc.visitHistory {
+				o.LogVisit(visitLog)
+			}
+
+			assert.Equal(t, tc.expectedFreshness, o.GetPageFreshness("1.1.1.1", tc.nodeLog.ID, tc.at))
+			assert.Equal(t, tc.expectedAge, o.GetPageAge("1.1.1.1", tc.nodeLog.ID, tc.at))
 		})
 	}
 }
+
+func TestAverageFreshnessAndAge(t *testing.T) {
+	now := time.Now()
+	o := observer.New()
+	o.LogNode(observer.NodeLog{ID: "node1", CreatedAt: now})
+	o.LogNode(observer.NodeLog{ID: "node2", CreatedAt: now, ModifiedAt: []time.Time{now.Add(30 * time.Minute)}})
+	o.LogVisit(observer.VisitLog{CrawlerID: "1.1.1.1", NodeID: "node1", VisitedAt: now.Add(10 * time.Minute)})
+	o.LogVisit(observer.VisitLog{CrawlerID: "1.1.1.1", NodeID: "node2", VisitedAt: now.Add(10 * time.Minute)})
+
+	at := now.Add(1 * time.Hour)
+	assert.Equal(t, 0.5, o.GetAverageFreshness("1.1.1.1", at))
+	assert.Equal(t, 15*time.Minute, o.GetAverageAge("1.1.1.1", at))
+	assert.Equal(t, float64(0), o.GetAverageFreshness("1.1.1.1", now.Add(-time.Minute)))
+}
+
+func TestConcurrentLogging(t *testing.T) {
+	now := time.Now()
+	o := observer.New()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			nodeID := observer.NodeID(fmt.Sprintf("node%d", i))
+			o.LogNode(observer.NodeLog{ID: nodeID, CreatedAt: now})
+			for j := 0; j < 100; j++ {
+				o.LogVisit(observer.VisitLog{CrawlerID: "1.1.1.1", NodeID: nodeID, VisitedAt: now})
+				o.LogNodeModification(nodeID, now)
+				o.GetAverageFreshness("1.1.1.1", now.Add(time.Minute))
+			}
+			o.LogNodeDeletion(nodeID, now.Add(time.Hour))
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Len(t, o.VisitHistory, 1000)
+	assert.Len(t, o.NodeLogMap, 10)
+	assert.NotNil(t, o.NodeLogMap["node0"].DeletedAt)
+}
+
+func TestFetchCounts(t *testing.T) {
+	now := time.Now()
+	o := observer.New()
+	o.LogVisit(observer.VisitLog{CrawlerID: "1.1.1.1", NodeID: "node1", VisitedAt: now})
+	o.LogVisit(observer.VisitLog{CrawlerID: "1.1.1.1", NodeID: "node1", VisitedAt: now.Add(time.Minute), Revalidated: true})
+	o.LogVisit(observer.VisitLog{CrawlerID: "1.1.1.1", NodeID: "node2", VisitedAt: now.Add(time.Minute), Revalidated: true})
+	o.LogVisit(observer.VisitLog{CrawlerID: "2.2.2.2", NodeID: "node1", VisitedAt: now})
+
+	tests := []struct {
+		name                  string
+		crawlerID             string
+		at                    time.Time
+		expectedFullFetches   int
+		expectedRevalidations int
+	}{
+		{"before visits", "1.1.1.1", now.Add(-time.Minute), 0, 0},
+		{"first visit", "1.1.1.1", now, 1, 0},
+		{"all visits", "1.1.1.1", now.Add(time.Hour), 1, 2},
+		{"other crawler", "2.2.2.2", now.Add(time.Hour), 1, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fullFetches, revalidations := o.GetFetchCounts(tt.crawlerID, tt.at)
+			assert.Equal(t, tt.expectedFullFetches, fullFetches)
+			assert.Equal(t, tt.expectedRevalidations, revalidations)
+		})
+	}
+}
+
+func TestInjectedDelay(t *testing.T) {
+	now := time.Now()
+	o := observer.New()
+	o.LogVisit(observer.VisitLog{CrawlerID: "1.1.1.1", NodeID: "node1", VisitedAt: now, Delay: time.Second})
+	o.LogVisit(observer.VisitLog{CrawlerID: "1.1.1.1", NodeID: "node2", VisitedAt: now})
+	o.LogVisit(observer.VisitLog{CrawlerID: "1.1.1.1", NodeID: "node1", VisitedAt: now.Add(time.Minute), Delay: 3 * time.Second})
+	o.LogVisit(observer.VisitLog{CrawlerID: "2.2.2.2", NodeID: "node1", VisitedAt: now, Delay: time.Second})
+
+	tests := []struct {
+		name                  string
+		crawlerID             string
+		at                    time.Time
+		expectedTotal         time.Duration
+		expectedDelayedVisits int
+	}{
+		{"before visits", "1.1.1.1", now.Add(-time.Minute), 0, 0},
+		{"first visits", "1.1.1.1", now, time.Second, 1},
+		{"all visits", "1.1.1.1", now.Add(time.Hour), 4 * time.Second, 2},
+		{"other crawler", "2.2.2.2", now.Add(time.Hour), time.Second, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			total, delayedVisits := o.GetInjectedDelay(tt.crawlerID, tt.at)
+			assert.Equal(t, tt.expectedTotal, total)
+			assert.Equal(t, tt.expectedDelayedVisits, delayedVisits)
+		})
+	}
+}
+
+func TestURLUpdates(t *testing.T) {
+	now := time.Now()
+	o := observer.New()
+	for _, nodeID := range []observer.NodeID{"node1", "node2", "node3"} {
+		o.LogNode(observer.NodeLog{ID: nodeID, CreatedAt: now.Add(-time.Hour)})
+	}
+	o.LogNodeMove("node1", now)
+	o.LogNodeMove("node2", now)
+	// visited before the move only, it does not count
+	o.LogVisit(observer.VisitLog{CrawlerID: "1.1.1.1", NodeID: "node1", VisitedAt: now.Add(-time.Minute)})
+	// still following the redirect from the former path
+	o.LogVisit(observer.VisitLog{CrawlerID: "1.1.1.1", NodeID: "node1", VisitedAt: now.Add(time.Minute), Redirected: true})
+	// redirected once, then visited at the new path
+	o.LogVisit(observer.VisitLog{CrawlerID: "1.1.1.1", NodeID: "node2", VisitedAt: now.Add(time.Minute), Redirected: true})
+	o.LogVisit(observer.VisitLog{CrawlerID: "1.1.1.1", NodeID: "node2", VisitedAt: now.Add(2 * time.Minute)})
+	// never moved
+	o.LogVisit(observer.VisitLog{CrawlerID: "1.1.1.1", NodeID: "node3", VisitedAt: now.Add(time.Minute)})
+
+	tests := []struct {
+		name             string
+		crawlerID        string
+		at               time.Time
+		expectedUpdated  int
+		expectedOutdated int
+	}{
+		{"before moves", "1.1.1.1", now.Add(-time.Second), 0, 0},
+		{"redirected visits", "1.1.1.1", now.Add(time.Minute), 0, 2},
+		{"all visits", "1.1.1.1", now.Add(time.Hour), 1, 1},
+		{"other crawler", "2.2.2.2", now.Add(time.Hour), 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updated, outdated := o.GetURLUpdates(tt.crawlerID, tt.at)
+			assert.Equal(t, tt.expectedUpdated, updated)
+			assert.Equal(t, tt.expectedOutdated, outdated)
+		})
+	}
+}
+
+func TestStaleIndex(t *testing.T) {
+	now := time.Now()
+	o := observer.New()
+	for _, nodeID := range []observer.NodeID{"node1", "node2", "node3"} {
+		o.LogNode(observer.NodeLog{ID: nodeID, CreatedAt: now.Add(-time.Hour)})
+	}
+	o.LogNodeDeletion("node1", now)
+	o.LogNodeDeletion("node2", now)
+	o.LogVisit(observer.VisitLog{CrawlerID: "1.1.1.1", NodeID: "node1", VisitedAt: now.Add(-time.Minute)})
+	// node1 is kept for ten minutes, node2 for an hour
+	o.LogVisit(observer.VisitLog{CrawlerID: "1.1.1.1", NodeID: "node1", VisitedAt: now.Add(5 * time.Minute), Deleted: true})
+	o.LogVisit(observer.VisitLog{CrawlerID: "1.1.1.1", NodeID: "node1", VisitedAt: now.Add(10 * time.Minute), Deleted: true})
+	o.LogVisit(observer.VisitLog{CrawlerID: "1.1.1.1", NodeID: "node2", VisitedAt: now.Add(time.Hour), Deleted: true})
+	o.LogVisit(observer.VisitLog{CrawlerID: "1.1.1.1", NodeID: "node3", VisitedAt: now.Add(time.Hour)})
+
+	tests := []struct {
+		name                  string
+		crawlerID             string
+		at                    time.Time
+		expectedStaleNodes    int
+		expectedMeanStaleTime time.Duration
+	}{
+		{"before deletions", "1.1.1.1", now, 0, 0},
+		{"first fetches", "1.1.1.1", now.Add(5 * time.Minute), 1, 5 * time.Minute},
+		{"all fetches", "1.1.1.1", now.Add(2 * time.Hour), 2, 35 * time.Minute},
+		{"other crawler", "2.2.2.2", now.Add(2 * time.Hour), 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			staleNodes, meanStaleTime := o.GetStaleIndex(tt.crawlerID, tt.at)
+			assert.Equal(t, tt.expectedStaleNodes, staleNodes)
+			assert.Equal(t, tt.expectedMeanStaleTime, meanStaleTime)
+		})
+	}
+
+	fullFetches, _ := o.GetFetchCounts("1.1.1.1", now.Add(2*time.Hour))
+	assert.Equal(t, 2, fullFetches, "Visits of deleted nodes should not count as fetches")
+}
+
+func TestTrapRequests(t *testing.T) {
+	now := time.Now()
+	o := observer.New()
+	o.LogNode(observer.NodeLog{ID: "node1", CreatedAt: now.Add(-time.Hour)})
+	o.LogVisit(observer.VisitLog{CrawlerID: "1.1.1.1", NodeID: "node1", VisitedAt: now})
+	o.LogVisit(observer.VisitLog{CrawlerID: "1.1.1.1", NodeID: "trap1", VisitedAt: now, Trap: "calendar"})
+	o.LogVisit(observer.VisitLog{CrawlerID: "1.1.1.1", NodeID: "trap2", VisitedAt: now.Add(time.Minute), Trap: "calendar"})
+	o.LogVisit(observer.VisitLog{CrawlerID: "1.1.1.1", NodeID: "trap3", VisitedAt: now.Add(time.Minute), Trap: "facets"})
+	o.LogVisit(observer.VisitLog{CrawlerID: "2.2.2.2", NodeID: "trap1", VisitedAt: now, Trap: "calendar"})
+
+	tests := []struct {
+		name      string
+		crawlerID string
+		at        time.Time
+		expected  map[string]int
+	}{
+		{"before visits", "1.1.1.1", now.Add(-time.Minute), map[string]int{}},
+		{"first visits", "1.1.1.1", now, map[string]int{"calendar": 1}},
+		{"all visits", "1.1.1.1", now.Add(time.Hour), map[string]int{"calendar": 2, "facets": 1}},
+		{"other crawler", "2.2.2.2", now.Add(time.Hour), map[string]int{"calendar": 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, o.GetTrapRequests(tt.crawlerID, tt.at))
+		})
+	}
+
+	// trap pages are not nodes of the graph
+	fullFetches, _ := o.GetFetchCounts("1.1.1.1", now.Add(time.Hour))
+	assert.Equal(t, 1, fullFetches)
+	assert.Equal(t, time.Hour, o.GetAge("1.1.1.1", now.Add(time.Hour)))
+}
+
+func TestDuplicateScore(t *testing.T) {
+	now := time.Now()
+	o := observer.New()
+	o.LogNode(observer.NodeLog{ID: "original", CreatedAt: now.Add(-time.Hour)})
+	o.LogNode(observer.NodeLog{ID: "near", CreatedAt: now.Add(-time.Hour), DuplicateOf: "original", Similarity: 0.8})
+	o.LogNode(observer.NodeLog{ID: "loose", CreatedAt: now.Add(-time.Hour), DuplicateOf: "original", Similarity: 0.5})
+	o.LogNode(observer.NodeLog{ID: "other", CreatedAt: now.Add(-time.Hour)})
+	o.LogVisit(observer.VisitLog{CrawlerID: "1.1.1.1", NodeID: "original", VisitedAt: now})
+	o.LogVisit(observer.VisitLog{CrawlerID: "1.1.1.1", NodeID: "original", VisitedAt: now.Add(1 * time.Minute)})
+	o.LogVisit(observer.VisitLog{CrawlerID: "1.1.1.1", NodeID: "original", VisitedAt: now.Add(2 * time.Minute), Variant: "trailing-slash"})
+	o.LogVisit(observer.VisitLog{CrawlerID: "1.1.1.1", NodeID: "near", VisitedAt: now.Add(3 * time.Minute)})
+	o.LogVisit(observer.VisitLog{CrawlerID: "1.1.1.1", NodeID: "loose", VisitedAt: now.Add(4 * time.Minute)})
+	o.LogVisit(observer.VisitLog{CrawlerID: "1.1.1.1", NodeID: "other", VisitedAt: now.Add(5 * time.Minute)})
+	o.LogVisit(observer.VisitLog{CrawlerID: "1.1.1.1", NodeID: "near", VisitedAt: now.Add(6 * time.Minute), Variant: "query", Revalidated: true})
+	o.LogVisit(observer.VisitLog{CrawlerID: "2.2.2.2", NodeID: "loose", VisitedAt: now})
+	o.LogVisit(observer.VisitLog{CrawlerID: "2.2.2.2", NodeID: "near", VisitedAt: now.Add(time.Minute)})
+
+	tests := []struct {
+		name                     string
+		crawlerID                string
+		at                       time.Time
+		expectedDuplicateFetches float64
+		expectedScore            float64
+	}{
+		{"no fetches", "1.1.1.1", now.Add(-time.Minute), 0, 0},
+		{"refetch", "1.1.1.1", now.Add(time.Minute), 0, 0},
+		{"URL variant", "1.1.1.1", now.Add(2 * time.Minute), 1, 1.0 / 3},
+		{"duplicates", "1.1.1.1", now.Add(time.Hour), 1 + 0.8 + 0.5, 2.3 / 6},
+		{"duplicates of the same page", "2.2.2.2", now.Add(time.Hour), 0.4, 0.2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			duplicateFetches, score := o.GetDuplicateScore(tt.crawlerID, tt.at)
+			assert.InDelta(t, tt.expectedDuplicateFetches, duplicateFetches, 1e-9)
+			assert.InDelta(t, tt.expectedScore, score, 1e-9)
+		})
+	}
+}
+
+func TestFaultCounts(t *testing.T) {
+	now := time.Now()
+	o := observer.New()
+	o.LogFault(observer.FaultLog{CrawlerID: "1.1.1.1", Type: observer.FaultTypeServiceUnavailable, Path: "/a", At: now})
+	o.LogFault(observer.FaultLog{CrawlerID: "1.1.1.1", Type: observer.FaultTypeServiceUnavailable, Path: "/b", At: now.Add(time.Minute)})
+	o.LogFault(observer.FaultLog{CrawlerID: "1.1.1.1", Type: observer.FaultTypeReset, Path: "/", At: now.Add(time.Minute)})
+	o.LogFault(observer.FaultLog{CrawlerID: "2.2.2.2", Type: observer.FaultTypeTimeout, Path: "/", At: now})
+
+	tests := []struct {
+		name      string
+		crawlerID string
+		at        time.Time
+		expected  map[observer.FaultType]int
+	}{
+		{"before faults", "1.1.1.1", now.Add(-time.Minute), map[observer.FaultType]int{}},
+		{"first fault", "1.1.1.1", now, map[observer.FaultType]int{observer.FaultTypeServiceUnavailable: 1}},
+		{"all faults", "1.1.1.1", now.Add(time.Hour), map[observer.FaultType]int{
+			observer.FaultTypeServiceUnavailable: 2,
+			observer.FaultTypeReset:              1,
+		}},
+		{"other crawler", "2.2.2.2", now.Add(time.Hour), map[observer.FaultType]int{observer.FaultTypeTimeout: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, o.GetFaultCounts(tt.crawlerID, tt.at))
+		})
+	}
+}
+
+func TestGetCrawlerIDs(t *testing.T) {
+	now := time.Now()
+	o := observer.New()
+	o.LogVisit(observer.VisitLog{CrawlerID: "2.2.2.2", NodeID: "node1", VisitedAt: now})
+	o.LogFault(observer.FaultLog{CrawlerID: "3.3.3.3", Type: observer.FaultTypeBanned, Path: "/", At: now})
+	o.LogVisit(observer.VisitLog{CrawlerID: "1.1.1.1", NodeID: "node1", VisitedAt: now})
+	o.LogFault(observer.FaultLog{CrawlerID: "1.1.1.1", Type: observer.FaultTypeRateLimited, Path: "/", At: now})
+
+	assert.Equal(t, []observer.CrawlerID{"2.2.2.2", "1.1.1.1", "3.3.3.3"}, o.GetCrawlerIDs())
+}
@@ -0,0 +1,151 @@
+package observer
+
+import (
+	"sync"
+	"time"
+)
+
+// Storage persists the logs of an Observer. The observer keeps its logs in
+// memory for querying and writes every change through to its storage, node
+// logs being saved as a whole when created and changed by node events after.
+type Storage interface {
+	SaveNode(nodeLog NodeLog) error
+	// SaveNodeEvent saves a change of a saved node log.
+	SaveNodeEvent(event NodeEvent) error
+	SaveVisit(visitLog VisitLog) error
+	SaveViolation(violationLog ViolationLog) error
+	SaveFault(faultLog FaultLog) error
+	// Load returns the latest version of every saved node log, its events
+	// applied, and all saved visit, violation and fault logs in the order they
+	// were saved.
+	Load() (NodeLogMapType, VisitHistoryType, ViolationHistoryType, FaultHistoryType, error)
+	Close() error
+}
+
+type NodeEventType string
+
+const (
+	NodeEventTypeDeletion     NodeEventType = "deletion"
+	NodeEventTypeModification NodeEventType = "modification"
+	NodeEventTypeMove         NodeEventType = "move"
+)
+
+// NodeEvent is a change of a node log, saved on its own so that storages
+// need not rewrite the whole history of the node.
+type NodeEvent struct {
+	NodeID NodeID
+	Type   NodeEventType
+	At     time.Time
+}
+
+// apply returns the node log changed by the event.
+func (nodeLog NodeLog) apply(event NodeEvent) NodeLog {
+	switch event.Type {
+	case NodeEventTypeDeletion:
+		deletedAt := event.At
+		nodeLog.DeletedAt = &deletedAt
+	case NodeEventTypeModification:
+		nodeLog.ModifiedAt = append(nodeLog.ModifiedAt, event.At)
+	case NodeEventTypeMove:
+		nodeLog.MovedAt = append(nodeLog.MovedAt, event.At)
+	}
+	return nodeLog
+}
+
+var _ Storage = &MemoryStorage{}
+
+// MemoryStorage keeps logs in memory, they are lost when the process exits.
+type MemoryStorage struct {
+	nodeLogMap       NodeLogMapType
+	visitHistory     VisitHistoryType
+	violationHistory ViolationHistoryType
+	faultHistory     FaultHistoryType
+	mu               sync.Mutex
+}
+
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{
+		nodeLogMap:       make(NodeLogMapType),
+		visitHistory:     make(VisitHistoryType, 0),
+		violationHistory: make(ViolationHistoryType, 0),
+		faultHistory:     make(FaultHistoryType, 0),
+	}
+}
+
+func (storage *MemoryStorage) SaveNode(nodeLog NodeLog) error {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+
+	storage.nodeLogMap[nodeLog.ID] = nodeLog.clone()
+	return nil
+}
+
+func (storage *MemoryStorage) SaveNodeEvent(event NodeEvent) error {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+
+	if nodeLog, ok := storage.nodeLogMap[event.NodeID]; ok {
+		storage.nodeLogMap[event.NodeID] = nodeLog.apply(event)
+	}
+	return nil
+}
+
+func (storage *MemoryStorage) SaveVisit(visitLog VisitLog) error {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+
+	storage.visitHistory = append(storage.visitHistory, visitLog)
+	return nil
+}
+
+func (storage *MemoryStorage) SaveViolation(violationLog ViolationLog) error {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+
+	storage.violationHistory = append(storage.violationHistory, violationLog)
+	return nil
+}
+
+func (storage *MemoryStorage) SaveFault(faultLog FaultLog) error {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+
+	storage.faultHistory = append(storage.faultHistory, faultLog)
+	return nil
+}
+
+func (storage *MemoryStorage) Load() (NodeLogMapType, VisitHistoryType, ViolationHistoryType, FaultHistoryType, error) {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+
+	nodeLogMap := make(NodeLogMapType, len(storage.nodeLogMap))
+	for nodeID, nodeLog := range storage.nodeLogMap {
+		nodeLogMap[nodeID] = nodeLog.clone()
+	}
+	visitHistory := make(VisitHistoryType, len(storage.visitHistory))
+	copy(visitHistory, storage.visitHistory)
+	violationHistory := make(ViolationHistoryType, len(storage.violationHistory))
+	copy(violationHistory, storage.violationHistory)
+	faultHistory := make(FaultHistoryType, len(storage.faultHistory))
+	copy(faultHistory, storage.faultHistory)
+	return nodeLogMap, visitHistory, violationHistory, faultHistory, nil
+}
+
+func (storage *MemoryStorage) Close() error {
+	return nil
+}
+
+// clone copies the node log so that it shares no memory with the original.
+func (nodeLog NodeLog) clone() NodeLog {
+	if nodeLog.DeletedAt != nil {
+		deletedAt := *nodeLog.DeletedAt
+		nodeLog.DeletedAt = &deletedAt
+	}
+	if nodeLog.ModifiedAt != nil {
+		nodeLog.ModifiedAt = append([]time.Time(nil), nodeLog.ModifiedAt...)
+	}
+	if nodeLog.MovedAt != nil {
+		nodeLog.MovedAt = append([]time.Time(nil), nodeLog.MovedAt...)
+	}
+	return nodeLog
+}
@@ -0,0 +1,32 @@
+package observer_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sdqri/sequined/internal/observer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStorage(t *testing.T) {
+	now := time.Now()
+	storage := observer.NewMemoryStorage()
+
+	o, err := observer.Open(storage)
+	require.NoError(t, err)
+	o.LogNode(observer.NodeLog{ID: "node1", CreatedAt: now})
+	o.LogNodeModification("node1", now.Add(time.Minute))
+	o.LogVisit(observer.VisitLog{CrawlerID: "1.1.1.1", NodeID: "node1", VisitedAt: now})
+
+	nodeLogMap, visitHistory, _, _, err := storage.Load()
+	require.NoError(t, err)
+	assert.Equal(t, o.NodeLogMap, nodeLogMap)
+	assert.Equal(t, o.VisitHistory, visitHistory)
+
+	// loaded logs share no memory with the storage
+	nodeLogMap["node1"].ModifiedAt[0] = now
+	reloaded, _, _, _, err := storage.Load()
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(time.Minute), reloaded["node1"].ModifiedAt[0])
+}